@@ -0,0 +1,225 @@
+// Command geoclassify annotates historical logs with the country and geo-fence
+// decision of each record, offline against a GeoLite2 database file:
+//
+//	geoclassify -countries US,CA calls.csv > calls-geo.csv
+//	geoclassify -policies policies.json -policy voice-signup -o out.jsonl events.jsonl.gz
+//	geoclassify -groups groups.json -regions EU27 signups.csv
+//	zcat access.log.gz | geoclassify -countries '*' -summary json
+//
+// The format (CSV with a header, JSON Lines, or common/combined access logs) is
+// detected from the first line unless -format is given. Annotated records go to
+// stdout or -o; summary counts per country and decision go to stderr.
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/classify"
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+)
+
+const usage = `Usage: geoclassify [flags] [FILE]
+
+Reads FILE (or stdin when omitted or "-"; .gz files are decompressed) and writes
+each record with geo_country, geo_decision, geo_ip_status, geo_reason and
+geo_error added. Without rule flags every country is allowed, so the output
+only adds countries.
+
+Flags:
+`
+
+// errUsage is returned for malformed command lines; main prints usage for it.
+var errUsage = errors.New("usage")
+
+func main() {
+	// GeoStore logs at info level when it opens a database; keep output clean.
+	slog.SetLogLoggerLevel(slog.LevelWarn)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, "geoclassify:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("geoclassify", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	dbPath := fs.String("db", "data/GeoLite2-Country.mmdb", "GeoIP country database")
+	provider := fs.String("provider", geofence.ProviderMaxMind, "database format: "+strings.Join(geofence.Providers(), ", "))
+	policiesPath := fs.String("policies", "", "named policies file")
+	groupsPath := fs.String("groups", "", "region groups file for -regions and policies naming regions")
+	policy := fs.String("policy", "", "named policy instead of -countries/-denied")
+	countries := fs.String("countries", "", `allowed countries, comma-separated (default "*")`)
+	denied := fs.String("denied", "", "denied countries, comma-separated")
+	regions := fs.String("regions", "", "allowed regions, comma-separated (requires -groups)")
+	lenient := fs.Bool("lenient", false, "also accept alpha-3 codes and country names in -countries/-denied")
+	action := fs.String("unknown-ip-action", "", "allow, deny or error for IPs without a country")
+	format := fs.String("format", string(classify.FormatAuto), "input format: auto, csv, jsonl or access")
+	field := fs.String("field", "", "CSV column or JSON key holding the IP (default detected)")
+	workers := fs.Int("workers", 0, "concurrent checks (default GOMAXPROCS)")
+	outPath := fs.String("o", "", "output file (default stdout)")
+	summaryFormat := fs.String("summary", "table", "summary on stderr: table, json or none")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() > 1 || !slices.Contains([]string{"table", "json", "none"}, *summaryFormat) {
+		fs.Usage()
+		return errUsage
+	}
+
+	ref := geofence.PolicyRef{
+		Name: *policy,
+		Inline: geofence.Policy{
+			AllowedCountries: splitList(*countries),
+			DeniedCountries:  splitList(*denied),
+			AllowedRegions:   splitList(*regions),
+			UnknownIPAction:  geofence.UnknownIPAction(*action),
+		},
+	}
+	if ref.Name == "" && len(ref.Inline.AllowedCountries) == 0 && len(ref.Inline.AllowedRegions) == 0 {
+		ref.Inline.AllowedCountries = []string{geofence.AllCountries}
+	}
+
+	var opts []geofence.Option
+	if *lenient {
+		opts = append(opts, geofence.WithLenientCountries())
+	}
+	if *groupsPath != "" {
+		groups, err := geofence.LoadGroups(*groupsPath)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, geofence.WithGroups(groups))
+	}
+	if *policiesPath != "" {
+		policies, err := geofence.LoadPolicies(*policiesPath)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, geofence.WithPolicies(policies))
+	}
+	store, err := geofence.NewGeoStore(*dbPath, geofence.WithProvider(*provider))
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	in, closeIn, err := openInput(fs.Arg(0), stdin)
+	if err != nil {
+		return err
+	}
+	defer closeIn()
+
+	out := stdout
+	var outFile *os.File
+	if *outPath != "" {
+		outFile, err = os.Create(*outPath)
+		if err != nil {
+			return fmt.Errorf("create output: %w", err)
+		}
+		defer outFile.Close()
+		out = outFile
+	}
+
+	summary, err := classify.Run(ctx, geofence.NewChecker(store, opts...), in, out, classify.Config{
+		Format:  classify.Format(*format),
+		Field:   *field,
+		Policy:  ref,
+		Workers: *workers,
+	})
+	if err != nil {
+		return err
+	}
+	if outFile != nil {
+		if err := outFile.Close(); err != nil {
+			return fmt.Errorf("close output: %w", err)
+		}
+	}
+
+	switch *summaryFormat {
+	case "json":
+		enc := json.NewEncoder(stderr)
+		enc.SetIndent("", "  ")
+		return enc.Encode(summary)
+	case "table":
+		return writeSummary(stderr, summary)
+	}
+	return nil
+}
+
+// openInput opens path, or returns stdin for "" and "-". Files ending in .gz are
+// decompressed.
+func openInput(path string, stdin io.Reader) (io.Reader, func() error, error) {
+	if path == "" || path == "-" {
+		return stdin, func() error { return nil }, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open input: %w", err)
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return f, f.Close, nil
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("open input: %w", err)
+	}
+	return zr, func() error { return errors.Join(zr.Close(), f.Close()) }, nil
+}
+
+// writeSummary prints counts per country, busiest first, followed by the total.
+func writeSummary(w io.Writer, s classify.Summary) error {
+	countries := make([]string, 0, len(s.Countries))
+	for country := range s.Countries {
+		countries = append(countries, country)
+	}
+	slices.SortFunc(countries, func(a, b string) int {
+		if n := s.Countries[b].Total() - s.Countries[a].Total(); n != 0 {
+			return n
+		}
+		return strings.Compare(a, b)
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "COUNTRY\tALLOWED\tDENIED\tERRORS\tTOTAL\t")
+	for _, country := range countries {
+		c := s.Countries[country]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t\n", country, c.Allowed, c.Denied, c.Errors, c.Total())
+	}
+	fmt.Fprintf(tw, "TOTAL\t%d\t%d\t%d\t%d\t\n", s.Total.Allowed, s.Total.Denied, s.Total.Errors, s.Total.Total())
+	return tw.Flush()
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+	"github.com/jadenmounteer/avoxi-geo-fence/pkg/geofenceclient"
+)
+
+// openBackend returns a client for whichever of httpURL, grpcAddr or dbPath is
+// set. With none set it talks HTTP to a local server. timeout bounds each request
+// to a server, so long batches are not cut short.
+func openBackend(httpURL, grpcAddr, dbPath, provider, policiesPath string, timeout time.Duration) (geofenceclient.Client, error) {
+	set := 0
+	for _, v := range []string{httpURL, grpcAddr, dbPath} {
+		if v != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return nil, fmt.Errorf("-http, -grpc and -db are mutually exclusive")
+	}
+	if policiesPath != "" && dbPath == "" {
+		return nil, fmt.Errorf("-policies requires -db; servers use their own policies")
+	}
+
+	switch {
+	case grpcAddr != "":
+		return geofenceclient.NewGRPC([]string{grpcAddr}, geofenceclient.WithRetry(1, 0), geofenceclient.WithTimeout(timeout))
+	case dbPath != "":
+		return openLocal(dbPath, provider, policiesPath)
+	default:
+		if httpURL == "" {
+			httpURL = "http://localhost:8080"
+		}
+		return geofenceclient.NewHTTP([]string{httpURL}, geofenceclient.WithRetry(1, 0), geofenceclient.WithTimeout(timeout))
+	}
+}
+
+// localBackend implements geofenceclient.Client with an in-process Checker, so
+// the same commands work against a database file without a server.
+type localBackend struct {
+	store   *geofence.GeoStore
+	checker *geofence.Checker
+}
+
+func openLocal(dbPath, provider, policiesPath string) (*localBackend, error) {
+	var opts []geofence.Option
+	if policiesPath != "" {
+		policies, err := geofence.LoadPolicies(policiesPath)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, geofence.WithPolicies(policies))
+	}
+	store, err := geofence.NewGeoStore(dbPath, geofence.WithProvider(provider))
+	if err != nil {
+		return nil, err
+	}
+	return &localBackend{store: store, checker: geofence.NewChecker(store, opts...)}, nil
+}
+
+func (b *localBackend) Check(ctx context.Context, req geofenceclient.CheckRequest) (geofenceclient.CheckResult, error) {
+	result, err := b.checker.Evaluate(ctx, req.IPAddress, geofence.PolicyRef{
+		Name: req.Policy,
+		Inline: geofence.Policy{
+			AllowedCountries: req.AllowedCountries,
+			DeniedCountries:  req.DeniedCountries,
+			AllowedNetworks:  req.AllowedNetworks,
+			DeniedNetworks:   req.DeniedNetworks,
+			UnknownIPAction:  geofence.UnknownIPAction(req.UnknownIPAction),
+			AllowedRegions:   req.AllowedRegions,
+			DeniedRegions:    req.DeniedRegions,
+		},
+	})
+	if err != nil {
+		return geofenceclient.CheckResult{}, err
+	}
+	return geofenceclient.CheckResult{
+		Allowed:     result.Allowed,
+		Country:     result.Country,
+		DecidedBy:   string(result.DecidedBy),
+		IPStatus:    string(result.IPStatus),
+		Reason:      string(result.Reason),
+		MatchedRule: result.MatchedRule,
+		Provider:    result.Provider,
+	}, nil
+}
+
+func (b *localBackend) Lookup(ctx context.Context, ipAddress, locale string) (geofenceclient.LookupResult, error) {
+	result, err := b.checker.Lookup(ctx, ipAddress, locale)
+	if err != nil {
+		return geofenceclient.LookupResult{}, err
+	}
+	lookup := geofenceclient.LookupResult{
+		IPStatus:           string(result.IPStatus),
+		Country:            geofenceclient.Country(result.Country),
+		RegisteredCountry:  geofenceclient.Country(result.RegisteredCountry),
+		RepresentedCountry: geofenceclient.RepresentedCountry{Country: geofenceclient.Country(result.RepresentedCountry.Country), Type: result.RepresentedCountry.Type},
+		Continent:          geofenceclient.Continent(result.Continent),
+		Provider:           result.Provider,
+		Anonymity:          result.Anonymity.Names(),
+	}
+	if result.Network.IsValid() {
+		lookup.Network = result.Network.String()
+	}
+	for _, a := range result.Disagreement {
+		lookup.Disagreement = append(lookup.Disagreement, geofenceclient.SourceAnswer{Source: a.Source, Country: a.Country})
+	}
+	return lookup, nil
+}
+
+func (b *localBackend) Close() error {
+	return b.store.Close()
+}
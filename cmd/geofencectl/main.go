@@ -0,0 +1,191 @@
+// Command geofencectl runs ad-hoc checks and lookups against a geo-fence server
+// over HTTP or gRPC, or directly against a GeoLite2 database file.
+//
+//	geofencectl check -countries US,CA 8.8.8.8
+//	geofencectl -grpc localhost:9090 check -policy voice-signup 8.8.8.8 81.2.69.142
+//	geofencectl -db data/GeoLite2-Country.mmdb lookup -locale de 8.8.8.8
+//	geofencectl -o json batch -countries US ips.txt
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+	"github.com/jadenmounteer/avoxi-geo-fence/pkg/geofenceclient"
+)
+
+const usage = `Usage: geofencectl [flags] <command> [command flags] [args]
+
+Commands:
+  check   [rule flags] IP...   check IPs against countries or a named policy
+  lookup  [-locale L] IP...    show the database record of each IP
+  batch   [rule flags] FILE    check one IP per line of FILE ("-" for stdin)
+
+Rule flags:
+  -countries LIST          allowed countries, comma-separated ("*" for all)
+  -denied LIST             denied countries, comma-separated
+  -regions LIST            allowed regions, comma-separated, e.g. EU27,NA
+  -policy NAME             named policy instead of -countries/-denied/-regions
+  -unknown-ip-action A     allow, deny or error for IPs without a country
+
+Flags:
+`
+
+// errUsage is returned for malformed command lines; main prints usage for it.
+var errUsage = errors.New("usage")
+
+func main() {
+	// GeoStore logs at info level when it opens a database; keep -db output clean.
+	slog.SetLogLoggerLevel(slog.LevelWarn)
+	if err := run(context.Background(), os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, "geofencectl:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("geofencectl", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	httpURL := fs.String("http", "", "server base URL (default http://localhost:8080 when no backend is set)")
+	grpcAddr := fs.String("grpc", "", "server gRPC address, e.g. localhost:9090")
+	dbPath := fs.String("db", "", "check locally against this database file instead of a server")
+	provider := fs.String("provider", geofence.ProviderMaxMind, "database format for -db: "+strings.Join(geofence.Providers(), ", "))
+	policiesPath := fs.String("policies", "", "named policies file for -db")
+	output := fs.String("o", "table", "output format: table or json")
+	timeout := fs.Duration("timeout", 5*time.Second, "timeout for each request to a server")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() == 0 || (*output != "table" && *output != "json") {
+		fs.Usage()
+		return errUsage
+	}
+
+	b, err := openBackend(*httpURL, *grpcAddr, *dbPath, *provider, *policiesPath, *timeout)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	command, cmdArgs := fs.Arg(0), fs.Args()[1:]
+	var rows []row
+	switch command {
+	case "check":
+		req, ips, err := parseRuleFlags(command, cmdArgs)
+		if err != nil {
+			return err
+		}
+		rows = checkAll(ctx, b, req, ips)
+	case "lookup":
+		lfs := flag.NewFlagSet(command, flag.ContinueOnError)
+		locale := lfs.String("locale", "", "language of names: "+strings.Join(geofence.Locales, ", "))
+		if err := lfs.Parse(cmdArgs); err != nil {
+			return errUsage
+		}
+		if lfs.NArg() == 0 {
+			fs.Usage()
+			return errUsage
+		}
+		rows = lookupAll(ctx, b, *locale, lfs.Args())
+	case "batch":
+		req, files, err := parseRuleFlags(command, cmdArgs)
+		if err != nil {
+			return err
+		}
+		if len(files) != 1 {
+			fs.Usage()
+			return errUsage
+		}
+		ips, err := readIPs(files[0], stdin)
+		if err != nil {
+			return err
+		}
+		rows = checkAll(ctx, b, req, ips)
+	default:
+		fs.Usage()
+		return errUsage
+	}
+
+	if *output == "json" {
+		return writeJSON(stdout, rows)
+	}
+	return writeTable(stdout, command == "lookup", rows)
+}
+
+// parseRuleFlags parses the rule flags shared by check and batch and returns the
+// remaining arguments.
+func parseRuleFlags(command string, args []string) (geofenceclient.CheckRequest, []string, error) {
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	countries := fs.String("countries", "", "allowed countries, comma-separated")
+	denied := fs.String("denied", "", "denied countries, comma-separated")
+	regions := fs.String("regions", "", "allowed regions, comma-separated, e.g. EU27,NA")
+	policy := fs.String("policy", "", "named policy")
+	action := fs.String("unknown-ip-action", "", "allow, deny or error")
+	if err := fs.Parse(args); err != nil {
+		return geofenceclient.CheckRequest{}, nil, errUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return geofenceclient.CheckRequest{}, nil, errUsage
+	}
+	return geofenceclient.CheckRequest{
+		Policy:           *policy,
+		AllowedCountries: splitList(*countries),
+		DeniedCountries:  splitList(*denied),
+		UnknownIPAction:  *action,
+		AllowedRegions:   splitList(*regions),
+	}, fs.Args(), nil
+}
+
+// readIPs reads one IP per line, skipping blank lines and # comments.
+func readIPs(path string, stdin io.Reader) ([]string, error) {
+	r := stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("open batch file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	var ips []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ips = append(ips, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read batch file: %w", err)
+	}
+	return ips, nil
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
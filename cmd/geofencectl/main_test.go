@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/api"
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+)
+
+type lookupFunc func(net.IP) (string, error)
+
+func (f lookupFunc) Lookup(ip net.IP) (string, error) { return f(ip) }
+
+func TestRun_HTTP(t *testing.T) {
+	checker := geofence.NewChecker(lookupFunc(func(ip net.IP) (string, error) {
+		if ip.String() == "81.2.69.142" {
+			return "GB", nil
+		}
+		return "US", nil
+	}))
+	mux := http.NewServeMux()
+	mux.Handle("/v1/check", api.NewCheckHandler(checker))
+	mux.Handle("/v1/lookup/{ip}", api.NewLookupHandler(checker))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := []struct {
+		name  string
+		args  []string
+		stdin string
+		want  []row
+	}{
+		{
+			name: "check",
+			args: []string{"check", "-countries", "US", "8.8.8.8", "81.2.69.142"},
+			want: []row{
+				{IPAddress: "8.8.8.8", Allowed: ptr(true), Country: "US", IPStatus: "geolocated", Reason: "COUNTRY_ALLOWED", MatchedRule: "US"},
+				{IPAddress: "81.2.69.142", Allowed: ptr(false), Country: "GB", IPStatus: "geolocated", Reason: "COUNTRY_NOT_IN_LIST"},
+			},
+		},
+		{
+			name: "lookup",
+			args: []string{"lookup", "81.2.69.142", "10.0.0.1"},
+			want: []row{
+				{IPAddress: "81.2.69.142", Country: "GB", IPStatus: "geolocated"},
+				{IPAddress: "10.0.0.1", Network: "10.0.0.0/8", IPStatus: "private"},
+			},
+		},
+		{
+			name:  "batch from stdin",
+			args:  []string{"batch", "-countries", "GB", "-"},
+			stdin: "# office\n81.2.69.142\n\nnot-an-ip\n",
+			want: []row{
+				{IPAddress: "81.2.69.142", Allowed: ptr(true), Country: "GB", IPStatus: "geolocated", Reason: "COUNTRY_ALLOWED", MatchedRule: "GB"},
+				{IPAddress: "not-an-ip", Error: "invalid request: invalid IP: not-an-ip"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			args := append([]string{"-http", srv.URL, "-o", "json"}, tt.args...)
+			if err := run(context.Background(), args, strings.NewReader(tt.stdin), &out); err != nil {
+				t.Fatalf("run() unexpected error: %v", err)
+			}
+			var got []row
+			if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+				t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d rows, want %d:\n%s", len(got), len(tt.want), out.String())
+			}
+			for i := range got {
+				gotJSON, _ := json.Marshal(got[i])
+				wantJSON, _ := json.Marshal(tt.want[i])
+				if string(gotJSON) != string(wantJSON) {
+					t.Errorf("row %d = %s, want %s", i, gotJSON, wantJSON)
+				}
+			}
+		})
+	}
+}
+
+func TestRun_TimeoutPerRequest(t *testing.T) {
+	checker := geofence.NewChecker(lookupFunc(func(net.IP) (string, error) { return "US", nil }))
+	handler := api.NewCheckHandler(checker)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(40 * time.Millisecond)
+		handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	// Four checks take longer than -timeout in total but each one fits in it.
+	var out bytes.Buffer
+	args := []string{"-http", srv.URL, "-timeout", "100ms", "-o", "json", "batch", "-countries", "US", "-"}
+	stdin := strings.Repeat("8.8.8.8\n", 4)
+	if err := run(context.Background(), args, strings.NewReader(stdin), &out); err != nil {
+		t.Fatalf("run() unexpected error: %v", err)
+	}
+	if strings.Contains(out.String(), `"error"`) {
+		t.Errorf("batch output has errors:\n%s", out.String())
+	}
+}
+
+func TestRun_Usage(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"frobnicate"},
+		{"-o", "xml", "lookup", "8.8.8.8"},
+		{"-http", "http://x", "-db", "x.mmdb", "lookup", "8.8.8.8"},
+	} {
+		if err := run(context.Background(), args, strings.NewReader(""), &bytes.Buffer{}); err == nil {
+			t.Errorf("run(%q) expected error, got nil", args)
+		}
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/pkg/geofenceclient"
+)
+
+// row is one line of output. Allowed is nil for lookups, and only lookups set
+// the name, registered country, continent and network.
+type row struct {
+	IPAddress         string   `json:"ip_address"`
+	Allowed           *bool    `json:"allowed,omitempty"`
+	Country           string   `json:"country"`
+	CountryName       string   `json:"country_name,omitempty"`
+	RegisteredCountry string   `json:"registered_country,omitempty"`
+	Continent         string   `json:"continent,omitempty"`
+	Network           string   `json:"network,omitempty"`
+	IPStatus          string   `json:"ip_status,omitempty"`
+	Reason            string   `json:"reason,omitempty"`
+	MatchedRule       string   `json:"matched_rule,omitempty"`
+	Provider          string   `json:"provider,omitempty"`
+	Anonymity         []string `json:"anonymity,omitempty"`
+	Error             string   `json:"error,omitempty"`
+}
+
+func checkAll(ctx context.Context, c geofenceclient.Client, req geofenceclient.CheckRequest, ips []string) []row {
+	rows := make([]row, len(ips))
+	for i, ip := range ips {
+		req.IPAddress = ip
+		result, err := c.Check(ctx, req)
+		rows[i] = newRow(ip, result, err)
+		if err == nil {
+			rows[i].Allowed = &result.Allowed
+		}
+	}
+	return rows
+}
+
+func lookupAll(ctx context.Context, c geofenceclient.Client, locale string, ips []string) []row {
+	rows := make([]row, len(ips))
+	for i, ip := range ips {
+		result, err := c.Lookup(ctx, ip, locale)
+		rows[i] = row{
+			IPAddress:         ip,
+			Country:           result.Country.ISOCode,
+			CountryName:       result.Country.Name,
+			RegisteredCountry: result.RegisteredCountry.ISOCode,
+			Continent:         result.Continent.Code,
+			Network:           result.Network,
+			IPStatus:          result.IPStatus,
+			Provider:          result.Provider,
+			Anonymity:         result.Anonymity,
+		}
+		if err != nil {
+			rows[i].Error = err.Error()
+		}
+	}
+	return rows
+}
+
+func newRow(ip string, result geofenceclient.CheckResult, err error) row {
+	r := row{
+		IPAddress:   ip,
+		Country:     result.Country,
+		IPStatus:    result.IPStatus,
+		Reason:      result.Reason,
+		MatchedRule: result.MatchedRule,
+		Provider:    result.Provider,
+		Anonymity:   result.Anonymity,
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+func writeJSON(w io.Writer, rows []row) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rows)
+}
+
+func writeTable(w io.Writer, lookup bool, rows []row) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if lookup {
+		fmt.Fprintln(tw, "IP\tCOUNTRY\tNAME\tCONTINENT\tNETWORK\tIP STATUS\tERROR")
+	} else {
+		fmt.Fprintln(tw, "IP\tALLOWED\tCOUNTRY\tIP STATUS\tREASON\tMATCHED RULE\tERROR")
+	}
+	for _, r := range rows {
+		if lookup {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.IPAddress, dash(r.Country), dash(r.CountryName), dash(r.Continent), dash(r.Network), dash(r.IPStatus), r.Error)
+			continue
+		}
+		allowed := "-"
+		if r.Allowed != nil {
+			allowed = fmt.Sprint(*r.Allowed)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.IPAddress, allowed, dash(r.Country), dash(r.IPStatus), dash(r.Reason), dash(r.MatchedRule), r.Error)
+	}
+	return tw.Flush()
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
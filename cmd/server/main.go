@@ -2,18 +2,28 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
 	"github.com/jadenmounteer/avoxi-geo-fence/internal/api"
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/clientip"
 	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
 	"github.com/jadenmounteer/avoxi-geo-fence/internal/pb"
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/telemetry"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
@@ -22,10 +32,26 @@ import (
 const version = "1.0.0"
 
 type config struct {
-	httpPort string
-	grpcPort string
-	dbPath   string
-	logLevel slog.Level
+	httpPort       string
+	grpcPort       string
+	dbPaths        []string
+	dbProviders    []string
+	dbStrategy     string
+	anonPath       string
+	anonProvider   string
+	asnPath        string
+	policiesPath   string
+	groupsPath     string
+	lenientCodes   bool
+	cacheSize      int
+	cacheTTL       time.Duration
+	dbWatch        bool
+	reloadDebounce time.Duration
+	tracesExporter string
+	checkCallerIP  bool
+	trustedProxies []string
+	authzPolicy    string
+	logLevel       slog.Level
 }
 
 func loadConfig() config {
@@ -47,8 +73,136 @@ func loadConfig() config {
 	if dbPath == "" {
 		dbPath = "data/GeoLite2-Country.mmdb"
 	}
+	dbProvider := os.Getenv("GEOIP_PROVIDER")
+	if dbProvider == "" {
+		dbProvider = geofence.ProviderMaxMind
+	}
+	dbStrategy := os.Getenv("GEOIP_STRATEGY")
+	if dbStrategy == "" {
+		dbStrategy = string(geofence.StrategyFirst)
+	}
+	anonProvider := os.Getenv("ANONYMITY_PROVIDER")
+	if anonProvider == "" {
+		anonProvider = geofence.ProviderMaxMind
+	}
+	policiesPath := os.Getenv("POLICIES_PATH")
+	groupsPath := os.Getenv("GROUPS_PATH")
+	lenientCodes := strings.EqualFold(os.Getenv("LENIENT_COUNTRY_CODES"), "true")
+	dbWatch := !strings.EqualFold(os.Getenv("DB_WATCH"), "false")
+	reloadDebounce := geofence.DefaultReloadDebounce
+	if v := os.Getenv("DB_RELOAD_DEBOUNCE"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			reloadDebounce = d
+		}
+	}
+	var cacheSize int
+	if v := os.Getenv("LOOKUP_CACHE_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cacheSize = n
+		}
+	}
+	cacheTTL := geofence.DefaultCacheTTL
+	if v := os.Getenv("LOOKUP_CACHE_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cacheTTL = d
+		}
+	}
+	tracesExporter := os.Getenv("OTEL_TRACES_EXPORTER")
+	if tracesExporter == "" {
+		tracesExporter = telemetry.ExporterNone
+	}
+	checkCallerIP := strings.EqualFold(os.Getenv("CHECK_CALLER_IP"), "true")
+	var trustedProxies []string
+	if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
+		trustedProxies = strings.Split(v, ",")
+	}
+	authzPolicy := os.Getenv("AUTHZ_POLICY")
 	level := parseLogLevel(os.Getenv("LOG_LEVEL"))
-	return config{httpPort: httpPort, grpcPort: grpcPort, dbPath: dbPath, logLevel: level}
+	return config{
+		httpPort:       httpPort,
+		grpcPort:       grpcPort,
+		dbPaths:        splitList(dbPath),
+		dbProviders:    splitList(dbProvider),
+		dbStrategy:     dbStrategy,
+		anonPath:       os.Getenv("ANONYMITY_DB_PATH"),
+		anonProvider:   anonProvider,
+		asnPath:        os.Getenv("ASN_DB_PATH"),
+		policiesPath:   policiesPath,
+		groupsPath:     groupsPath,
+		lenientCodes:   lenientCodes,
+		cacheSize:      cacheSize,
+		cacheTTL:       cacheTTL,
+		dbWatch:        dbWatch,
+		reloadDebounce: reloadDebounce,
+		tracesExporter: tracesExporter,
+		checkCallerIP:  checkCallerIP,
+		trustedProxies: trustedProxies,
+		authzPolicy:    authzPolicy,
+		logLevel:       level,
+	}
+}
+
+// splitList splits a comma-separated variable, trimming spaces around entries.
+func splitList(s string) []string {
+	list := strings.Split(s, ",")
+	for i := range list {
+		list[i] = strings.TrimSpace(list[i])
+	}
+	return list
+}
+
+// openStores opens one GeoStore per database path. providers holds either one
+// provider per path or a single provider for every path.
+func openStores(paths, providers []string) ([]*geofence.GeoStore, error) {
+	if len(providers) != 1 && len(providers) != len(paths) {
+		return nil, fmt.Errorf("GEOIP_PROVIDER lists %d providers for %d databases", len(providers), len(paths))
+	}
+	stores := make([]*geofence.GeoStore, 0, len(paths))
+	for i, path := range paths {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			closeStores(stores)
+			return nil, fmt.Errorf("database file does not exist: %s", path)
+		}
+		provider := providers[0]
+		if len(providers) > 1 {
+			provider = providers[i]
+		}
+		store, err := geofence.NewGeoStore(path, geofence.WithProvider(provider))
+		if err != nil {
+			closeStores(stores)
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		stores = append(stores, store)
+	}
+	return stores, nil
+}
+
+// databaseFile is a GeoStore or AnonymityStore: a file the server watches,
+// reloads on SIGHUP and closes on shutdown.
+type databaseFile interface {
+	Path() string
+	Watch(ctx context.Context, debounce time.Duration) error
+	Reload() error
+	Close() error
+}
+
+func closeStores[S databaseFile](stores []S) {
+	for _, store := range stores {
+		if err := store.Close(); err != nil {
+			slog.Error("close database", "path", store.Path(), "err", err)
+		}
+	}
+}
+
+// sourceName names the consensus source for store: its provider, suffixed with
+// its position when an earlier database uses the same provider.
+func sourceName(stores []*geofence.GeoStore, i int) string {
+	for _, earlier := range stores[:i] {
+		if earlier.Provider() == stores[i].Provider() {
+			return fmt.Sprintf("%s-%d", stores[i].Provider(), i+1)
+		}
+	}
+	return stores[i].Provider()
 }
 
 func parseLogLevel(s string) slog.Level {
@@ -64,6 +218,16 @@ func parseLogLevel(s string) slog.Level {
 	}
 }
 
+// isTracedPath excludes health probes and metrics scrapes from tracing.
+func isTracedPath(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/health", "/ready", "/metrics":
+		return false
+	default:
+		return true
+	}
+}
+
 func main() {
 	cfg := loadConfig()
 
@@ -71,33 +235,157 @@ func main() {
 	logger := slog.New(jsonHandler).With("service", "geo-fence-service", "version", version)
 	slog.SetDefault(logger)
 
-	if _, err := os.Stat(cfg.dbPath); os.IsNotExist(err) {
-		slog.Error("database file does not exist", "path", cfg.dbPath)
+	shutdownTracing, err := telemetry.SetupTracing(context.Background(), cfg.tracesExporter, "geo-fence-service", version)
+	if err != nil {
+		slog.Error("failed to set up tracing", "exporter", cfg.tracesExporter, "err", err)
 		os.Exit(1)
 	}
 
-	store, err := geofence.NewGeoStore(cfg.dbPath)
+	strategy, err := geofence.ParseStrategy(cfg.dbStrategy)
+	if err != nil {
+		slog.Error("invalid GEOIP_STRATEGY", "err", err)
+		os.Exit(1)
+	}
+	stores, err := openStores(cfg.dbPaths, cfg.dbProviders)
 	if err != nil {
 		slog.Error("failed to open GeoIP database", "err", err)
 		os.Exit(1)
 	}
+	// The first database is the primary: readiness and the build time metric
+	// follow it.
+	store := stores[0]
+
+	var checkerOpts []geofence.Option
+	var anonStore *geofence.AnonymityStore
+	if cfg.anonPath != "" {
+		anonStore, err = geofence.NewAnonymityStore(cfg.anonPath,
+			geofence.WithProvider(cfg.anonProvider),
+			geofence.WithASNDatabase(cfg.asnPath),
+		)
+		if err != nil {
+			slog.Error("failed to open anonymous IP database", "err", err)
+			os.Exit(1)
+		}
+		checkerOpts = append(checkerOpts, geofence.WithAnonymity(anonStore))
+	}
+	if cfg.lenientCodes {
+		checkerOpts = append(checkerOpts, geofence.WithLenientCountries())
+	}
+	var groups geofence.Groups
+	if cfg.groupsPath != "" {
+		groups, err = geofence.LoadGroups(cfg.groupsPath)
+		if err != nil {
+			slog.Error("failed to load groups", "path", cfg.groupsPath, "err", err)
+			os.Exit(1)
+		}
+		slog.Info("groups loaded", "path", cfg.groupsPath, "count", len(groups))
+		checkerOpts = append(checkerOpts, geofence.WithGroups(groups))
+	}
+	if cfg.policiesPath != "" {
+		policies, err := geofence.LoadPolicies(cfg.policiesPath)
+		if err != nil {
+			slog.Error("failed to load policies", "path", cfg.policiesPath, "err", err)
+			os.Exit(1)
+		}
+		slog.Info("policies loaded", "path", cfg.policiesPath, "count", len(policies))
+		checkerOpts = append(checkerOpts, geofence.WithPolicies(policies))
+	}
 
-	checker := geofence.NewChecker(store)
+	// Each database gets its own cache, in front of it rather than the consensus,
+	// so the Checker still sees every database's answer.
+	sources := make([]geofence.Source, len(stores))
+	var caches []*geofence.CachedLookuper
+	for i, s := range stores {
+		sources[i] = geofence.Source{Name: sourceName(stores, i), Lookup: s}
+		if cfg.cacheSize > 0 {
+			cache := geofence.NewCachedLookuper(s, cfg.cacheSize, cfg.cacheTTL)
+			s.OnReload(cache.Purge)
+			caches = append(caches, cache)
+			sources[i].Lookup = cache
+		}
+	}
+	if len(caches) > 0 {
+		slog.Info("lookup cache enabled", "size", cfg.cacheSize, "ttl", cfg.cacheTTL)
+	}
+	lookuper := sources[0].Lookup
+	if len(sources) > 1 {
+		consensus, err := geofence.NewConsensusLookuper(strategy, sources...)
+		if err != nil {
+			slog.Error("failed to combine GeoIP databases", "err", err)
+			os.Exit(1)
+		}
+		lookuper = consensus
+		slog.Info("consulting multiple GeoIP databases", "count", len(sources), "strategy", consensus.Strategy())
+	}
+	// The anonymous IP database is watched, reloaded and closed along with the
+	// country databases.
+	files := make([]databaseFile, 0, len(stores)+1)
+	for _, s := range stores {
+		files = append(files, s)
+	}
+	if anonStore != nil {
+		files = append(files, anonStore)
+	}
+
+	checker := geofence.NewChecker(lookuper, checkerOpts...)
+	if err := checker.Validate(); err != nil {
+		slog.Error("invalid policies", "path", cfg.policiesPath, "err", err)
+		os.Exit(1)
+	}
 	healthHandler := api.NewHealthHandler(store)
 
+	authzPolicy := geofence.PolicyRef{Name: cfg.authzPolicy}
+	if cfg.authzPolicy != "" {
+		if _, err := checker.Resolve(authzPolicy); err != nil {
+			slog.Error("invalid AUTHZ_POLICY", "policy", cfg.authzPolicy, "err", err)
+			os.Exit(1)
+		}
+	}
+
+	clientIP, err := clientip.NewResolver(cfg.trustedProxies)
+	if err != nil {
+		slog.Error("invalid TRUSTED_PROXIES", "err", err)
+		os.Exit(1)
+	}
+	var apiOpts []api.Option
+	if cfg.checkCallerIP {
+		apiOpts = append(apiOpts, api.WithClientIP(clientIP))
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	metrics := api.NewMetrics(registry, store)
+	if len(caches) > 0 {
+		api.RegisterCacheMetrics(registry, caches...)
+	}
+
 	mux := http.NewServeMux()
-	mux.Handle("/v1/check", api.LoggingMiddleware(api.NewCheckHandler(checker)))
+	mux.Handle("/v1/check", api.LoggingMiddleware(metrics.Middleware(api.NewCheckHandler(checker, apiOpts...))))
+	mux.Handle("/v1/check:batch", api.LoggingMiddleware(metrics.Middleware(api.NewBatchCheckHandler(checker))))
+	mux.Handle("/v1/lookup/{ip}", api.LoggingMiddleware(metrics.Middleware(api.NewLookupHandler(checker))))
+	mux.Handle("/v1/regions", api.LoggingMiddleware(metrics.Middleware(api.NewRegionsHandler(groups))))
+	mux.Handle("/v1/regions/{name}", api.LoggingMiddleware(metrics.Middleware(api.NewRegionsHandler(groups))))
+	mux.Handle("/v1/authz", api.LoggingMiddleware(metrics.Middleware(api.NewAuthzHandler(checker, clientIP))))
 	mux.HandleFunc("/health", healthHandler.Liveness)
 	mux.HandleFunc("/ready", healthHandler.Ready)
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
 
 	httpServer := &http.Server{
 		Addr:    ":" + cfg.httpPort,
-		Handler: mux,
+		Handler: otelhttp.NewHandler(mux, "geofence", otelhttp.WithFilter(isTracedPath)),
 	}
 
-	grpcServer := grpc.NewServer()
-	pb.RegisterGeoFenceServiceServer(grpcServer, api.NewGeoFenceServer(checker))
+	grpcServer := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(metrics.UnaryServerInterceptor()),
+	)
+	pb.RegisterGeoFenceServiceServer(grpcServer, api.NewGeoFenceServer(checker, apiOpts...))
 	pb.RegisterHealthServiceServer(grpcServer, healthHandler)
+	// Without a default policy every ext_authz check lacking a route policy would
+	// fail, so the Authorization service is only served when one is configured.
+	if cfg.authzPolicy != "" {
+		authv3.RegisterAuthorizationServer(grpcServer, api.NewAuthzServer(checker, authzPolicy))
+	}
 	reflection.Register(grpcServer)
 
 	lis, err := net.Listen("tcp", ":"+cfg.grpcPort)
@@ -117,13 +405,37 @@ func main() {
 		return grpcServer.Serve(lis)
 	})
 
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	if cfg.dbWatch {
+		for _, s := range files {
+			g.Go(func() error {
+				if err := s.Watch(watchCtx, cfg.reloadDebounce); err != nil {
+					slog.Error("database watcher stopped", "path", s.Path(), "err", err)
+				}
+				return nil
+			})
+		}
+	}
+
 	slog.Info("server starting", "http_port", cfg.httpPort, "grpc_port", cfg.grpcPort)
 
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	<-sigChan
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	for sig := range sigChan {
+		if sig != syscall.SIGHUP {
+			break
+		}
+		for _, s := range files {
+			slog.Info("SIGHUP received, reloading GeoIP database", "path", s.Path())
+			if err := s.Reload(); err != nil {
+				slog.Error("database reload failed; keeping previous database", "path", s.Path(), "err", err)
+			}
+		}
+	}
 
 	slog.Info("shutting down gracefully")
+	stopWatch()
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -132,8 +444,9 @@ func main() {
 	if err := httpServer.Shutdown(shutdownCtx); err != nil {
 		slog.Error("http server shutdown", "err", err)
 	}
-	if err := store.Close(); err != nil {
-		slog.Error("close GeoStore", "err", err)
+	closeStores(files)
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("tracing shutdown", "err", err)
 	}
 
 	if err := g.Wait(); err != nil {
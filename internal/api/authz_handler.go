@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/clientip"
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+)
+
+// HeaderGeoPolicy names the policy for an AuthzHandler request when the policy
+// query parameter is not set.
+const HeaderGeoPolicy = "X-Geo-Policy"
+
+// AuthzHandler handles /v1/authz subrequests from nginx auth_request and Traefik
+// forwardAuth. The client IP comes from the connection or, behind a trusted proxy,
+// its forwarding headers, and the named policy from the policy query parameter or
+// the X-Geo-Policy header.
+type AuthzHandler struct {
+	checker  *geofence.Checker
+	resolver *clientip.Resolver
+}
+
+// NewAuthzHandler creates an AuthzHandler with the given Checker and client IP resolver.
+func NewAuthzHandler(checker *geofence.Checker, resolver *clientip.Resolver) *AuthzHandler {
+	return &AuthzHandler{checker: checker, resolver: resolver}
+}
+
+// ServeHTTP implements http.Handler. Any method is accepted because nginx forwards
+// the original request's method on auth subrequests. The decision is returned as
+// 200 or 403 with an empty body and X-Geo-Country, X-Geo-Decision and X-Geo-Reason
+// headers; errors use the same statuses as CheckHandler.
+func (h *AuthzHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	policy := r.URL.Query().Get("policy")
+	if policy == "" {
+		policy = r.Header.Get(HeaderGeoPolicy)
+	}
+	ipAddress, _ := h.resolver.FromRequest(r)
+
+	result, err := h.checker.Evaluate(r.Context(), ipAddress, geofence.PolicyRef{Name: policy})
+	observeResult(r.Context(), result, err)
+	if err != nil {
+		code, msg := checkErrorStatus(ipAddress, err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: msg, Fields: invalidFields(err)})
+		return
+	}
+
+	decision, code := "denied", http.StatusForbidden
+	if result.Allowed {
+		decision, code = "allowed", http.StatusOK
+	}
+	w.Header().Set(HeaderGeoCountry, result.Country)
+	w.Header().Set(HeaderGeoDecision, decision)
+	w.Header().Set(HeaderGeoReason, string(result.Reason))
+	w.WriteHeader(code)
+}
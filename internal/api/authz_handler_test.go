@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/clientip"
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+)
+
+func TestAuthzHandler_ServeHTTP(t *testing.T) {
+	lookup := mockLookuper{lookup: func(ip net.IP) (string, error) {
+		if ip.String() == "1.2.3.4" {
+			return "", geofence.ErrUnknownIP
+		}
+		return "US", nil
+	}}
+	checker := geofence.NewChecker(lookup, geofence.WithPolicies(map[string]geofence.Policy{
+		"us-only": {AllowedCountries: []string{"US"}},
+		"gb-only": {AllowedCountries: []string{"GB"}},
+		"strict":  {AllowedCountries: []string{"US"}, UnknownIPAction: geofence.UnknownIPError},
+		"invalid": {AllowedCountries: []string{"US", "USA"}},
+	}))
+	resolver, _ := clientip.NewResolver([]string{"10.0.0.0/8"})
+	handler := NewAuthzHandler(checker, resolver)
+
+	tests := []struct {
+		name         string
+		target       string
+		headers      map[string]string
+		wantStatus   int
+		wantDecision string
+		wantCountry  string
+		wantFields   map[string][]string
+	}{
+		{
+			name:         "allowed via query parameter",
+			target:       "/v1/authz?policy=us-only",
+			headers:      map[string]string{"X-Forwarded-For": "8.8.8.8"},
+			wantStatus:   http.StatusOK,
+			wantDecision: "allowed",
+			wantCountry:  "US",
+		},
+		{
+			name:         "denied via policy header",
+			target:       "/v1/authz",
+			headers:      map[string]string{"X-Forwarded-For": "8.8.8.8", HeaderGeoPolicy: "gb-only"},
+			wantStatus:   http.StatusForbidden,
+			wantDecision: "denied",
+			wantCountry:  "US",
+		},
+		{
+			name:       "missing policy",
+			target:     "/v1/authz",
+			headers:    map[string]string{"X-Forwarded-For": "8.8.8.8"},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown IP with error action",
+			target:     "/v1/authz?policy=strict",
+			headers:    map[string]string{"X-Real-IP": "1.2.3.4"},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "invalid country code",
+			target:     "/v1/authz?policy=invalid",
+			headers:    map[string]string{"X-Forwarded-For": "8.8.8.8"},
+			wantStatus: http.StatusBadRequest,
+			wantFields: map[string][]string{"allowed_countries": {"USA"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.RemoteAddr = "10.0.0.2:5555"
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantDecision == "" {
+				var body ErrorResponse
+				if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+					t.Fatalf("decode error body: %v", err)
+				}
+				if !reflect.DeepEqual(body.Fields, tt.wantFields) {
+					t.Errorf("fields = %v, want %v", body.Fields, tt.wantFields)
+				}
+				return
+			}
+			if rec.Body.Len() != 0 {
+				t.Errorf("body = %q, want empty", rec.Body.String())
+			}
+			if got := rec.Header().Get(HeaderGeoDecision); got != tt.wantDecision {
+				t.Errorf("X-Geo-Decision = %q, want %q", got, tt.wantDecision)
+			}
+			if got := rec.Header().Get(HeaderGeoCountry); got != tt.wantCountry {
+				t.Errorf("X-Geo-Country = %q, want %q", got, tt.wantCountry)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AuthzPolicyContextKey is the ext_authz context extension that selects a named
+// policy per route, overriding the server's default policy.
+const AuthzPolicyContextKey = "geofence_policy"
+
+// Response headers set on every decision so upstreams and clients can see it.
+const (
+	HeaderGeoCountry  = "x-geo-country"
+	HeaderGeoDecision = "x-geo-decision"
+	HeaderGeoReason   = "x-geo-reason"
+)
+
+// AuthzServer implements the Envoy ext_authz Authorization service. Envoy sends
+// the downstream address of each request; AuthzServer checks it against a policy
+// and tells Envoy to forward or reject the request.
+type AuthzServer struct {
+	authv3.UnimplementedAuthorizationServer
+	checker *geofence.Checker
+	policy  geofence.PolicyRef
+}
+
+// NewAuthzServer creates an AuthzServer that checks requests against policy. A
+// route can select a different named policy with the geofence_policy context
+// extension.
+func NewAuthzServer(checker *geofence.Checker, policy geofence.PolicyRef) *AuthzServer {
+	return &AuthzServer{checker: checker, policy: policy}
+}
+
+// Check implements authv3.AuthorizationServer. Allowed requests are forwarded with
+// the geo headers added upstream; denied requests, unknown IPs under the "error"
+// action, and requests that cannot be checked because the source address or the
+// policy is invalid are rejected with 403. Only server faults are returned as gRPC
+// errors, leaving them to Envoy's failure_mode_allow setting, so a configuration
+// mistake never lets traffic through.
+func (s *AuthzServer) Check(ctx context.Context, req *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+	attrs := req.GetAttributes()
+	ipAddress := attrs.GetSource().GetAddress().GetSocketAddress().GetAddress()
+
+	policy := s.policy
+	if name := attrs.GetContextExtensions()[AuthzPolicyContextKey]; name != "" {
+		policy = geofence.PolicyRef{Name: name}
+	}
+
+	result, err := s.checker.Evaluate(ctx, ipAddress, policy)
+	observeResult(ctx, result, err)
+	if err != nil {
+		if errors.Is(err, geofence.ErrUnknownIP) {
+			slog.Info("unknown IP", "ip_address", ipAddress, "err", err)
+			return deniedResponse(result), nil
+		}
+		if isValidationError(err) {
+			slog.Warn("authz validation error", "ip_address", ipAddress, "err", err)
+			return deniedResponse(result), nil
+		}
+		slog.Error("authz check failed", "err", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	if !result.Allowed {
+		return deniedResponse(result), nil
+	}
+	return &authv3.CheckResponse{
+		Status: &rpcstatus.Status{Code: int32(codes.OK)},
+		HttpResponse: &authv3.CheckResponse_OkResponse{
+			OkResponse: &authv3.OkHttpResponse{Headers: geoHeaders(result, "allowed")},
+		},
+	}, nil
+}
+
+func deniedResponse(result geofence.CheckResult) *authv3.CheckResponse {
+	return &authv3.CheckResponse{
+		Status: &rpcstatus.Status{Code: int32(codes.PermissionDenied)},
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{
+			DeniedResponse: &authv3.DeniedHttpResponse{
+				Status:  &typev3.HttpStatus{Code: typev3.StatusCode_Forbidden},
+				Headers: geoHeaders(result, "denied"),
+			},
+		},
+	}
+}
+
+// geoHeaders overwrites rather than appends, so a client cannot pass its own
+// x-geo-* values through to the upstream.
+func geoHeaders(result geofence.CheckResult, decision string) []*corev3.HeaderValueOption {
+	header := func(key, value string) *corev3.HeaderValueOption {
+		return &corev3.HeaderValueOption{
+			Header:       &corev3.HeaderValue{Key: key, Value: value},
+			AppendAction: corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+		}
+	}
+	return []*corev3.HeaderValueOption{
+		header(HeaderGeoCountry, result.Country),
+		header(HeaderGeoDecision, decision),
+		header(HeaderGeoReason, string(result.Reason)),
+	}
+}
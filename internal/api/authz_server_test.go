@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+	"google.golang.org/grpc/codes"
+)
+
+func authzRequest(ip string, contextExtensions map[string]string) *authv3.CheckRequest {
+	return &authv3.CheckRequest{Attributes: &authv3.AttributeContext{
+		Source: &authv3.AttributeContext_Peer{Address: &corev3.Address{
+			Address: &corev3.Address_SocketAddress{SocketAddress: &corev3.SocketAddress{Address: ip}},
+		}},
+		ContextExtensions: contextExtensions,
+	}}
+}
+
+func TestAuthzServer_Check(t *testing.T) {
+	lookup := mockLookuper{lookup: func(ip net.IP) (string, error) {
+		if ip.String() == "1.2.3.4" {
+			return "", geofence.ErrUnknownIP
+		}
+		return "US", nil
+	}}
+	checker := geofence.NewChecker(lookup, geofence.WithPolicies(map[string]geofence.Policy{
+		"us-only": {AllowedCountries: []string{"US"}},
+		"gb-only": {AllowedCountries: []string{"GB"}},
+		"strict":  {AllowedCountries: []string{"US"}, UnknownIPAction: geofence.UnknownIPError},
+	}))
+	server := NewAuthzServer(checker, geofence.PolicyRef{Name: "us-only"})
+
+	tests := []struct {
+		name         string
+		req          *authv3.CheckRequest
+		wantStatus   codes.Code
+		wantDecision string
+		wantCountry  string
+	}{
+		{
+			name:         "allowed",
+			req:          authzRequest("8.8.8.8", nil),
+			wantStatus:   codes.OK,
+			wantDecision: "allowed",
+			wantCountry:  "US",
+		},
+		{
+			name:         "route policy denies",
+			req:          authzRequest("8.8.8.8", map[string]string{AuthzPolicyContextKey: "gb-only"}),
+			wantStatus:   codes.PermissionDenied,
+			wantDecision: "denied",
+			wantCountry:  "US",
+		},
+		{
+			name:         "unknown IP with error action is denied",
+			req:          authzRequest("1.2.3.4", map[string]string{AuthzPolicyContextKey: "strict"}),
+			wantStatus:   codes.PermissionDenied,
+			wantDecision: "denied",
+		},
+		{
+			name:         "missing source address is denied",
+			req:          &authv3.CheckRequest{},
+			wantStatus:   codes.PermissionDenied,
+			wantDecision: "denied",
+		},
+		{
+			name:         "unknown route policy is denied",
+			req:          authzRequest("8.8.8.8", map[string]string{AuthzPolicyContextKey: "nope"}),
+			wantStatus:   codes.PermissionDenied,
+			wantDecision: "denied",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := server.Check(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("Check() unexpected error: %v", err)
+			}
+			if got := codes.Code(resp.GetStatus().GetCode()); got != tt.wantStatus {
+				t.Errorf("status = %v, want %v", got, tt.wantStatus)
+			}
+
+			headers := resp.GetOkResponse().GetHeaders()
+			if tt.wantStatus != codes.OK {
+				headers = resp.GetDeniedResponse().GetHeaders()
+				if got := resp.GetDeniedResponse().GetStatus().GetCode(); got != typev3.StatusCode_Forbidden {
+					t.Errorf("denied HTTP status = %v, want Forbidden", got)
+				}
+			}
+			got := make(map[string]string)
+			for _, h := range headers {
+				got[h.GetHeader().GetKey()] = h.GetHeader().GetValue()
+			}
+			if got[HeaderGeoDecision] != tt.wantDecision || got[HeaderGeoCountry] != tt.wantCountry {
+				t.Errorf("headers = %v, want decision=%q country=%q", got, tt.wantDecision, tt.wantCountry)
+			}
+		})
+	}
+}
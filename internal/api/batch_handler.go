@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+)
+
+// BatchCheckHandler handles POST /v1/check:batch requests.
+type BatchCheckHandler struct {
+	checker *geofence.Checker
+}
+
+// NewBatchCheckHandler creates a BatchCheckHandler with the given Checker.
+func NewBatchCheckHandler(checker *geofence.Checker) *BatchCheckHandler {
+	return &BatchCheckHandler{checker: checker}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *BatchCheckHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "method not allowed"})
+		return
+	}
+
+	var req BatchCheckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("failed to decode request body", "err", err)
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "malformed JSON"})
+		return
+	}
+
+	items := make([]geofence.BatchItem, len(req.Items))
+	for i, item := range req.Items {
+		items[i] = geofence.BatchItem{IP: item.IPAddress, Policy: item.policyRef()}
+	}
+
+	results, err := h.checker.CheckBatch(r.Context(), items, req.policyRef())
+	if err != nil {
+		if errors.Is(err, geofence.ErrEmptyBatch) || errors.Is(err, geofence.ErrBatchTooLarge) {
+			slog.Info("validation error", "items", len(req.Items), "err", err)
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+			return
+		}
+		slog.Error("batch check failed", "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "internal server error"})
+		return
+	}
+
+	resp := BatchCheckResponse{Results: make([]BatchCheckResult, len(results))}
+	for i, result := range results {
+		observeResult(r.Context(), result.CheckResult, result.Err)
+		resp.Results[i] = BatchCheckResult{
+			IPAddress:    req.Items[i].IPAddress,
+			Allowed:      result.Allowed,
+			Country:      result.Country,
+			DecidedBy:    string(result.DecidedBy),
+			IPStatus:     string(result.IPStatus),
+			Reason:       string(result.Reason),
+			MatchedRule:  result.MatchedRule,
+			Provider:     result.Provider,
+			Disagreement: newSourceAnswers(result.Disagreement),
+			Anonymity:    result.Anonymity.Names(),
+			Error:        batchItemError(req.Items[i].IPAddress, result.Err),
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// batchItemError converts a per-item Check error into the message reported to the
+// caller, matching the single-check mapping: validation errors and unknown IPs
+// (with unknown_ip_action "error") are reported verbatim, and anything else is
+// logged and reported as an internal error.
+func batchItemError(ipAddress string, err error) string {
+	if err == nil {
+		return ""
+	}
+	if isValidationError(err) || errors.Is(err, geofence.ErrUnknownIP) {
+		return err.Error()
+	}
+	slog.Error("batch item check failed", "ip_address", ipAddress, "err", err)
+	return "internal server error"
+}
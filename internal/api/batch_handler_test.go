@@ -0,0 +1,84 @@
+package api
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+)
+
+func TestBatchCheckHandler_ServeHTTP(t *testing.T) {
+	lookup := mockLookuper{lookup: func(ip net.IP) (string, error) {
+		if ip.String() == "192.168.1.1" {
+			return "", geofence.ErrUnknownIP
+		}
+		return "US", nil
+	}}
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "shared and per-item allow lists",
+			method:     http.MethodPost,
+			body:       `{"allowed_countries":["US"],"items":[{"ip_address":"8.8.8.8"},{"ip_address":"8.8.4.4","allowed_countries":["GB"]}]}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"results":[{"ip_address":"8.8.8.8","allowed":true,"country":"US","decided_by":"country","ip_status":"geolocated","reason":"COUNTRY_ALLOWED","matched_rule":"US"},{"ip_address":"8.8.4.4","allowed":false,"country":"US","decided_by":"country","ip_status":"geolocated","reason":"COUNTRY_NOT_IN_LIST"}]}`,
+		},
+		{
+			name:       "bad item does not fail batch",
+			method:     http.MethodPost,
+			body:       `{"allowed_countries":["US"],"items":[{"ip_address":"not-an-ip"},{"ip_address":"192.168.1.1"},{"ip_address":"8.8.8.8"}]}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"results":[{"ip_address":"not-an-ip","allowed":false,"country":"","error":"invalid IP: not-an-ip"},{"ip_address":"192.168.1.1","allowed":false,"country":"","ip_status":"private","reason":"PRIVATE_IP","matched_rule":"192.168.0.0/16"},{"ip_address":"8.8.8.8","allowed":true,"country":"US","decided_by":"country","ip_status":"geolocated","reason":"COUNTRY_ALLOWED","matched_rule":"US"}]}`,
+		},
+		{
+			name:       "empty batch",
+			method:     http.MethodPost,
+			body:       `{"allowed_countries":["US"],"items":[]}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"batch must contain at least one item"}`,
+		},
+		{
+			name:       "malformed JSON",
+			method:     http.MethodPost,
+			body:       `{invalid}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"malformed JSON"}`,
+		},
+		{
+			name:       "GET returns 405",
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+			wantBody:   `{"error":"method not allowed"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewBatchCheckHandler(geofence.NewChecker(lookup))
+
+			req := httptest.NewRequest(tt.method, "/v1/check:batch", bytes.NewBufferString(tt.body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := strings.TrimSuffix(rec.Body.String(), "\n"); got != tt.wantBody {
+				t.Errorf("body = %s, want %s", got, tt.wantBody)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+		})
+	}
+}
@@ -2,7 +2,6 @@ package api
 
 import (
 	"encoding/json"
-	"errors"
 	"log/slog"
 	"net/http"
 
@@ -12,11 +11,12 @@ import (
 // CheckHandler handles POST /v1/check requests.
 type CheckHandler struct {
 	checker *geofence.Checker
+	opts    options
 }
 
 // NewCheckHandler creates a CheckHandler with the given Checker.
-func NewCheckHandler(checker *geofence.Checker) *CheckHandler {
-	return &CheckHandler{checker: checker}
+func NewCheckHandler(checker *geofence.Checker, opts ...Option) *CheckHandler {
+	return &CheckHandler{checker: checker, opts: newOptions(opts)}
 }
 
 // ServeHTTP implements http.Handler.
@@ -37,25 +37,32 @@ func (h *CheckHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.checker.Check(req.IPAddress, req.AllowedCountries)
+	var callerIP string
+	if req.IPAddress == "" && h.opts.clientIP != nil {
+		callerIP, _ = h.opts.clientIP.FromRequest(r)
+		req.IPAddress = callerIP
+	}
+
+	result, err := h.checker.Evaluate(r.Context(), req.IPAddress, req.policyRef())
+	observeResult(r.Context(), result, err)
 	if err != nil {
-		if errors.Is(err, geofence.ErrUnknownIP) {
-			w.WriteHeader(http.StatusOK)
-			_ = json.NewEncoder(w).Encode(CheckResponse{Allowed: false, Country: ""})
-			return
-		}
-		if errors.Is(err, geofence.ErrEmptyAllowedCountries) || errors.Is(err, geofence.ErrInvalidIP) {
-			slog.Info("validation error", "ip_address", req.IPAddress, "err", err)
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
-			return
-		}
-		slog.Error("check failed", "err", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "internal server error"})
+		code, msg := checkErrorStatus(req.IPAddress, err)
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: msg, Fields: invalidFields(err)})
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(CheckResponse{Allowed: result.Allowed, Country: result.Country})
+	_ = json.NewEncoder(w).Encode(CheckResponse{
+		IPAddress:    callerIP,
+		Allowed:      result.Allowed,
+		Country:      result.Country,
+		DecidedBy:    string(result.DecidedBy),
+		IPStatus:     string(result.IPStatus),
+		Reason:       string(result.Reason),
+		MatchedRule:  result.MatchedRule,
+		Provider:     result.Provider,
+		Disagreement: newSourceAnswers(result.Disagreement),
+		Anonymity:    result.Anonymity.Names(),
+	})
 }
@@ -37,7 +37,7 @@ func TestCheckHandler_ServeHTTP(t *testing.T) {
 			body:   `{"ip_address":"8.8.8.8","allowed_countries":["US","CA"]}`,
 			mockLookup: func(net.IP) (string, error) { return "US", nil },
 			wantStatus:  http.StatusOK,
-			wantBody:    `{"allowed":true,"country":"US"}`,
+			wantBody:    `{"allowed":true,"country":"US","decided_by":"country","ip_status":"geolocated","reason":"COUNTRY_ALLOWED","matched_rule":"US"}`,
 			checkContentType: true,
 		},
 		{
@@ -46,7 +46,7 @@ func TestCheckHandler_ServeHTTP(t *testing.T) {
 			body:   `{"ip_address":"8.8.8.8","allowed_countries":["GB"]}`,
 			mockLookup: func(net.IP) (string, error) { return "US", nil },
 			wantStatus:     http.StatusOK,
-			wantBody:       `{"allowed":false,"country":"US"}`,
+			wantBody:       `{"allowed":false,"country":"US","decided_by":"country","ip_status":"geolocated","reason":"COUNTRY_NOT_IN_LIST"}`,
 			checkContentType: true,
 		},
 		{
@@ -86,10 +86,44 @@ func TestCheckHandler_ServeHTTP(t *testing.T) {
 		{
 			name:   "POST unknown IP returns 200 with allowed false",
 			method: http.MethodPost,
-			body:   `{"ip_address":"192.168.1.1","allowed_countries":["US"]}`,
+			body:   `{"ip_address":"1.2.3.4","allowed_countries":["US"]}`,
 			mockLookup: func(net.IP) (string, error) { return "", geofence.ErrUnknownIP },
 			wantStatus:     http.StatusOK,
-			wantBody:       `{"allowed":false,"country":""}`,
+			wantBody:       `{"allowed":false,"country":"","ip_status":"unknown","reason":"UNKNOWN_IP"}`,
+			checkContentType: true,
+		},
+		{
+			name:             "POST private IP reported as private",
+			method:           http.MethodPost,
+			body:             `{"ip_address":"192.168.1.1","allowed_countries":["US"]}`,
+			wantStatus:       http.StatusOK,
+			wantBody:         `{"allowed":false,"country":"","ip_status":"private","reason":"PRIVATE_IP","matched_rule":"192.168.0.0/16"}`,
+			checkContentType: true,
+		},
+		{
+			name:             "POST unknown IP allowed by unknown_ip_action",
+			method:           http.MethodPost,
+			body:             `{"ip_address":"1.2.3.4","allowed_countries":["US"],"unknown_ip_action":"allow"}`,
+			mockLookup:       func(net.IP) (string, error) { return "", geofence.ErrUnknownIP },
+			wantStatus:       http.StatusOK,
+			wantBody:         `{"allowed":true,"country":"","ip_status":"unknown","reason":"UNKNOWN_IP"}`,
+			checkContentType: true,
+		},
+		{
+			name:             "POST unknown IP with unknown_ip_action error returns 422",
+			method:           http.MethodPost,
+			body:             `{"ip_address":"1.2.3.4","allowed_countries":["US"],"unknown_ip_action":"error"}`,
+			mockLookup:       func(net.IP) (string, error) { return "", geofence.ErrUnknownIP },
+			wantStatus:       http.StatusUnprocessableEntity,
+			wantErrInBody:    true,
+			checkContentType: true,
+		},
+		{
+			name:             "POST invalid unknown_ip_action",
+			method:           http.MethodPost,
+			body:             `{"ip_address":"1.2.3.4","allowed_countries":["US"],"unknown_ip_action":"maybe"}`,
+			wantStatus:       http.StatusBadRequest,
+			wantErrInBody:    true,
 			checkContentType: true,
 		},
 	}
@@ -135,3 +169,99 @@ func TestCheckHandler_ServeHTTP(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckHandler_ServeHTTP_Policy(t *testing.T) {
+	lookup := mockLookuper{lookup: func(net.IP) (string, error) { return "US", nil }}
+	checker := geofence.NewChecker(lookup, geofence.WithPolicies(map[string]geofence.Policy{
+		"voice-signup": {AllowedCountries: []string{"US", "CA", "GB"}},
+	}))
+	handler := NewCheckHandler(checker)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "named policy",
+			body:       `{"ip_address":"8.8.8.8","policy":"voice-signup"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"allowed":true,"country":"US","decided_by":"country","ip_status":"geolocated","reason":"COUNTRY_ALLOWED","matched_rule":"US"}`,
+		},
+		{
+			name:       "inline deny list",
+			body:       `{"ip_address":"8.8.8.8","allowed_countries":["*"],"denied_countries":["US"]}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"allowed":false,"country":"US","decided_by":"country","ip_status":"geolocated","reason":"COUNTRY_DENIED","matched_rule":"US"}`,
+		},
+		{
+			name:       "network override beats country",
+			body:       `{"ip_address":"8.8.8.8","allowed_countries":["US"],"denied_networks":["8.8.8.0/24"]}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"allowed":false,"country":"","decided_by":"override","reason":"OVERRIDE_RULE","matched_rule":"8.8.8.0/24"}`,
+		},
+		{
+			name:       "invalid network",
+			body:       `{"ip_address":"8.8.8.8","allowed_countries":["US"],"denied_networks":["8.8.8.0/99"]}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"invalid network: 8.8.8.0/99"}`,
+		},
+		{
+			name:       "unknown policy",
+			body:       `{"ip_address":"8.8.8.8","policy":"nope"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"unknown policy: nope"}`,
+		},
+		{
+			name:       "policy and allowed_countries",
+			body:       `{"ip_address":"8.8.8.8","policy":"voice-signup","allowed_countries":["US"]}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"policy and inline country lists are mutually exclusive"}`,
+		},
+		{
+			name:       "invalid country codes",
+			body:       `{"ip_address":"8.8.8.8","allowed_countries":["USA","CA","United States"],"denied_countries":["U S"]}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"invalid country code: allowed_countries: \"USA\", \"United States\"; denied_countries: \"U S\"","fields":{"allowed_countries":["USA","United States"],"denied_countries":["U S"]}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/check", bytes.NewBufferString(tt.body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := strings.TrimSuffix(rec.Body.String(), "\n"); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+// providerLookuper is a mockLookuper that reports which provider answered.
+type providerLookuper struct {
+	mockLookuper
+}
+
+func (providerLookuper) Provider() string {
+	return geofence.ProviderIPinfo
+}
+
+func TestCheckHandler_ServeHTTP_Provider(t *testing.T) {
+	lookup := providerLookuper{mockLookuper{lookup: func(net.IP) (string, error) { return "US", nil }}}
+	handler := NewCheckHandler(geofence.NewChecker(lookup))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/check", bytes.NewBufferString(`{"ip_address":"8.8.8.8","allowed_countries":["US"]}`))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	want := `{"allowed":true,"country":"US","decided_by":"country","ip_status":"geolocated","reason":"COUNTRY_ALLOWED","matched_rule":"US","provider":"ipinfo"}`
+	if got := strings.TrimSuffix(rec.Body.String(), "\n"); got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
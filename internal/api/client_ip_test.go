@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/clientip"
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/pb"
+	"google.golang.org/grpc/peer"
+)
+
+func TestCheckHandler_ServeHTTP_CallerIP(t *testing.T) {
+	lookup := mockLookuper{lookup: func(ip net.IP) (string, error) {
+		if ip.String() == "81.2.69.142" {
+			return "GB", nil
+		}
+		return "US", nil
+	}}
+	resolver, _ := clientip.NewResolver(nil)
+	handler := NewCheckHandler(geofence.NewChecker(lookup), WithClientIP(resolver))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/check", strings.NewReader(`{"allowed_countries": ["GB"]}`))
+	req.RemoteAddr = "81.2.69.142:5555"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	want := `{"ip_address":"81.2.69.142","allowed":true,"country":"GB","decided_by":"country","ip_status":"geolocated","reason":"COUNTRY_ALLOWED","matched_rule":"GB"}`
+	if got := strings.TrimSuffix(rec.Body.String(), "\n"); rec.Code != http.StatusOK || got != want {
+		t.Errorf("got %d %s, want 200 %s", rec.Code, got, want)
+	}
+
+	// Without WithClientIP an empty ip_address is still a validation error.
+	req = httptest.NewRequest(http.MethodPost, "/v1/check", strings.NewReader(`{"allowed_countries": ["GB"]}`))
+	rec = httptest.NewRecorder()
+	NewCheckHandler(geofence.NewChecker(lookup)).ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status without WithClientIP = %d, want 400", rec.Code)
+	}
+}
+
+func TestGeoFenceServer_CheckAccess_CallerIP(t *testing.T) {
+	lookup := mockLookuper{lookup: func(net.IP) (string, error) { return "US", nil }}
+	resolver, _ := clientip.NewResolver(nil)
+	server := NewGeoFenceServer(geofence.NewChecker(lookup), WithClientIP(resolver))
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("8.8.8.8"), Port: 5555}})
+	resp, err := server.CheckAccess(ctx, &pb.CheckRequest{AllowedCountries: []string{"US"}})
+	if err != nil {
+		t.Fatalf("CheckAccess() unexpected error: %v", err)
+	}
+	if resp.IpAddress != "8.8.8.8" || !resp.Allowed {
+		t.Errorf("got ip_address=%q allowed=%v, want 8.8.8.8 true", resp.IpAddress, resp.Allowed)
+	}
+}
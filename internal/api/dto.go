@@ -1,18 +1,207 @@
 package api
 
+import "github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+
+// PolicyFields select the rules a check runs against: a named server-side policy,
+// or inline allowed/denied country and region lists and network overrides. They
+// are shared by check requests and batch items. UnknownIPAction ("allow", "deny"
+// or "error") may also be sent with a named policy to override its action.
+// DeniedAnonymity denies anonymous IPs with any of the listed flags, e.g. "vpn" or
+// "tor"; it needs an anonymous IP database on the server.
+type PolicyFields struct {
+	AllowedCountries []string `json:"allowed_countries"`
+	DeniedCountries  []string `json:"denied_countries,omitempty"`
+	AllowedNetworks  []string `json:"allowed_networks,omitempty"`
+	DeniedNetworks   []string `json:"denied_networks,omitempty"`
+	UnknownIPAction  string   `json:"unknown_ip_action,omitempty"`
+	Policy           string   `json:"policy,omitempty"`
+	AllowedRegions   []string `json:"allowed_regions,omitempty"`
+	DeniedRegions    []string `json:"denied_regions,omitempty"`
+	DeniedAnonymity  []string `json:"denied_anonymity,omitempty"`
+}
+
+func (f PolicyFields) policyRef() geofence.PolicyRef {
+	return geofence.PolicyRef{
+		Name: f.Policy,
+		Inline: geofence.Policy{
+			AllowedCountries: f.AllowedCountries,
+			DeniedCountries:  f.DeniedCountries,
+			AllowedNetworks:  f.AllowedNetworks,
+			DeniedNetworks:   f.DeniedNetworks,
+			UnknownIPAction:  geofence.UnknownIPAction(f.UnknownIPAction),
+			AllowedRegions:   f.AllowedRegions,
+			DeniedRegions:    f.DeniedRegions,
+			DeniedAnonymity:  f.DeniedAnonymity,
+		},
+	}
+}
+
 // CheckRequest is the JSON body for POST /v1/check.
 type CheckRequest struct {
-	IPAddress        string   `json:"ip_address"`
-	AllowedCountries []string `json:"allowed_countries"`
+	IPAddress string `json:"ip_address"`
+	PolicyFields
 }
 
 // CheckResponse is the JSON body returned on successful check.
+// DecidedBy is "country", "override" (a network override matched) or "anonymity"
+// (the IP has a flag the policy denies).
+// IPStatus is "geolocated", "unknown" (not in the database) or "private"
+// (private, loopback or reserved); it is omitted when an override decided first.
+// Reason is a code such as COUNTRY_ALLOWED or OVERRIDE_RULE, and MatchedRule is the
+// country code, "*", or network prefix that decided. IPAddress is only set when
+// the request omitted ip_address and the caller's own IP was checked. Provider is
+// the GeoIP provider that answered the lookup, e.g. "maxmind". Disagreement lists
+// every database's answer when several were consulted and they disagreed.
+// Anonymity lists the IP's anonymity flags, e.g. "vpn" or "hosting", when the
+// server has an anonymous IP database and the IP is listed in it.
 type CheckResponse struct {
-	Allowed bool   `json:"allowed"`
-	Country string `json:"country"`
+	IPAddress    string         `json:"ip_address,omitempty"`
+	Allowed      bool           `json:"allowed"`
+	Country      string         `json:"country"`
+	DecidedBy    string         `json:"decided_by,omitempty"`
+	IPStatus     string         `json:"ip_status,omitempty"`
+	Reason       string         `json:"reason,omitempty"`
+	MatchedRule  string         `json:"matched_rule,omitempty"`
+	Provider     string         `json:"provider,omitempty"`
+	Disagreement []SourceAnswer `json:"disagreement,omitempty"`
+	Anonymity    []string       `json:"anonymity,omitempty"`
 }
 
 // ErrorResponse is the JSON body returned on error.
 type ErrorResponse struct {
 	Error string `json:"error"`
+	// Fields lists the rejected entries of each request field, e.g. country codes
+	// that are not ISO 3166-1 alpha-2.
+	Fields map[string][]string `json:"fields,omitempty"`
+}
+
+// BatchCheckRequest is the JSON body for POST /v1/check:batch.
+// The embedded PolicyFields apply to every item that does not set its own.
+type BatchCheckRequest struct {
+	PolicyFields
+	Items []BatchCheckItem `json:"items"`
+}
+
+// BatchCheckItem is a single IP in a batch request.
+type BatchCheckItem struct {
+	IPAddress string `json:"ip_address"`
+	PolicyFields
+}
+
+// BatchCheckResponse is the JSON body returned by POST /v1/check:batch.
+// Results are in the same order as the request items.
+type BatchCheckResponse struct {
+	Results []BatchCheckResult `json:"results"`
+}
+
+// BatchCheckResult is the outcome for one batch item. Error is set when the item
+// could not be checked; the rest of the batch is unaffected.
+type BatchCheckResult struct {
+	IPAddress    string         `json:"ip_address"`
+	Allowed      bool           `json:"allowed"`
+	Country      string         `json:"country"`
+	DecidedBy    string         `json:"decided_by,omitempty"`
+	IPStatus     string         `json:"ip_status,omitempty"`
+	Reason       string         `json:"reason,omitempty"`
+	MatchedRule  string         `json:"matched_rule,omitempty"`
+	Provider     string         `json:"provider,omitempty"`
+	Disagreement []SourceAnswer `json:"disagreement,omitempty"`
+	Anonymity    []string       `json:"anonymity,omitempty"`
+	Error        string         `json:"error,omitempty"`
+}
+
+// LookupResponse is the JSON body returned by GET /v1/lookup/{ip}. IPStatus is
+// "geolocated", "unknown" or "private"; records the database has no data for are
+// omitted. Network is the database prefix that matched, or the reserved range for
+// private IPs. Provider is the GeoIP provider that answered, and Disagreement and
+// Anonymity are set as on CheckResponse.
+type LookupResponse struct {
+	IPAddress          string                  `json:"ip_address"`
+	IPStatus           string                  `json:"ip_status"`
+	Country            *CountryInfo            `json:"country,omitempty"`
+	RegisteredCountry  *CountryInfo            `json:"registered_country,omitempty"`
+	RepresentedCountry *RepresentedCountryInfo `json:"represented_country,omitempty"`
+	Continent          *ContinentInfo          `json:"continent,omitempty"`
+	Network            string                  `json:"network,omitempty"`
+	Provider           string                  `json:"provider,omitempty"`
+	Disagreement       []SourceAnswer          `json:"disagreement,omitempty"`
+	Anonymity          []string                `json:"anonymity,omitempty"`
+}
+
+// CountryInfo is a country with its name in the requested locale.
+type CountryInfo struct {
+	ISOCode           string `json:"iso_code"`
+	Name              string `json:"name,omitempty"`
+	IsInEuropeanUnion bool   `json:"is_in_european_union"`
+}
+
+// RepresentedCountryInfo is the country represented by e.g. a military base.
+type RepresentedCountryInfo struct {
+	CountryInfo
+	Type string `json:"type,omitempty"`
+}
+
+// ContinentInfo is a continent with its name in the requested locale.
+type ContinentInfo struct {
+	Code string `json:"code"`
+	Name string `json:"name,omitempty"`
+}
+
+func newLookupResponse(ipAddress string, result geofence.LookupResult) LookupResponse {
+	resp := LookupResponse{
+		IPAddress:    ipAddress,
+		IPStatus:     string(result.IPStatus),
+		Provider:     result.Provider,
+		Disagreement: newSourceAnswers(result.Disagreement),
+		Anonymity:    result.Anonymity.Names(),
+	}
+	if c := result.Country; c.ISOCode != "" {
+		resp.Country = &CountryInfo{ISOCode: c.ISOCode, Name: c.Name, IsInEuropeanUnion: c.IsInEuropeanUnion}
+	}
+	if c := result.RegisteredCountry; c.ISOCode != "" {
+		resp.RegisteredCountry = &CountryInfo{ISOCode: c.ISOCode, Name: c.Name, IsInEuropeanUnion: c.IsInEuropeanUnion}
+	}
+	if c := result.RepresentedCountry; c.ISOCode != "" {
+		resp.RepresentedCountry = &RepresentedCountryInfo{
+			CountryInfo: CountryInfo{ISOCode: c.ISOCode, Name: c.Name, IsInEuropeanUnion: c.IsInEuropeanUnion},
+			Type:        c.Type,
+		}
+	}
+	if c := result.Continent; c.Code != "" {
+		resp.Continent = &ContinentInfo{Code: c.Code, Name: c.Name}
+	}
+	if result.Network.IsValid() {
+		resp.Network = result.Network.String()
+	}
+	return resp
+}
+
+// SourceAnswer is one GeoIP database's answer for an IP. Country is empty when the
+// database does not know the IP.
+type SourceAnswer struct {
+	Source  string `json:"source"`
+	Country string `json:"country"`
+}
+
+func newSourceAnswers(answers []geofence.SourceAnswer) []SourceAnswer {
+	if answers == nil {
+		return nil
+	}
+	out := make([]SourceAnswer, len(answers))
+	for i, a := range answers {
+		out[i] = SourceAnswer{Source: a.Source, Country: a.Country}
+	}
+	return out
+}
+
+// RegionsResponse is the JSON body returned by GET /v1/regions, sorted by name.
+type RegionsResponse struct {
+	Regions []Region `json:"regions"`
+}
+
+// Region is a configured region and its member countries, returned by
+// GET /v1/regions/{name}.
+type Region struct {
+	Name      string   `json:"name"`
+	Countries []string `json:"countries"`
 }
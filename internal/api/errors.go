@@ -0,0 +1,82 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// isValidationError reports whether err was caused by a bad request rather than a
+// server fault. HTTP handlers map these to 400 and gRPC methods to InvalidArgument.
+func isValidationError(err error) bool {
+	return errors.Is(err, geofence.ErrEmptyAllowedCountries) ||
+		errors.Is(err, geofence.ErrInvalidIP) ||
+		errors.Is(err, geofence.ErrUnknownPolicy) ||
+		errors.Is(err, geofence.ErrPolicyConflict) ||
+		errors.Is(err, geofence.ErrInvalidNetwork) ||
+		errors.Is(err, geofence.ErrInvalidUnknownIPAction) ||
+		errors.Is(err, geofence.ErrUnsupportedLocale) ||
+		errors.Is(err, geofence.ErrUnknownRegion) ||
+		errors.Is(err, geofence.ErrInvalidCountryCode) ||
+		errors.Is(err, geofence.ErrInvalidAnonymity) ||
+		errors.Is(err, geofence.ErrAnonymityUnavailable)
+}
+
+// invalidFields returns the rejected entries of each request field for validation
+// errors that carry them, such as invalid country codes.
+func invalidFields(err error) map[string][]string {
+	var codeErr *geofence.CountryCodeError
+	if errors.As(err, &codeErr) {
+		return codeErr.Fields()
+	}
+	return nil
+}
+
+// validationStatus converts a validation error to InvalidArgument. Rejected field
+// entries are attached as a google.rpc.BadRequest detail.
+func validationStatus(err error) error {
+	st := status.New(codes.InvalidArgument, err.Error())
+	fields := invalidFields(err)
+	if len(fields) == 0 {
+		return st.Err()
+	}
+	badRequest := &errdetails.BadRequest{}
+	for _, field := range slices.Sorted(maps.Keys(fields)) {
+		for _, entry := range fields[field] {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       field,
+				Description: fmt.Sprintf("%q is not an ISO 3166-1 alpha-2 country code", entry),
+			})
+		}
+	}
+	detailed, detailErr := st.WithDetails(badRequest)
+	if detailErr != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// checkErrorStatus maps an error from Checker.Evaluate or Checker.Lookup to an HTTP status and the
+// message reported to the caller, logging it. Unknown IPs (with unknown_ip_action
+// "error") are 422, validation errors 400, and anything else is logged as a fault
+// and reported as a generic 500.
+func checkErrorStatus(ipAddress string, err error) (int, string) {
+	if errors.Is(err, geofence.ErrUnknownIP) {
+		slog.Info("unknown IP", "ip_address", ipAddress, "err", err)
+		return http.StatusUnprocessableEntity, err.Error()
+	}
+	if isValidationError(err) {
+		slog.Info("validation error", "ip_address", ipAddress, "err", err)
+		return http.StatusBadRequest, err.Error()
+	}
+	slog.Error("check failed", "err", err)
+	return http.StatusInternalServerError, "internal server error"
+}
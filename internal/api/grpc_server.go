@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"strings"
 
 	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
 	"github.com/jadenmounteer/avoxi-geo-fence/internal/pb"
@@ -15,26 +16,125 @@ import (
 type GeoFenceServer struct {
 	pb.UnimplementedGeoFenceServiceServer
 	checker *geofence.Checker
+	opts    options
 }
 
 // NewGeoFenceServer creates a GeoFenceServer with the given Checker.
-func NewGeoFenceServer(checker *geofence.Checker) *GeoFenceServer {
-	return &GeoFenceServer{checker: checker}
+func NewGeoFenceServer(checker *geofence.Checker, opts ...Option) *GeoFenceServer {
+	return &GeoFenceServer{checker: checker, opts: newOptions(opts)}
 }
 
 // CheckAccess checks whether the given IP is in one of the allowed countries.
+// Without an IP address, the caller's own IP is checked if WithClientIP is set.
 func (s *GeoFenceServer) CheckAccess(ctx context.Context, req *pb.CheckRequest) (*pb.CheckResponse, error) {
-	result, err := s.checker.Check(req.GetIpAddress(), req.GetAllowedCountries())
+	ipAddress := req.GetIpAddress()
+	var callerIP string
+	if ipAddress == "" && s.opts.clientIP != nil {
+		callerIP, _ = s.opts.clientIP.FromContext(ctx)
+		ipAddress = callerIP
+	}
+
+	result, err := s.checker.Evaluate(ctx, ipAddress, protoPolicyRef(req))
+	observeResult(ctx, result, err)
 	if err != nil {
 		if errors.Is(err, geofence.ErrUnknownIP) {
-			return &pb.CheckResponse{Allowed: false, Country: ""}, nil
+			return nil, status.Error(codes.NotFound, err.Error())
 		}
-		if errors.Is(err, geofence.ErrEmptyAllowedCountries) || errors.Is(err, geofence.ErrInvalidIP) {
-			return nil, status.Error(codes.InvalidArgument, err.Error())
+		if isValidationError(err) {
+			return nil, validationStatus(err)
 		}
 		slog.Error("check failed", "err", err)
 		return nil, status.Error(codes.Internal, "internal server error")
 	}
 
-	return &pb.CheckResponse{Allowed: result.Allowed, Country: result.Country}, nil
+	return &pb.CheckResponse{
+		IpAddress:    callerIP,
+		Allowed:      result.Allowed,
+		Country:      result.Country,
+		DecidedBy:    protoDecisionSource(result.DecidedBy),
+		IpStatus:     protoIPStatus(result.IPStatus),
+		Reason:       protoReason(result.Reason),
+		MatchedRule:  result.MatchedRule,
+		Provider:     result.Provider,
+		Disagreement: protoSourceAnswers(result.Disagreement),
+		Anonymity:    result.Anonymity.Names(),
+	}, nil
+}
+
+// BatchCheckAccess checks many IPs in one call and returns results in input order.
+// Per-item failures are reported in each result's error field.
+func (s *GeoFenceServer) BatchCheckAccess(ctx context.Context, req *pb.BatchCheckRequest) (*pb.BatchCheckResponse, error) {
+	items := make([]geofence.BatchItem, len(req.GetItems()))
+	for i, item := range req.GetItems() {
+		items[i] = geofence.BatchItem{
+			IP:     item.GetIpAddress(),
+			Policy: protoPolicyRef(item),
+		}
+	}
+
+	results, err := s.checker.CheckBatch(ctx, items, protoPolicyRef(req))
+	if err != nil {
+		if errors.Is(err, geofence.ErrEmptyBatch) || errors.Is(err, geofence.ErrBatchTooLarge) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		slog.Error("batch check failed", "err", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	resp := &pb.BatchCheckResponse{Results: make([]*pb.BatchCheckResult, len(results))}
+	for i, result := range results {
+		observeResult(ctx, result.CheckResult, result.Err)
+		ipAddress := items[i].IP
+		resp.Results[i] = &pb.BatchCheckResult{
+			IpAddress:    ipAddress,
+			Allowed:      result.Allowed,
+			Country:      result.Country,
+			DecidedBy:    protoDecisionSource(result.DecidedBy),
+			IpStatus:     protoIPStatus(result.IPStatus),
+			Reason:       protoReason(result.Reason),
+			MatchedRule:  result.MatchedRule,
+			Provider:     result.Provider,
+			Disagreement: protoSourceAnswers(result.Disagreement),
+			Anonymity:    result.Anonymity.Names(),
+			Error:        batchItemError(ipAddress, result.Err),
+		}
+	}
+	return resp, nil
+}
+
+// Lookup returns the database record for an IP without evaluating any rules.
+// Without an IP address, the caller's own IP is looked up if WithClientIP is set.
+func (s *GeoFenceServer) Lookup(ctx context.Context, req *pb.LookupRequest) (*pb.LookupResponse, error) {
+	ipAddress := req.GetIpAddress()
+	if ipAddress == "" && s.opts.clientIP != nil {
+		ipAddress, _ = s.opts.clientIP.FromContext(ctx)
+	}
+
+	result, err := s.checker.Lookup(ctx, ipAddress, req.GetLocale())
+	if err != nil {
+		if isValidationError(err) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		slog.Error("lookup failed", "err", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+	return protoLookupResponse(ipAddress, result), nil
+}
+
+// ListRegions returns the configured regions, or only the one named in the request.
+func (s *GeoFenceServer) ListRegions(_ context.Context, req *pb.ListRegionsRequest) (*pb.ListRegionsResponse, error) {
+	groups := s.checker.Groups()
+	if name := req.GetName(); name != "" {
+		countries, ok := groups.Members(name)
+		if !ok {
+			return nil, status.Errorf(codes.NotFound, "%v: %s", geofence.ErrUnknownRegion, name)
+		}
+		return &pb.ListRegionsResponse{Regions: []*pb.Region{{Name: strings.ToUpper(name), Countries: countries}}}, nil
+	}
+
+	resp := &pb.ListRegionsResponse{Regions: make([]*pb.Region, 0, len(groups))}
+	for _, name := range groups.Names() {
+		resp.Regions = append(resp.Regions, &pb.Region{Name: name, Countries: groups[name]})
+	}
+	return resp, nil
 }
@@ -3,10 +3,12 @@ package api
 import (
 	"context"
 	"net"
+	"slices"
 	"testing"
 
 	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
 	"github.com/jadenmounteer/avoxi-geo-fence/internal/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -53,6 +55,16 @@ func TestGeoFenceServer_CheckAccess(t *testing.T) {
 			wantAllowed: false,
 			wantCountry: "",
 		},
+		{
+			name: "unknown IP with error action",
+			req: &pb.CheckRequest{
+				IpAddress:        "1.2.3.4",
+				AllowedCountries: []string{"US"},
+				UnknownIpAction:  pb.UnknownIPAction_UNKNOWN_IP_ACTION_ERROR,
+			},
+			mockLookup: func(net.IP) (string, error) { return "", geofence.ErrUnknownIP },
+			wantCode:   codes.NotFound,
+		},
 		{
 			name: "invalid IP",
 			req: &pb.CheckRequest{
@@ -107,3 +119,169 @@ func TestGeoFenceServer_CheckAccess(t *testing.T) {
 		})
 	}
 }
+
+func TestGeoFenceServer_BatchCheckAccess(t *testing.T) {
+	lookup := mockLookuper{lookup: func(ip net.IP) (string, error) {
+		if ip.String() == "192.168.1.1" {
+			return "", geofence.ErrUnknownIP
+		}
+		return "US", nil
+	}}
+	server := NewGeoFenceServer(geofence.NewChecker(lookup))
+
+	resp, err := server.BatchCheckAccess(context.Background(), &pb.BatchCheckRequest{
+		AllowedCountries: []string{"US"},
+		Items: []*pb.BatchCheckItem{
+			{IpAddress: "8.8.8.8"},
+			{IpAddress: "8.8.4.4", AllowedCountries: []string{"GB"}},
+			{IpAddress: "not-an-ip"},
+			{IpAddress: "192.168.1.1"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("BatchCheckAccess() unexpected error: %v", err)
+	}
+
+	want := []struct {
+		allowed bool
+		country string
+		hasErr  bool
+	}{
+		{allowed: true, country: "US"},
+		{allowed: false, country: "US"},
+		{hasErr: true},
+		{allowed: false, country: ""},
+	}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("len(Results) = %d, want %d", len(resp.Results), len(want))
+	}
+	for i, w := range want {
+		got := resp.Results[i]
+		if (got.Error != "") != w.hasErr {
+			t.Errorf("Results[%d].Error = %q, want error=%v", i, got.Error, w.hasErr)
+		}
+		if got.Allowed != w.allowed || got.Country != w.country {
+			t.Errorf("Results[%d] = {%v %q}, want {%v %q}", i, got.Allowed, got.Country, w.allowed, w.country)
+		}
+	}
+
+	_, err = server.BatchCheckAccess(context.Background(), &pb.BatchCheckRequest{AllowedCountries: []string{"US"}})
+	if st, _ := status.FromError(err); st.Code() != codes.InvalidArgument {
+		t.Errorf("empty batch status code = %v, want InvalidArgument", st.Code())
+	}
+}
+
+func TestGeoFenceServer_CheckAccess_Policy(t *testing.T) {
+	lookup := mockLookuper{lookup: func(net.IP) (string, error) { return "US", nil }}
+	checker := geofence.NewChecker(lookup, geofence.WithPolicies(map[string]geofence.Policy{
+		"voice-signup": {AllowedCountries: []string{"US", "CA", "GB"}},
+	}))
+	server := NewGeoFenceServer(checker)
+
+	resp, err := server.CheckAccess(context.Background(), &pb.CheckRequest{IpAddress: "8.8.8.8", Policy: "voice-signup"})
+	if err != nil {
+		t.Fatalf("CheckAccess() unexpected error: %v", err)
+	}
+	if !resp.Allowed || resp.Country != "US" {
+		t.Errorf("got allowed=%v country=%q, want allowed=true country=US", resp.Allowed, resp.Country)
+	}
+	if resp.Reason != pb.Reason_REASON_COUNTRY_ALLOWED || resp.MatchedRule != "US" {
+		t.Errorf("got reason=%v matched_rule=%q, want REASON_COUNTRY_ALLOWED US", resp.Reason, resp.MatchedRule)
+	}
+
+	_, err = server.CheckAccess(context.Background(), &pb.CheckRequest{IpAddress: "8.8.8.8", Policy: "nope"})
+	if st, _ := status.FromError(err); st.Code() != codes.InvalidArgument {
+		t.Errorf("unknown policy status code = %v, want InvalidArgument", st.Code())
+	}
+}
+
+func TestGeoFenceServer_CheckAccessInvalidCountries(t *testing.T) {
+	lookup := mockLookuper{lookup: func(net.IP) (string, error) { return "US", nil }}
+	server := NewGeoFenceServer(geofence.NewChecker(lookup))
+
+	_, err := server.CheckAccess(context.Background(), &pb.CheckRequest{
+		IpAddress:        "8.8.8.8",
+		AllowedCountries: []string{"USA", "CA"},
+		DeniedCountries:  []string{"Cuba"},
+	})
+	st, _ := status.FromError(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("status code = %v, want InvalidArgument", st.Code())
+	}
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			violations = badRequest.GetFieldViolations()
+		}
+	}
+	if len(violations) != 2 ||
+		violations[0].GetField() != "allowed_countries" || violations[0].GetDescription() != `"USA" is not an ISO 3166-1 alpha-2 country code` ||
+		violations[1].GetField() != "denied_countries" {
+		t.Errorf("field violations = %v", violations)
+	}
+
+	lenient := NewGeoFenceServer(geofence.NewChecker(lookup, geofence.WithLenientCountries()))
+	resp, err := lenient.CheckAccess(context.Background(), &pb.CheckRequest{IpAddress: "8.8.8.8", AllowedCountries: []string{"USA"}})
+	if err != nil {
+		t.Fatalf("lenient CheckAccess() unexpected error: %v", err)
+	}
+	if !resp.GetAllowed() || resp.GetMatchedRule() != "US" {
+		t.Errorf("lenient CheckAccess() = %v, want allowed by US", resp)
+	}
+}
+
+func TestGeoFenceServer_CheckAccessDisagreement(t *testing.T) {
+	source := func(name, country string) geofence.Source {
+		return geofence.Source{Name: name, Lookup: mockLookuper{lookup: func(net.IP) (string, error) { return country, nil }}}
+	}
+	lookup, err := geofence.NewConsensusLookuper(geofence.StrategyDenyAny, source("maxmind", "US"), source("ipinfo", "IR"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewGeoFenceServer(geofence.NewChecker(lookup))
+
+	resp, err := server.CheckAccess(context.Background(), &pb.CheckRequest{
+		IpAddress:        "8.8.8.8",
+		AllowedCountries: []string{"*"},
+		DeniedCountries:  []string{"IR"},
+	})
+	if err != nil {
+		t.Fatalf("CheckAccess() unexpected error: %v", err)
+	}
+	if resp.GetAllowed() || resp.GetCountry() != "IR" || resp.GetProvider() != "ipinfo" {
+		t.Errorf("CheckAccess() = allowed %v, country %q, provider %q, want denied by ipinfo's IR", resp.GetAllowed(), resp.GetCountry(), resp.GetProvider())
+	}
+	answers := resp.GetDisagreement()
+	if len(answers) != 2 || answers[0].GetSource() != "maxmind" || answers[0].GetCountry() != "US" || answers[1].GetCountry() != "IR" {
+		t.Errorf("Disagreement = %v, want maxmind US and ipinfo IR", answers)
+	}
+}
+
+func TestGeoFenceServer_CheckAccessAnonymity(t *testing.T) {
+	lookup := mockLookuper{lookup: func(net.IP) (string, error) { return "US", nil }}
+	server := NewGeoFenceServer(geofence.NewChecker(lookup, geofence.WithAnonymity(anonymityLookuper{})))
+
+	resp, err := server.CheckAccess(context.Background(), &pb.CheckRequest{
+		IpAddress:        "8.8.8.8",
+		AllowedCountries: []string{"US"},
+		DeniedAnonymity:  []string{"tor"},
+	})
+	if err != nil {
+		t.Fatalf("CheckAccess() unexpected error: %v", err)
+	}
+	if resp.GetAllowed() || resp.GetDecidedBy() != pb.DecisionSource_DECISION_SOURCE_ANONYMITY || resp.GetReason() != pb.Reason_REASON_ANONYMOUS_IP {
+		t.Errorf("CheckAccess() = allowed %v, decided by %v, reason %v, want denied for anonymity", resp.GetAllowed(), resp.GetDecidedBy(), resp.GetReason())
+	}
+	if got := resp.GetAnonymity(); !slices.Equal(got, []string{"anonymous", "tor"}) {
+		t.Errorf("Anonymity = %v, want [anonymous tor]", got)
+	}
+
+	_, err = NewGeoFenceServer(geofence.NewChecker(lookup)).CheckAccess(context.Background(), &pb.CheckRequest{
+		IpAddress:        "8.8.8.8",
+		AllowedCountries: []string{"US"},
+		DeniedAnonymity:  []string{"tor"},
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("CheckAccess() without an anonymous IP database error = %v, want InvalidArgument", err)
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+)
+
+// LookupHandler handles GET /v1/lookup/{ip} requests, returning the database
+// record for an IP without evaluating any rules. The optional locale query
+// parameter selects the language of names.
+type LookupHandler struct {
+	checker *geofence.Checker
+}
+
+// NewLookupHandler creates a LookupHandler with the given Checker.
+func NewLookupHandler(checker *geofence.Checker) *LookupHandler {
+	return &LookupHandler{checker: checker}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *LookupHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "method not allowed"})
+		return
+	}
+
+	ipAddress := r.PathValue("ip")
+	result, err := h.checker.Lookup(r.Context(), ipAddress, r.URL.Query().Get("locale"))
+	if err != nil {
+		code, msg := checkErrorStatus(ipAddress, err)
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: msg})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(newLookupResponse(ipAddress, result))
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+type mockInfoLookuper struct {
+	mockLookuper
+}
+
+func (mockInfoLookuper) LookupInfo(ip net.IP, locale string) (geofence.GeoInfo, error) {
+	if ip.String() != "81.2.69.142" {
+		return geofence.GeoInfo{}, geofence.ErrUnknownIP
+	}
+	name := "United Kingdom"
+	if locale == "de" {
+		name = "Vereinigtes Königreich"
+	}
+	gb := geofence.Country{ISOCode: "GB", Name: name}
+	return geofence.GeoInfo{
+		Country:           gb,
+		RegisteredCountry: gb,
+		Continent:         geofence.Continent{Code: "EU", Name: "Europe"},
+		Network:           netip.MustParsePrefix("81.2.69.0/24"),
+	}, nil
+}
+
+func TestLookupHandler_ServeHTTP(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/v1/lookup/{ip}", NewLookupHandler(geofence.NewChecker(mockInfoLookuper{})))
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "geolocated",
+			method:     http.MethodGet,
+			target:     "/v1/lookup/81.2.69.142",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"ip_address":"81.2.69.142","ip_status":"geolocated","country":{"iso_code":"GB","name":"United Kingdom","is_in_european_union":false},"registered_country":{"iso_code":"GB","name":"United Kingdom","is_in_european_union":false},"continent":{"code":"EU","name":"Europe"},"network":"81.2.69.0/24"}`,
+		},
+		{
+			name:       "locale",
+			method:     http.MethodGet,
+			target:     "/v1/lookup/81.2.69.142?locale=de",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"ip_address":"81.2.69.142","ip_status":"geolocated","country":{"iso_code":"GB","name":"Vereinigtes Königreich","is_in_european_union":false},"registered_country":{"iso_code":"GB","name":"Vereinigtes Königreich","is_in_european_union":false},"continent":{"code":"EU","name":"Europe"},"network":"81.2.69.0/24"}`,
+		},
+		{
+			name:       "unknown",
+			method:     http.MethodGet,
+			target:     "/v1/lookup/8.8.4.4",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"ip_address":"8.8.4.4","ip_status":"unknown"}`,
+		},
+		{
+			name:       "private IPv6",
+			method:     http.MethodGet,
+			target:     "/v1/lookup/fd00::1",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"ip_address":"fd00::1","ip_status":"private","network":"fc00::/7"}`,
+		},
+		{
+			name:       "invalid IP",
+			method:     http.MethodGet,
+			target:     "/v1/lookup/not-an-ip",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"invalid IP: not-an-ip"}`,
+		},
+		{
+			name:       "unsupported locale",
+			method:     http.MethodGet,
+			target:     "/v1/lookup/81.2.69.142?locale=xx",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"unsupported locale: xx"}`,
+		},
+		{
+			name:       "wrong method",
+			method:     http.MethodPost,
+			target:     "/v1/lookup/81.2.69.142",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantBody:   `{"error":"method not allowed"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.wantBody {
+				t.Errorf("body = %s, want %s", got, tt.wantBody)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+		})
+	}
+}
+
+func TestGeoFenceServer_Lookup(t *testing.T) {
+	srv := NewGeoFenceServer(geofence.NewChecker(mockInfoLookuper{}))
+
+	resp, err := srv.Lookup(context.Background(), &pb.LookupRequest{IpAddress: "81.2.69.142", Locale: "de"})
+	if err != nil {
+		t.Fatalf("Lookup() unexpected error: %v", err)
+	}
+	gb := &pb.CountryInfo{IsoCode: "GB", Name: "Vereinigtes Königreich"}
+	want := &pb.LookupResponse{
+		IpAddress:         "81.2.69.142",
+		IpStatus:          pb.IPStatus_IP_STATUS_GEOLOCATED,
+		Country:           gb,
+		RegisteredCountry: gb,
+		Continent:         &pb.ContinentInfo{Code: "EU", Name: "Europe"},
+		Network:           "81.2.69.0/24",
+	}
+	if !proto.Equal(resp, want) {
+		t.Errorf("Lookup() = %v, want %v", resp, want)
+	}
+
+	for _, req := range []*pb.LookupRequest{
+		{IpAddress: "bogus"},
+		{IpAddress: "81.2.69.142", Locale: "xx"},
+	} {
+		_, err := srv.Lookup(context.Background(), req)
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Lookup(%v) code = %v, want InvalidArgument", req, status.Code(err))
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/pb"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	transportHTTP = "http"
+	transportGRPC = "grpc"
+)
+
+// Metrics holds the Prometheus collectors for both transports. HTTP handlers are
+// instrumented with Middleware and gRPC methods with UnaryServerInterceptor; both
+// share the same collectors so dashboards can compare transports directly.
+type Metrics struct {
+	requests      *prometheus.CounterVec
+	duration      *prometheus.HistogramVec
+	decisions     *prometheus.CounterVec
+	unknownIPs    *prometheus.CounterVec
+	errors        *prometheus.CounterVec
+	disagreements *prometheus.CounterVec
+	anonymous     *prometheus.CounterVec
+}
+
+// NewMetrics creates the collectors and registers them with reg. If store is
+// non-nil, the build time of the loaded database is exported as a gauge that
+// follows reloads.
+func NewMetrics(reg prometheus.Registerer, store *geofence.GeoStore) *Metrics {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "geofence_requests_total",
+			Help: "Requests handled, by transport, route or method, and status code.",
+		}, []string{"transport", "route", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "geofence_request_duration_seconds",
+			Help:    "Request latency, by transport and route or method.",
+			Buckets: []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25},
+		}, []string{"transport", "route"}),
+		decisions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "geofence_decisions_total",
+			Help: "Geo-fence decisions, by outcome and country (\"none\" when the IP has no country).",
+		}, []string{"decision", "country"}),
+		unknownIPs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "geofence_unknown_ips_total",
+			Help: "Checked IPs without a country, by status (unknown or private).",
+		}, []string{"status"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "geofence_errors_total",
+			Help: "Failed requests, by transport and error type.",
+		}, []string{"transport", "type"}),
+		disagreements: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "geofence_source_disagreements_total",
+			Help: "Checks where a GeoIP database reported a country other than the one the consensus strategy chose, by database and the country it reported.",
+		}, []string{"source", "country"}),
+		anonymous: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "geofence_anonymous_ips_total",
+			Help: "Checked IPs listed in the anonymous IP database, by flag; an IP with several flags is counted once per flag.",
+		}, []string{"flag"}),
+	}
+	reg.MustRegister(m.requests, m.duration, m.decisions, m.unknownIPs, m.errors, m.disagreements, m.anonymous)
+
+	if store != nil {
+		reg.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "geofence_database_build_epoch_seconds",
+			Help: "Unix time the loaded GeoIP database was built.",
+		}, func() float64 {
+			return float64(store.BuildEpoch().Unix())
+		}))
+	}
+	return m
+}
+
+// RegisterCacheMetrics exports the hit, miss and eviction counts and size of the
+// lookup caches, summed when there is one per database.
+func RegisterCacheMetrics(reg prometheus.Registerer, caches ...*geofence.CachedLookuper) {
+	stats := func() geofence.CacheStats {
+		var total geofence.CacheStats
+		for _, cache := range caches {
+			s := cache.Stats()
+			total.Hits += s.Hits
+			total.Misses += s.Misses
+			total.Evictions += s.Evictions
+			total.Entries += s.Entries
+		}
+		return total
+	}
+	reg.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "geofence_cache_hits_total",
+			Help: "Country lookups answered from the lookup cache.",
+		}, func() float64 { return float64(stats().Hits) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "geofence_cache_misses_total",
+			Help: "Country lookups that went to the database.",
+		}, func() float64 { return float64(stats().Misses) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "geofence_cache_evictions_total",
+			Help: "Lookup cache entries evicted to stay within the size limit.",
+		}, func() float64 { return float64(stats().Evictions) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "geofence_cache_entries",
+			Help: "Networks currently held in the lookup cache.",
+		}, func() float64 { return float64(stats().Entries) }),
+	)
+}
+
+type metricsKey struct{}
+
+// observeResult records a check against the Metrics carried by ctx, if any.
+// Unknown and private IPs are counted even when err is ErrUnknownIP; a decision
+// is only counted when err is nil, and so are the databases that disagreed with it
+// and the IP's anonymity flags.
+func observeResult(ctx context.Context, result geofence.CheckResult, err error) {
+	m, ok := ctx.Value(metricsKey{}).(*Metrics)
+	if !ok {
+		return
+	}
+	if result.IPStatus == geofence.IPStatusUnknown || result.IPStatus == geofence.IPStatusPrivate {
+		m.unknownIPs.WithLabelValues(string(result.IPStatus)).Inc()
+	}
+	if err != nil {
+		return
+	}
+	decision := "denied"
+	if result.Allowed {
+		decision = "allowed"
+	}
+	country := result.Country
+	if country == "" {
+		country = "none"
+	}
+	m.decisions.WithLabelValues(decision, country).Inc()
+	for _, a := range result.Disagreement {
+		if a.Country != "" && !a.Agrees {
+			m.disagreements.WithLabelValues(a.Source, a.Country).Inc()
+		}
+	}
+	for _, flag := range result.Anonymity.Names() {
+		m.anonymous.WithLabelValues(flag).Inc()
+	}
+}
+
+// Middleware records request count, latency and errors for an HTTP handler. The
+// route label is the ServeMux pattern that matched. A 403 is a denial from
+// /v1/authz rather than a failure, so it is not counted as an error.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		r = r.WithContext(context.WithValue(r.Context(), metricsKey{}, m))
+		next.ServeHTTP(recorder, r)
+
+		route := r.Pattern
+		m.requests.WithLabelValues(transportHTTP, route, strconv.Itoa(recorder.status)).Inc()
+		m.duration.WithLabelValues(transportHTTP, route).Observe(time.Since(start).Seconds())
+		if recorder.status >= http.StatusBadRequest && recorder.status != http.StatusForbidden {
+			m.errors.WithLabelValues(transportHTTP, httpErrorType(recorder.status)).Inc()
+		}
+	})
+}
+
+// UnaryServerInterceptor records request count, latency and errors for gRPC methods.
+func (m *Metrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(context.WithValue(ctx, metricsKey{}, m), req)
+
+		code := status.Code(err)
+		m.requests.WithLabelValues(transportGRPC, info.FullMethod, code.String()).Inc()
+		m.duration.WithLabelValues(transportGRPC, info.FullMethod).Observe(time.Since(start).Seconds())
+		if code != codes.OK {
+			m.errors.WithLabelValues(transportGRPC, grpcErrorType(info.FullMethod, code)).Inc()
+		}
+		return resp, err
+	}
+}
+
+// httpErrorType maps an HTTP error status to the error type label shared with gRPC.
+func httpErrorType(code int) string {
+	switch code {
+	case http.StatusBadRequest:
+		return "invalid_argument"
+	case http.StatusNotFound:
+		return "unknown_region" // only /v1/regions/{name} returns 404
+	case http.StatusUnprocessableEntity:
+		return "unknown_ip"
+	case http.StatusMethodNotAllowed:
+		return "method_not_allowed"
+	case http.StatusServiceUnavailable:
+		return "unavailable"
+	default:
+		if code >= http.StatusInternalServerError {
+			return "internal"
+		}
+		return "other"
+	}
+}
+
+// grpcErrorType maps a gRPC status code to the error type label shared with HTTP.
+// NotFound is an unknown region from ListRegions and an unknown IP elsewhere.
+func grpcErrorType(method string, code codes.Code) string {
+	switch code {
+	case codes.InvalidArgument:
+		return "invalid_argument"
+	case codes.NotFound:
+		if method == pb.GeoFenceService_ListRegions_FullMethodName {
+			return "unknown_region"
+		}
+		return "unknown_ip"
+	case codes.Unavailable:
+		return "unavailable"
+	case codes.Internal:
+		return "internal"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,249 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/pb"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+)
+
+func TestMetrics_Middleware(t *testing.T) {
+	lookup := mockLookuper{lookup: func(ip net.IP) (string, error) {
+		if ip.String() == "1.2.3.4" {
+			return "", geofence.ErrUnknownIP
+		}
+		return "US", nil
+	}}
+	metrics := NewMetrics(prometheus.NewRegistry(), nil)
+	mux := http.NewServeMux()
+	mux.Handle("/v1/check", metrics.Middleware(NewCheckHandler(geofence.NewChecker(lookup))))
+
+	bodies := []string{
+		`{"ip_address": "8.8.8.8", "allowed_countries": ["US"]}`,
+		`{"ip_address": "8.8.8.8", "allowed_countries": ["GB"]}`,
+		`{"ip_address": "1.2.3.4", "allowed_countries": ["US"]}`,
+		`{"ip_address": "10.0.0.1", "allowed_countries": ["US"], "unknown_ip_action": "error"}`,
+		`{"ip_address": "not-an-ip", "allowed_countries": ["US"]}`,
+	}
+	for _, body := range bodies {
+		req := httptest.NewRequest(http.MethodPost, "/v1/check", strings.NewReader(body))
+		mux.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	counters := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"requests 200", testutil.ToFloat64(metrics.requests.WithLabelValues("http", "/v1/check", "200")), 3},
+		{"requests 422", testutil.ToFloat64(metrics.requests.WithLabelValues("http", "/v1/check", "422")), 1},
+		{"requests 400", testutil.ToFloat64(metrics.requests.WithLabelValues("http", "/v1/check", "400")), 1},
+		{"allowed US", testutil.ToFloat64(metrics.decisions.WithLabelValues("allowed", "US")), 1},
+		{"denied US", testutil.ToFloat64(metrics.decisions.WithLabelValues("denied", "US")), 1},
+		{"denied none", testutil.ToFloat64(metrics.decisions.WithLabelValues("denied", "none")), 1},
+		{"unknown IPs", testutil.ToFloat64(metrics.unknownIPs.WithLabelValues("unknown")), 1},
+		{"private IPs", testutil.ToFloat64(metrics.unknownIPs.WithLabelValues("private")), 1},
+		{"unknown_ip errors", testutil.ToFloat64(metrics.errors.WithLabelValues("http", "unknown_ip")), 1},
+		{"invalid_argument errors", testutil.ToFloat64(metrics.errors.WithLabelValues("http", "invalid_argument")), 1},
+	}
+	for _, c := range counters {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+	if n := testutil.CollectAndCount(metrics.duration); n != 1 {
+		t.Errorf("duration series = %d, want 1", n)
+	}
+
+	metrics = NewMetrics(prometheus.NewRegistry(), nil)
+	mux = http.NewServeMux()
+	mux.Handle("/v1/regions/{name}", metrics.Middleware(NewRegionsHandler(testGroups)))
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/regions/atlantis", nil))
+	if got := testutil.ToFloat64(metrics.errors.WithLabelValues("http", "unknown_region")); got != 1 {
+		t.Errorf("unknown_region errors = %v, want 1", got)
+	}
+}
+
+func TestMetrics_UnaryServerInterceptor(t *testing.T) {
+	lookup := mockLookuper{lookup: func(net.IP) (string, error) { return "US", nil }}
+	server := NewGeoFenceServer(geofence.NewChecker(lookup))
+	metrics := NewMetrics(prometheus.NewRegistry(), nil)
+	interceptor := metrics.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/geofence.v1.GeoFenceService/CheckAccess"}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return server.CheckAccess(ctx, req.(*pb.CheckRequest))
+	}
+
+	_, _ = interceptor(context.Background(), &pb.CheckRequest{IpAddress: "8.8.8.8", AllowedCountries: []string{"US"}}, info, handler)
+	_, _ = interceptor(context.Background(), &pb.CheckRequest{IpAddress: "not-an-ip", AllowedCountries: []string{"US"}}, info, handler)
+
+	if got := testutil.ToFloat64(metrics.requests.WithLabelValues("grpc", info.FullMethod, "OK")); got != 1 {
+		t.Errorf("OK requests = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.requests.WithLabelValues("grpc", info.FullMethod, "InvalidArgument")); got != 1 {
+		t.Errorf("InvalidArgument requests = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.errors.WithLabelValues("grpc", "invalid_argument")); got != 1 {
+		t.Errorf("invalid_argument errors = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.decisions.WithLabelValues("allowed", "US")); got != 1 {
+		t.Errorf("allowed US decisions = %v, want 1", got)
+	}
+
+	regionsInfo := &grpc.UnaryServerInfo{FullMethod: pb.GeoFenceService_ListRegions_FullMethodName}
+	regionsHandler := func(ctx context.Context, req any) (any, error) {
+		return server.ListRegions(ctx, req.(*pb.ListRegionsRequest))
+	}
+	_, _ = interceptor(context.Background(), &pb.ListRegionsRequest{Name: "atlantis"}, regionsInfo, regionsHandler)
+	if got := testutil.ToFloat64(metrics.errors.WithLabelValues("grpc", "unknown_region")); got != 1 {
+		t.Errorf("unknown_region errors = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.errors.WithLabelValues("grpc", "unknown_ip")); got != 0 {
+		t.Errorf("unknown_ip errors = %v, want 0", got)
+	}
+}
+
+func TestRegisterCacheMetrics(t *testing.T) {
+	lookup := mockLookuper{lookup: func(net.IP) (string, error) { return "US", nil }}
+	cache := geofence.NewCachedLookuper(lookup, 1, 0)
+	for _, ip := range []string{"8.8.8.8", "8.8.8.8", "8.8.4.4"} {
+		_, _ = cache.Lookup(net.ParseIP(ip))
+	}
+
+	registry := prometheus.NewRegistry()
+	RegisterCacheMetrics(registry, cache)
+	want := `
+# HELP geofence_cache_entries Networks currently held in the lookup cache.
+# TYPE geofence_cache_entries gauge
+geofence_cache_entries 1
+# HELP geofence_cache_evictions_total Lookup cache entries evicted to stay within the size limit.
+# TYPE geofence_cache_evictions_total counter
+geofence_cache_evictions_total 1
+# HELP geofence_cache_hits_total Country lookups answered from the lookup cache.
+# TYPE geofence_cache_hits_total counter
+geofence_cache_hits_total 1
+# HELP geofence_cache_misses_total Country lookups that went to the database.
+# TYPE geofence_cache_misses_total counter
+geofence_cache_misses_total 2
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMetrics_Disagreements(t *testing.T) {
+	source := func(name, country string) geofence.Source {
+		return geofence.Source{Name: name, Lookup: mockLookuper{lookup: func(net.IP) (string, error) { return country, nil }}}
+	}
+	lookup, err := geofence.NewConsensusLookuper(geofence.StrategyMajority, source("maxmind", "US"), source("ipinfo", "CA"), source("dbip", "CA"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics := NewMetrics(prometheus.NewRegistry(), nil)
+	mux := http.NewServeMux()
+	mux.Handle("/v1/check", metrics.Middleware(NewCheckHandler(geofence.NewChecker(lookup))))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/check", strings.NewReader(`{"ip_address":"8.8.8.8","allowed_countries":["CA"]}`))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	want := `{"allowed":true,"country":"CA","decided_by":"country","ip_status":"geolocated","reason":"COUNTRY_ALLOWED","matched_rule":"CA","provider":"ipinfo,dbip",` +
+		`"disagreement":[{"source":"maxmind","country":"US"},{"source":"ipinfo","country":"CA"},{"source":"dbip","country":"CA"}]}`
+	if got := strings.TrimSuffix(rec.Body.String(), "\n"); got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+	if got := testutil.ToFloat64(metrics.disagreements.WithLabelValues("maxmind", "US")); got != 1 {
+		t.Errorf("maxmind disagreements = %v, want 1", got)
+	}
+	if n := testutil.CollectAndCount(metrics.disagreements); n != 1 {
+		t.Errorf("disagreement series = %d, want 1", n)
+	}
+
+	// Under deny_any the result reports the denying source's country, but the
+	// source that disagreed is still the one the strategy did not choose.
+	denyAny, err := geofence.NewConsensusLookuper(geofence.StrategyDenyAny, source("maxmind", "US"), source("ipinfo", "IR"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics = NewMetrics(prometheus.NewRegistry(), nil)
+	mux = http.NewServeMux()
+	mux.Handle("/v1/check", metrics.Middleware(NewCheckHandler(geofence.NewChecker(denyAny))))
+	req = httptest.NewRequest(http.MethodPost, "/v1/check", strings.NewReader(`{"ip_address":"8.8.8.8","allowed_countries":["*"],"denied_countries":["IR"]}`))
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+	if got := testutil.ToFloat64(metrics.disagreements.WithLabelValues("ipinfo", "IR")); got != 1 {
+		t.Errorf("ipinfo disagreements = %v, want 1", got)
+	}
+	if n := testutil.CollectAndCount(metrics.disagreements); n != 1 {
+		t.Errorf("deny_any disagreement series = %d, want 1", n)
+	}
+}
+
+// anonymityLookuper flags every IP as a Tor exit.
+type anonymityLookuper struct{}
+
+func (anonymityLookuper) LookupAnonymity(net.IP) (geofence.Anonymity, error) {
+	return geofence.AnonymityAnonymous | geofence.AnonymityTor, nil
+}
+
+func TestMetrics_Anonymity(t *testing.T) {
+	lookup := mockLookuper{lookup: func(net.IP) (string, error) { return "US", nil }}
+	metrics := NewMetrics(prometheus.NewRegistry(), nil)
+	mux := http.NewServeMux()
+	mux.Handle("/v1/check", metrics.Middleware(NewCheckHandler(geofence.NewChecker(lookup, geofence.WithAnonymity(anonymityLookuper{})))))
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+		want     string
+	}{
+		{
+			name:     "flagged",
+			body:     `{"ip_address":"8.8.8.8","allowed_countries":["US"]}`,
+			wantCode: http.StatusOK,
+			want:     `{"allowed":true,"country":"US","decided_by":"country","ip_status":"geolocated","reason":"COUNTRY_ALLOWED","matched_rule":"US","anonymity":["anonymous","tor"]}`,
+		},
+		{
+			name:     "denied",
+			body:     `{"ip_address":"8.8.8.8","allowed_countries":["US"],"denied_anonymity":["tor"]}`,
+			wantCode: http.StatusOK,
+			want:     `{"allowed":false,"country":"US","decided_by":"anonymity","ip_status":"geolocated","reason":"ANONYMOUS_IP","matched_rule":"tor","anonymity":["anonymous","tor"]}`,
+		},
+		{
+			name:     "invalid flag",
+			body:     `{"ip_address":"8.8.8.8","allowed_countries":["US"],"denied_anonymity":["proxy"]}`,
+			wantCode: http.StatusBadRequest,
+			want:     `{"error":"invalid anonymity flag: \"proxy\" (want anonymous, vpn, hosting, public_proxy, residential_proxy, tor)"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/check", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if got := strings.TrimSuffix(rec.Body.String(), "\n"); got != tt.want {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := testutil.ToFloat64(metrics.anonymous.WithLabelValues("tor")); got != 2 {
+		t.Errorf("tor IPs = %v, want 2", got)
+	}
+	if n := testutil.CollectAndCount(metrics.anonymous); n != 2 {
+		t.Errorf("anonymity series = %d, want 2", n)
+	}
+}
@@ -0,0 +1,27 @@
+package api
+
+import "github.com/jadenmounteer/avoxi-geo-fence/internal/clientip"
+
+// Option configures optional behavior of CheckHandler and GeoFenceServer.
+type Option func(*options)
+
+type options struct {
+	clientIP *clientip.Resolver
+}
+
+// WithClientIP makes check requests without an IP address check the caller's own
+// IP instead, as derived by resolver. Without it such requests are rejected as
+// invalid.
+func WithClientIP(resolver *clientip.Resolver) Option {
+	return func(o *options) {
+		o.clientIP = resolver
+	}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
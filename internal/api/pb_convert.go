@@ -0,0 +1,144 @@
+package api
+
+import (
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/pb"
+)
+
+// policyMessage is implemented by the request messages that select a policy.
+type policyMessage interface {
+	GetPolicy() string
+	GetAllowedCountries() []string
+	GetDeniedCountries() []string
+	GetAllowedNetworks() []string
+	GetDeniedNetworks() []string
+	GetUnknownIpAction() pb.UnknownIPAction
+	GetAllowedRegions() []string
+	GetDeniedRegions() []string
+	GetDeniedAnonymity() []string
+}
+
+// protoPolicyRef builds the policy selection shared by the check request messages.
+func protoPolicyRef(m policyMessage) geofence.PolicyRef {
+	return geofence.PolicyRef{
+		Name: m.GetPolicy(),
+		Inline: geofence.Policy{
+			AllowedCountries: m.GetAllowedCountries(),
+			DeniedCountries:  m.GetDeniedCountries(),
+			AllowedNetworks:  m.GetAllowedNetworks(),
+			DeniedNetworks:   m.GetDeniedNetworks(),
+			UnknownIPAction:  unknownIPActionFromProto(m.GetUnknownIpAction()),
+			AllowedRegions:   m.GetAllowedRegions(),
+			DeniedRegions:    m.GetDeniedRegions(),
+			DeniedAnonymity:  m.GetDeniedAnonymity(),
+		},
+	}
+}
+
+// unknownIPActionFromProto maps the proto enum to a geofence.UnknownIPAction.
+// UNSPECIFIED maps to empty so a named policy's own action applies.
+func unknownIPActionFromProto(action pb.UnknownIPAction) geofence.UnknownIPAction {
+	switch action {
+	case pb.UnknownIPAction_UNKNOWN_IP_ACTION_DENY:
+		return geofence.UnknownIPDeny
+	case pb.UnknownIPAction_UNKNOWN_IP_ACTION_ALLOW:
+		return geofence.UnknownIPAllow
+	case pb.UnknownIPAction_UNKNOWN_IP_ACTION_ERROR:
+		return geofence.UnknownIPError
+	default:
+		return ""
+	}
+}
+
+// protoDecisionSource maps a geofence.DecisionSource to its proto enum.
+func protoDecisionSource(source geofence.DecisionSource) pb.DecisionSource {
+	switch source {
+	case geofence.DecidedByCountry:
+		return pb.DecisionSource_DECISION_SOURCE_COUNTRY
+	case geofence.DecidedByOverride:
+		return pb.DecisionSource_DECISION_SOURCE_OVERRIDE
+	case geofence.DecidedByAnonymity:
+		return pb.DecisionSource_DECISION_SOURCE_ANONYMITY
+	default:
+		return pb.DecisionSource_DECISION_SOURCE_UNSPECIFIED
+	}
+}
+
+// protoIPStatus maps a geofence.IPStatus to its proto enum.
+func protoIPStatus(s geofence.IPStatus) pb.IPStatus {
+	switch s {
+	case geofence.IPStatusGeolocated:
+		return pb.IPStatus_IP_STATUS_GEOLOCATED
+	case geofence.IPStatusUnknown:
+		return pb.IPStatus_IP_STATUS_UNKNOWN
+	case geofence.IPStatusPrivate:
+		return pb.IPStatus_IP_STATUS_PRIVATE
+	default:
+		return pb.IPStatus_IP_STATUS_UNSPECIFIED
+	}
+}
+
+// protoReason maps a geofence.Reason to its proto enum.
+func protoReason(r geofence.Reason) pb.Reason {
+	switch r {
+	case geofence.ReasonCountryAllowed:
+		return pb.Reason_REASON_COUNTRY_ALLOWED
+	case geofence.ReasonCountryNotInList:
+		return pb.Reason_REASON_COUNTRY_NOT_IN_LIST
+	case geofence.ReasonCountryDenied:
+		return pb.Reason_REASON_COUNTRY_DENIED
+	case geofence.ReasonUnknownIP:
+		return pb.Reason_REASON_UNKNOWN_IP
+	case geofence.ReasonPrivateIP:
+		return pb.Reason_REASON_PRIVATE_IP
+	case geofence.ReasonOverrideRule:
+		return pb.Reason_REASON_OVERRIDE_RULE
+	case geofence.ReasonAnonymousIP:
+		return pb.Reason_REASON_ANONYMOUS_IP
+	default:
+		return pb.Reason_REASON_UNSPECIFIED
+	}
+}
+
+// protoSourceAnswers converts per-database answers to their proto messages.
+func protoSourceAnswers(answers []geofence.SourceAnswer) []*pb.SourceAnswer {
+	if answers == nil {
+		return nil
+	}
+	out := make([]*pb.SourceAnswer, len(answers))
+	for i, a := range answers {
+		out[i] = &pb.SourceAnswer{Source: a.Source, Country: a.Country}
+	}
+	return out
+}
+
+func protoLookupResponse(ipAddress string, result geofence.LookupResult) *pb.LookupResponse {
+	resp := &pb.LookupResponse{
+		IpAddress:    ipAddress,
+		IpStatus:     protoIPStatus(result.IPStatus),
+		Provider:     result.Provider,
+		Disagreement: protoSourceAnswers(result.Disagreement),
+		Anonymity:    result.Anonymity.Names(),
+	}
+	if c := result.Country; c.ISOCode != "" {
+		resp.Country = &pb.CountryInfo{IsoCode: c.ISOCode, Name: c.Name, IsInEuropeanUnion: c.IsInEuropeanUnion}
+	}
+	if c := result.RegisteredCountry; c.ISOCode != "" {
+		resp.RegisteredCountry = &pb.CountryInfo{IsoCode: c.ISOCode, Name: c.Name, IsInEuropeanUnion: c.IsInEuropeanUnion}
+	}
+	if c := result.RepresentedCountry; c.ISOCode != "" {
+		resp.RepresentedCountry = &pb.RepresentedCountryInfo{
+			IsoCode:           c.ISOCode,
+			Name:              c.Name,
+			IsInEuropeanUnion: c.IsInEuropeanUnion,
+			Type:              c.Type,
+		}
+	}
+	if c := result.Continent; c.Code != "" {
+		resp.Continent = &pb.ContinentInfo{Code: c.Code, Name: c.Name}
+	}
+	if result.Network.IsValid() {
+		resp.Network = result.Network.String()
+	}
+	return resp
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/pb"
+)
+
+func TestProtoReason(t *testing.T) {
+	tests := []struct {
+		reason geofence.Reason
+		want   pb.Reason
+	}{
+		{reason: geofence.ReasonCountryAllowed, want: pb.Reason_REASON_COUNTRY_ALLOWED},
+		{reason: geofence.ReasonCountryNotInList, want: pb.Reason_REASON_COUNTRY_NOT_IN_LIST},
+		{reason: geofence.ReasonCountryDenied, want: pb.Reason_REASON_COUNTRY_DENIED},
+		{reason: geofence.ReasonUnknownIP, want: pb.Reason_REASON_UNKNOWN_IP},
+		{reason: geofence.ReasonPrivateIP, want: pb.Reason_REASON_PRIVATE_IP},
+		{reason: geofence.ReasonOverrideRule, want: pb.Reason_REASON_OVERRIDE_RULE},
+		{reason: geofence.ReasonAnonymousIP, want: pb.Reason_REASON_ANONYMOUS_IP},
+		{reason: "", want: pb.Reason_REASON_UNSPECIFIED},
+		{reason: "SOMETHING_NEW", want: pb.Reason_REASON_UNSPECIFIED},
+	}
+
+	for _, tt := range tests {
+		if got := protoReason(tt.reason); got != tt.want {
+			t.Errorf("protoReason(%q) = %v, want %v", tt.reason, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+)
+
+// RegionsHandler handles GET /v1/regions and GET /v1/regions/{name}, showing the
+// countries each region used in allowed_regions and denied_regions expands to.
+type RegionsHandler struct {
+	groups geofence.Groups
+}
+
+// NewRegionsHandler creates a RegionsHandler for the given regions.
+func NewRegionsHandler(groups geofence.Groups) *RegionsHandler {
+	return &RegionsHandler{groups: groups}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *RegionsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "method not allowed"})
+		return
+	}
+
+	if name := r.PathValue("name"); name != "" {
+		countries, ok := h.groups.Members(name)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "unknown region: " + name})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(Region{Name: strings.ToUpper(name), Countries: countries})
+		return
+	}
+
+	resp := RegionsResponse{Regions: make([]Region, 0, len(h.groups))}
+	for _, name := range h.groups.Names() {
+		resp.Regions = append(resp.Regions, Region{Name: name, Countries: h.groups[name]})
+	}
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
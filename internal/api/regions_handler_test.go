@@ -0,0 +1,152 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+var testGroups = geofence.Groups{
+	"EU27": {"DE", "FR"},
+	"NA":   {"CA", "MX", "US"},
+}
+
+func TestRegionsHandler_ServeHTTP(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/v1/regions", NewRegionsHandler(testGroups))
+	mux.Handle("/v1/regions/{name}", NewRegionsHandler(testGroups))
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "list",
+			method:     http.MethodGet,
+			target:     "/v1/regions",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"regions":[{"name":"EU27","countries":["DE","FR"]},{"name":"NA","countries":["CA","MX","US"]}]}`,
+		},
+		{
+			name:       "single region",
+			method:     http.MethodGet,
+			target:     "/v1/regions/na",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"name":"NA","countries":["CA","MX","US"]}`,
+		},
+		{
+			name:       "unknown region",
+			method:     http.MethodGet,
+			target:     "/v1/regions/MARS",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"error":"unknown region: MARS"}`,
+		},
+		{
+			name:       "wrong method",
+			method:     http.MethodPost,
+			target:     "/v1/regions",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantBody:   `{"error":"method not allowed"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.wantBody {
+				t.Errorf("body = %s, want %s", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestCheckHandler_Regions(t *testing.T) {
+	lookup := mockLookuper{lookup: func(net.IP) (string, error) { return "US", nil }}
+	h := NewCheckHandler(geofence.NewChecker(lookup, geofence.WithGroups(testGroups)))
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "allowed by region",
+			body:       `{"ip_address":"8.8.8.8","allowed_regions":["NA"]}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"allowed":true,"country":"US","decided_by":"country","ip_status":"geolocated","reason":"COUNTRY_ALLOWED","matched_rule":"NA"}`,
+		},
+		{
+			name:       "unknown region",
+			body:       `{"ip_address":"8.8.8.8","allowed_regions":["MARS"]}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"unknown region: MARS"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/check", bytes.NewBufferString(tt.body)))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.wantBody {
+				t.Errorf("body = %s, want %s", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestGeoFenceServer_ListRegions(t *testing.T) {
+	lookup := mockLookuper{lookup: func(net.IP) (string, error) { return "US", nil }}
+	srv := NewGeoFenceServer(geofence.NewChecker(lookup, geofence.WithGroups(testGroups)))
+
+	resp, err := srv.ListRegions(context.Background(), &pb.ListRegionsRequest{Name: "eu27"})
+	if err != nil {
+		t.Fatalf("ListRegions() unexpected error: %v", err)
+	}
+	want := &pb.ListRegionsResponse{Regions: []*pb.Region{{Name: "EU27", Countries: []string{"DE", "FR"}}}}
+	if !proto.Equal(resp, want) {
+		t.Errorf("ListRegions() = %v, want %v", resp, want)
+	}
+
+	all, err := srv.ListRegions(context.Background(), &pb.ListRegionsRequest{})
+	if err != nil {
+		t.Fatalf("ListRegions() unexpected error: %v", err)
+	}
+	if len(all.GetRegions()) != 2 || all.GetRegions()[0].GetName() != "EU27" {
+		t.Errorf("ListRegions() = %v, want EU27 then NA", all)
+	}
+
+	if _, err := srv.ListRegions(context.Background(), &pb.ListRegionsRequest{Name: "MARS"}); status.Code(err) != codes.NotFound {
+		t.Errorf("ListRegions(MARS) code = %v, want NotFound", status.Code(err))
+	}
+
+	check, err := srv.CheckAccess(context.Background(), &pb.CheckRequest{IpAddress: "8.8.8.8", AllowedRegions: []string{"NA"}})
+	if err != nil {
+		t.Fatalf("CheckAccess() unexpected error: %v", err)
+	}
+	if !check.GetAllowed() || check.GetMatchedRule() != "NA" {
+		t.Errorf("CheckAccess() = %v, want allowed by NA", check)
+	}
+}
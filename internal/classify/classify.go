@@ -0,0 +1,203 @@
+// Package classify annotates historical log records with the country and
+// geo-fence decision of the IP each one contains. It reads CSV, JSON Lines and
+// common/combined access logs, checks records on a pool of workers and writes
+// them back out in input order with the decision appended.
+package classify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+	"golang.org/x/sync/errgroup"
+)
+
+// Decision labels used in the output and the summary.
+const (
+	DecisionAllowed = "allowed"
+	DecisionDenied  = "denied"
+	DecisionError   = "error"
+)
+
+// NoCountry is the summary key for records without a country.
+const NoCountry = "none"
+
+// Config configures Run.
+type Config struct {
+	// Format of the input. FormatAuto detects it from the first line.
+	Format Format
+	// Field is the CSV column or JSON key holding the IP. When empty it is
+	// detected from common names (ip, ip_address, client_ip, ...) or the first
+	// value that parses as an IP. Access logs always use the remote host.
+	Field string
+	// Policy is checked for every record.
+	Policy geofence.PolicyRef
+	// Workers is the number of concurrent checks. The default is GOMAXPROCS.
+	Workers int
+}
+
+// Counts tallies decisions.
+type Counts struct {
+	Allowed int `json:"allowed"`
+	Denied  int `json:"denied"`
+	Errors  int `json:"errors"`
+}
+
+// Total returns the number of records counted.
+func (c Counts) Total() int {
+	return c.Allowed + c.Denied + c.Errors
+}
+
+func (c *Counts) add(decision string) {
+	switch decision {
+	case DecisionAllowed:
+		c.Allowed++
+	case DecisionDenied:
+		c.Denied++
+	default:
+		c.Errors++
+	}
+}
+
+// Summary holds decision counts overall and per country.
+type Summary struct {
+	Total     Counts             `json:"total"`
+	Countries map[string]*Counts `json:"countries"` // keyed by ISO code or NoCountry
+}
+
+func (s *Summary) add(country, decision string) {
+	if country == "" {
+		country = NoCountry
+	}
+	c, ok := s.Countries[country]
+	if !ok {
+		c = &Counts{}
+		s.Countries[country] = c
+	}
+	c.add(decision)
+	s.Total.add(decision)
+}
+
+// record is one input record. ip is the raw value found in the IP field; err is
+// set when the record could not be parsed or has no IP.
+type record struct {
+	ip   string
+	err  error
+	data any // format-specific, passed back to the codec when writing
+}
+
+// annotation is the outcome of checking one record.
+type annotation struct {
+	result geofence.CheckResult
+	err    error
+}
+
+func (a annotation) decision() string {
+	switch {
+	case a.err != nil:
+		return DecisionError
+	case a.result.Allowed:
+		return DecisionAllowed
+	default:
+		return DecisionDenied
+	}
+}
+
+// job carries a record through the worker pool. done is buffered so workers
+// never wait on the writer.
+type job struct {
+	rec  record
+	done chan annotation
+}
+
+// Run reads records from r, checks each against cfg.Policy and writes the
+// annotated records to w in input order. Records that cannot be checked are
+// written with an error annotation and counted as errors; only unreadable input
+// or a failed write stops the run.
+func Run(ctx context.Context, checker *geofence.Checker, r io.Reader, w io.Writer, cfg Config) (Summary, error) {
+	summary := Summary{Countries: make(map[string]*Counts)}
+	// Resolve and prepare once rather than for every record.
+	policy, err := checker.Resolve(cfg.Policy)
+	if err == nil {
+		policy, err = checker.Prepare(policy)
+	}
+	if err != nil {
+		return summary, fmt.Errorf("policy: %w", err)
+	}
+	c, err := newCodec(cfg.Format, cfg.Field, r, w)
+	if err != nil {
+		return summary, err
+	}
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	work := make(chan *job, workers)
+	// order holds jobs in input order; its size bounds how far reading can run
+	// ahead of writing.
+	order := make(chan *job, workers*64)
+
+	g.Go(func() error {
+		defer close(work)
+		defer close(order)
+		for {
+			rec, err := c.read()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			j := &job{rec: rec, done: make(chan annotation, 1)}
+			for _, ch := range []chan *job{order, work} {
+				select {
+				case ch <- j:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+	})
+	for range workers {
+		g.Go(func() error {
+			for j := range work {
+				j.done <- check(ctx, checker, policy, j.rec)
+			}
+			return nil
+		})
+	}
+	g.Go(func() error {
+		for j := range order {
+			var a annotation
+			select {
+			case a = <-j.done:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if err := c.write(j.rec, a); err != nil {
+				return fmt.Errorf("write output: %w", err)
+			}
+			summary.add(a.result.Country, a.decision())
+		}
+		if err := c.flush(); err != nil {
+			return fmt.Errorf("write output: %w", err)
+		}
+		return nil
+	})
+
+	err = g.Wait()
+	return summary, err
+}
+
+func check(ctx context.Context, checker *geofence.Checker, policy geofence.Policy, rec record) annotation {
+	if rec.err != nil {
+		return annotation{err: rec.err}
+	}
+	result, err := checker.CheckPolicy(ctx, hostOf(rec.ip), policy)
+	return annotation{result: result, err: err}
+}
@@ -0,0 +1,199 @@
+package classify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+)
+
+type mockLookuper struct {
+	lookup func(net.IP) (string, error)
+}
+
+func (m mockLookuper) Lookup(ip net.IP) (string, error) {
+	return m.lookup(ip)
+}
+
+func newTestChecker() *geofence.Checker {
+	return geofence.NewChecker(mockLookuper{lookup: func(ip net.IP) (string, error) {
+		switch ip.String() {
+		case "8.8.8.8":
+			return "US", nil
+		case "81.2.69.142":
+			return "GB", nil
+		default:
+			return "", geofence.ErrUnknownIP
+		}
+	}})
+}
+
+var usOnly = geofence.PolicyRef{Inline: geofence.Policy{AllowedCountries: []string{"US"}}}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		field  string
+		input  string
+		want   string
+	}{
+		{
+			name: "csv with well-known column",
+			input: "call_id,src_ip,duration\n" +
+				"1,8.8.8.8,30\n" +
+				"2,81.2.69.142:5060,12\n" +
+				"3,,5\n",
+			want: "call_id,src_ip,duration,geo_country,geo_decision,geo_ip_status,geo_reason,geo_error\n" +
+				"1,8.8.8.8,30,US,allowed,geolocated,COUNTRY_ALLOWED,\n" +
+				"2,81.2.69.142:5060,12,GB,denied,geolocated,COUNTRY_NOT_IN_LIST,\n" +
+				"3,,5,,error,,,no IP in record\n",
+		},
+		{
+			name:  "csv column detected from first row",
+			input: "when,who,peer\n2024-01-01,alice,8.8.8.8\n2024-01-02,bob\n",
+			want: "when,who,peer,geo_country,geo_decision,geo_ip_status,geo_reason,geo_error\n" +
+				"2024-01-01,alice,8.8.8.8,US,allowed,geolocated,COUNTRY_ALLOWED,\n" +
+				"2024-01-02,bob,,,error,,,no IP in record\n",
+		},
+		{
+			name:  "csv explicit field",
+			field: "B",
+			input: "ip,b\n8.8.8.8,81.2.69.142\n",
+			want: "ip,b,geo_country,geo_decision,geo_ip_status,geo_reason,geo_error\n" +
+				"8.8.8.8,81.2.69.142,GB,denied,geolocated,COUNTRY_NOT_IN_LIST,\n",
+		},
+		{
+			name: "jsonl",
+			input: `{"ts":1,"client_ip":"8.8.8.8"}` + "\n" +
+				"\n" +
+				`{"ts":2,"peer":"[2001:db8::1]:443"}` + "\n" +
+				`{}` + "\n" +
+				`not json` + "\n",
+			want: `{"ts":1,"client_ip":"8.8.8.8","geo_country":"US","geo_decision":"allowed","geo_ip_status":"geolocated","geo_reason":"COUNTRY_ALLOWED"}` + "\n" +
+				`{"ts":2,"peer":"[2001:db8::1]:443","geo_country":"","geo_decision":"denied","geo_ip_status":"private","geo_reason":"PRIVATE_IP"}` + "\n" +
+				`{"geo_country":"","geo_decision":"error","geo_error":"no IP in record"}` + "\n" +
+				`not json` + "\n",
+		},
+		{
+			name:  "jsonl explicit field",
+			field: "Peer",
+			input: `{"client_ip":"8.8.8.8","peer":"81.2.69.142"}` + "\n",
+			want:  `{"client_ip":"8.8.8.8","peer":"81.2.69.142","geo_country":"GB","geo_decision":"denied","geo_ip_status":"geolocated","geo_reason":"COUNTRY_NOT_IN_LIST"}` + "\n",
+		},
+		{
+			name: "combined access log",
+			input: `81.2.69.142 - - [10/Oct/2024:13:55:36 +0000] "GET / HTTP/1.1" 200 2326 "-" "curl/8.0"` + "\n" +
+				`8.8.8.8 - frank [10/Oct/2024:13:55:37 +0000] "GET /a HTTP/1.1" 404 0` + "\n" +
+				`garbage` + "\n",
+			want: `81.2.69.142 - - [10/Oct/2024:13:55:36 +0000] "GET / HTTP/1.1" 200 2326 "-" "curl/8.0" geo_country=GB geo_decision=denied geo_ip_status=geolocated geo_reason=COUNTRY_NOT_IN_LIST` + "\n" +
+				`8.8.8.8 - frank [10/Oct/2024:13:55:37 +0000] "GET /a HTTP/1.1" 404 0 geo_country=US geo_decision=allowed geo_ip_status=geolocated geo_reason=COUNTRY_ALLOWED` + "\n" +
+				`garbage geo_country=- geo_decision=error geo_ip_status=- geo_reason=- geo_error="no IP in record"` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			cfg := Config{Format: tt.format, Field: tt.field, Policy: usOnly, Workers: 3}
+			if _, err := Run(context.Background(), newTestChecker(), strings.NewReader(tt.input), &out, cfg); err != nil {
+				t.Fatalf("Run() unexpected error: %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("output:\n%s\nwant:\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRun_OrderAndSummary(t *testing.T) {
+	ips := []string{"8.8.8.8", "81.2.69.142", "203.0.113.9", "bogus"}
+	var in, want strings.Builder
+	in.WriteString("ip\n")
+	for i := range 1000 {
+		ip := ips[i%len(ips)]
+		fmt.Fprintf(&in, "%s\n", ip)
+		fmt.Fprintf(&want, "%s\n", ip)
+	}
+
+	var out bytes.Buffer
+	summary, err := Run(context.Background(), newTestChecker(), strings.NewReader(in.String()), &out, Config{Policy: usOnly, Workers: 8})
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	var got strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n")[1:] {
+		ip, _, _ := strings.Cut(line, ",")
+		fmt.Fprintf(&got, "%s\n", ip)
+	}
+	if got.String() != want.String() {
+		t.Error("output is not in input order")
+	}
+
+	wantTotal := Counts{Allowed: 250, Denied: 500, Errors: 250}
+	if summary.Total != wantTotal {
+		t.Errorf("Total = %+v, want %+v", summary.Total, wantTotal)
+	}
+	wantCountries := map[string]Counts{
+		"US":      {Allowed: 250},
+		"GB":      {Denied: 250},
+		NoCountry: {Denied: 250, Errors: 250},
+	}
+	if len(summary.Countries) != len(wantCountries) {
+		t.Fatalf("Countries = %v, want %v", summary.Countries, wantCountries)
+	}
+	for country, want := range wantCountries {
+		if got := summary.Countries[country]; got == nil || *got != want {
+			t.Errorf("Countries[%s] = %+v, want %+v", country, got, want)
+		}
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		input   string
+		wantErr error
+	}{
+		{
+			name:    "unknown format",
+			cfg:     Config{Format: "xml", Policy: usOnly},
+			input:   "ip\n8.8.8.8\n",
+			wantErr: ErrUnknownFormat,
+		},
+		{
+			name:    "missing column",
+			cfg:     Config{Field: "caller", Policy: usOnly},
+			input:   "ip\n8.8.8.8\n",
+			wantErr: ErrNoIPField,
+		},
+		{
+			name:    "no column holds an IP",
+			cfg:     Config{Policy: usOnly},
+			input:   "a,b\nx,y\n",
+			wantErr: ErrNoIPField,
+		},
+		{
+			name:    "empty policy",
+			cfg:     Config{},
+			input:   "ip\n8.8.8.8\n",
+			wantErr: geofence.ErrEmptyAllowedCountries,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Run(context.Background(), newTestChecker(), strings.NewReader(tt.input), &bytes.Buffer{}, tt.cfg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Run() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,404 @@
+package classify
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Format is an input format.
+type Format string
+
+const (
+	// FormatAuto detects the format from the first line: a JSON object, an access
+	// log line, or otherwise a CSV header.
+	FormatAuto Format = "auto"
+	// FormatCSV is CSV with a header row. Annotations are added as columns.
+	FormatCSV Format = "csv"
+	// FormatJSONL is one JSON object per line. Annotations are added as keys.
+	FormatJSONL Format = "jsonl"
+	// FormatAccessLog is the common or combined access log format. Annotations
+	// are appended to each line as key=value pairs.
+	FormatAccessLog Format = "access"
+)
+
+var (
+	// ErrUnknownFormat is returned for a Format other than the ones above.
+	ErrUnknownFormat = errors.New("unknown format")
+	// ErrNoIPField is returned when the configured IP field is not in the CSV
+	// header, or no column holds an IP.
+	ErrNoIPField = errors.New("no IP field")
+	// errNoIP marks a record without an IP value.
+	errNoIP = errors.New("no IP in record")
+	// errNotObject marks a JSON Lines record that is not a JSON object.
+	errNotObject = errors.New("not a JSON object")
+)
+
+// ipFieldNames are the field names, in order of preference, taken to hold the IP
+// when Config.Field is empty. Matching is case-insensitive.
+var ipFieldNames = []string{
+	"ip", "ip_address", "ipaddress", "client_ip", "remote_ip", "remote_addr",
+	"source_ip", "src_ip", "caller_ip", "calling_ip",
+}
+
+// accessLogRE matches the start of a common or combined log line and captures
+// the remote host.
+var accessLogRE = regexp.MustCompile(`^(\S+) \S+ \S+ \[[^\]]*\] "`)
+
+// annotationColumns are the CSV columns appended to each row.
+var annotationColumns = []string{"geo_country", "geo_decision", "geo_ip_status", "geo_reason", "geo_error"}
+
+// maxLineSize bounds a single JSON Lines or access log line.
+const maxLineSize = 1 << 20
+
+// codec reads records in one format and writes them back with annotations. read
+// and write are called from different goroutines and must not share state.
+type codec interface {
+	// read returns the next record, or io.EOF at the end of the input.
+	read() (record, error)
+	write(rec record, a annotation) error
+	flush() error
+}
+
+func newCodec(format Format, field string, r io.Reader, w io.Writer) (codec, error) {
+	br := bufio.NewReader(r)
+	if format == FormatAuto || format == "" {
+		format = detectFormat(br)
+	}
+	switch format {
+	case FormatCSV:
+		return newCSVCodec(field, br, w)
+	case FormatJSONL:
+		return &jsonlCodec{lines: newLineScanner(br), w: bufio.NewWriter(w), field: field}, nil
+	case FormatAccessLog:
+		return &accessLogCodec{lines: newLineScanner(br), w: bufio.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+// detectFormat peeks at the first line without consuming it.
+func detectFormat(br *bufio.Reader) Format {
+	head, _ := br.Peek(4096) // a short read at EOF is fine
+	head = bytes.TrimLeft(bytes.TrimPrefix(head, []byte("\ufeff")), " \t\r\n")
+	line, _, _ := bytes.Cut(head, []byte("\n"))
+	switch {
+	case bytes.HasPrefix(line, []byte("{")):
+		return FormatJSONL
+	case accessLogRE.Match(line):
+		return FormatAccessLog
+	default:
+		return FormatCSV
+	}
+}
+
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), maxLineSize)
+	return s
+}
+
+// hostOf strips a port from an address such as "203.0.113.7:5060" or
+// "[2001:db8::1]:443". Anything else is returned unchanged for the Checker to
+// validate.
+func hostOf(s string) string {
+	s = strings.TrimSpace(s)
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return ap.Addr().String()
+	}
+	return s
+}
+
+func isIP(s string) bool {
+	_, err := netip.ParseAddr(hostOf(s))
+	return err == nil
+}
+
+// csvCodec reads CSV with a header row and appends annotationColumns.
+type csvCodec struct {
+	r       *csv.Reader
+	w       *csv.Writer
+	col     int
+	width   int      // header width; short rows are padded to it
+	pending []string // first data row, read ahead to detect the IP column
+}
+
+func newCSVCodec(field string, r io.Reader, w io.Writer) (*csvCodec, error) {
+	c := &csvCodec{r: csv.NewReader(r), w: csv.NewWriter(w), col: -1}
+	c.r.FieldsPerRecord = -1
+
+	header, err := c.r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("read CSV header: %w", io.ErrUnexpectedEOF)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read CSV header: %w", err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	c.width = len(header)
+
+	if field != "" {
+		c.col = indexFold(header, field)
+		if c.col < 0 {
+			return nil, fmt.Errorf("%w: column %q not in header", ErrNoIPField, field)
+		}
+	} else {
+		for _, name := range ipFieldNames {
+			if c.col = indexFold(header, name); c.col >= 0 {
+				break
+			}
+		}
+	}
+	if c.col < 0 {
+		// No well-known name; use the first column of the first row holding an IP.
+		c.pending, err = c.r.Read()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("read CSV: %w", err)
+		}
+		for i, v := range c.pending {
+			if isIP(v) {
+				c.col = i
+				break
+			}
+		}
+		if c.col < 0 && c.pending != nil {
+			return nil, fmt.Errorf("%w: set the IP column explicitly", ErrNoIPField)
+		}
+	}
+
+	if err := c.w.Write(append(header, annotationColumns...)); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *csvCodec) read() (record, error) {
+	row := c.pending
+	c.pending = nil
+	if row == nil {
+		var err error
+		row, err = c.r.Read()
+		if errors.Is(err, io.EOF) {
+			return record{}, io.EOF
+		}
+		if err != nil {
+			return record{}, fmt.Errorf("read CSV: %w", err)
+		}
+	}
+	rec := record{data: row}
+	if c.col < len(row) && row[c.col] != "" {
+		rec.ip = row[c.col]
+	} else {
+		rec.err = errNoIP
+	}
+	return rec, nil
+}
+
+func (c *csvCodec) write(rec record, a annotation) error {
+	row := rec.data.([]string)
+	for len(row) < c.width {
+		row = append(row, "")
+	}
+	var errText string
+	if a.err != nil {
+		errText = a.err.Error()
+	}
+	return c.w.Write(append(row,
+		a.result.Country, a.decision(), string(a.result.IPStatus), string(a.result.Reason), errText))
+}
+
+func (c *csvCodec) flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func indexFold(list []string, s string) int {
+	for i, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return i
+		}
+	}
+	return -1
+}
+
+// jsonlCodec reads one JSON object per line and adds annotation keys, keeping
+// the original bytes of each object. Lines that are not objects are copied
+// through unchanged.
+type jsonlCodec struct {
+	lines *bufio.Scanner
+	w     *bufio.Writer
+	field string
+}
+
+// jsonlRecord is the data of a JSON Lines record.
+type jsonlRecord struct {
+	line  []byte
+	empty bool // the object has no keys, so no comma is needed before ours
+}
+
+type jsonAnnotation struct {
+	Country  string `json:"geo_country"`
+	Decision string `json:"geo_decision"`
+	IPStatus string `json:"geo_ip_status,omitempty"`
+	Reason   string `json:"geo_reason,omitempty"`
+	Error    string `json:"geo_error,omitempty"`
+}
+
+func (c *jsonlCodec) read() (record, error) {
+	for c.lines.Scan() {
+		line := bytes.TrimSpace(c.lines.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		line = bytes.Clone(line)
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(line, &obj); err != nil || obj == nil {
+			return record{err: errNotObject, data: jsonlRecord{line: line}}, nil
+		}
+		rec := record{data: jsonlRecord{line: line, empty: len(obj) == 0}}
+		if rec.ip = c.findIP(obj); rec.ip == "" {
+			rec.err = errNoIP
+		}
+		return rec, nil
+	}
+	if err := c.lines.Err(); err != nil {
+		return record{}, fmt.Errorf("read JSON Lines: %w", err)
+	}
+	return record{}, io.EOF
+}
+
+// findIP returns the IP value of obj: the configured field, else the first
+// well-known name, else the first string value that parses as an IP. Field names
+// match case-insensitively, as CSV headers do; keys are taken in sorted order so
+// the choice does not depend on map iteration.
+func (c *jsonlCodec) findIP(obj map[string]json.RawMessage) string {
+	str := func(raw json.RawMessage) string {
+		var s string
+		_ = json.Unmarshal(raw, &s)
+		return s
+	}
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	field := func(name string) (json.RawMessage, bool) {
+		if raw, ok := obj[name]; ok {
+			return raw, true
+		}
+		for _, key := range keys {
+			if strings.EqualFold(key, name) {
+				return obj[key], true
+			}
+		}
+		return nil, false
+	}
+
+	if c.field != "" {
+		raw, _ := field(c.field)
+		return str(raw)
+	}
+	for _, name := range ipFieldNames {
+		if raw, ok := field(name); ok {
+			return str(raw)
+		}
+	}
+	for _, key := range keys {
+		if s := str(obj[key]); isIP(s) {
+			return s
+		}
+	}
+	return ""
+}
+
+func (c *jsonlCodec) write(rec record, a annotation) error {
+	data := rec.data.(jsonlRecord)
+	if errors.Is(rec.err, errNotObject) {
+		_, err := fmt.Fprintf(c.w, "%s\n", data.line)
+		return err
+	}
+	ann := jsonAnnotation{
+		Country:  a.result.Country,
+		Decision: a.decision(),
+		IPStatus: string(a.result.IPStatus),
+		Reason:   string(a.result.Reason),
+	}
+	if a.err != nil {
+		ann.Error = a.err.Error()
+	}
+	extra, err := json.Marshal(ann)
+	if err != nil {
+		return err
+	}
+	sep := ","
+	if data.empty {
+		sep = ""
+	}
+	// Splice our keys in before the closing brace.
+	_, err = fmt.Fprintf(c.w, "%s%s%s\n", data.line[:len(data.line)-1], sep, extra[1:])
+	return err
+}
+
+func (c *jsonlCodec) flush() error {
+	return c.w.Flush()
+}
+
+// accessLogCodec reads common or combined access log lines and appends
+// annotations as key=value pairs.
+type accessLogCodec struct {
+	lines *bufio.Scanner
+	w     *bufio.Writer
+}
+
+func (c *accessLogCodec) read() (record, error) {
+	for c.lines.Scan() {
+		line := c.lines.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		rec := record{data: line}
+		if m := accessLogRE.FindStringSubmatch(line); m != nil && m[1] != "-" {
+			rec.ip = m[1]
+		} else {
+			rec.err = errNoIP
+		}
+		return rec, nil
+	}
+	if err := c.lines.Err(); err != nil {
+		return record{}, fmt.Errorf("read access log: %w", err)
+	}
+	return record{}, io.EOF
+}
+
+func (c *accessLogCodec) write(rec record, a annotation) error {
+	dash := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+	_, err := fmt.Fprintf(c.w, "%s geo_country=%s geo_decision=%s geo_ip_status=%s geo_reason=%s",
+		rec.data.(string), dash(a.result.Country), a.decision(), dash(string(a.result.IPStatus)), dash(string(a.result.Reason)))
+	if err == nil && a.err != nil {
+		_, err = fmt.Fprintf(c.w, " geo_error=%s", strconv.Quote(a.err.Error()))
+	}
+	if err == nil {
+		err = c.w.WriteByte('\n')
+	}
+	return err
+}
+
+func (c *accessLogCodec) flush() error {
+	return c.w.Flush()
+}
@@ -0,0 +1,155 @@
+// Package clientip derives the IP address of the client behind an HTTP request or
+// gRPC call, honouring forwarding headers only from trusted proxies.
+package clientip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// ErrInvalidTrustedProxy is returned when a trusted proxy is not an IP or CIDR prefix.
+var ErrInvalidTrustedProxy = errors.New("invalid trusted proxy")
+
+// Resolver derives the IP of the client that sent a request. Forwarding
+// headers are only honoured when the connection comes from a trusted proxy, so a
+// client cannot spoof its address by setting them itself.
+type Resolver struct {
+	trusted []netip.Prefix
+}
+
+// NewResolver creates a Resolver that trusts forwarding headers from the given
+// proxies, each an IP or CIDR prefix. With no trusted proxies the connection's
+// remote address is always used.
+func NewResolver(trustedProxies []string) (*Resolver, error) {
+	r := &Resolver{trusted: make([]netip.Prefix, 0, len(trustedProxies))}
+	for _, s := range trustedProxies {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(s)
+			if addrErr != nil {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidTrustedProxy, s)
+			}
+			prefix = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+		}
+		r.trusted = append(r.trusted, prefix.Masked())
+	}
+	return r, nil
+}
+
+// FromRequest returns the client IP for an HTTP request, or false if RemoteAddr
+// cannot be parsed.
+func (r *Resolver) FromRequest(req *http.Request) (string, bool) {
+	remote, ok := parseHostAddr(req.RemoteAddr)
+	if !ok {
+		return "", false
+	}
+	return r.resolve(remote, req.Header.Values).String(), true
+}
+
+// FromContext returns the client IP for a gRPC call from its peer address and,
+// when the peer is a trusted proxy, the forwarding headers in its metadata.
+func (r *Resolver) FromContext(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "", false
+	}
+	remote, ok := parseHostAddr(p.Addr.String())
+	if !ok {
+		return "", false
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	return r.resolve(remote, md.Get).String(), true
+}
+
+// resolve walks the forwarding chain from the nearest hop outwards and returns the
+// first address that is not a trusted proxy. Forwarded is preferred over
+// X-Forwarded-For, which is preferred over X-Real-IP; only the first one present
+// is used. An unparseable hop ends the walk at the last trusted address.
+func (r *Resolver) resolve(remote netip.Addr, header func(string) []string) netip.Addr {
+	if !r.isTrusted(remote) {
+		return remote
+	}
+
+	chain := forwardedFor(header("Forwarded"))
+	if len(chain) == 0 {
+		chain = splitList(header("X-Forwarded-For"))
+	}
+	if len(chain) == 0 {
+		chain = splitList(header("X-Real-IP"))
+	}
+
+	client := remote
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr, ok := parseHostAddr(chain[i])
+		if !ok {
+			break
+		}
+		client = addr
+		if !r.isTrusted(addr) {
+			break
+		}
+	}
+	return client
+}
+
+func (r *Resolver) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range r.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedFor extracts the for= parameter of each element of RFC 7239 Forwarded
+// headers, in order.
+func forwardedFor(values []string) []string {
+	var chain []string
+	for _, element := range splitList(values) {
+		for _, pair := range strings.Split(element, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if ok && strings.EqualFold(key, "for") {
+				chain = append(chain, strings.Trim(value, `"`))
+			}
+		}
+	}
+	return chain
+}
+
+// splitList splits comma-separated header values into trimmed, non-empty entries.
+func splitList(values []string) []string {
+	var list []string
+	for _, v := range values {
+		for _, entry := range strings.Split(v, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				list = append(list, entry)
+			}
+		}
+	}
+	return list
+}
+
+// parseHostAddr parses an IP with an optional port, e.g. "192.0.2.1",
+// "192.0.2.1:8080", "2001:db8::1" or "[2001:db8::1]:8080".
+func parseHostAddr(s string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, err := netip.ParseAddr(strings.Trim(s, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap().WithZone(""), true
+}
@@ -0,0 +1,109 @@
+package clientip
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestResolver_FromRequest(t *testing.T) {
+	resolver, err := NewResolver([]string{"10.0.0.0/8", "2001:db8::1"})
+	if err != nil {
+		t.Fatalf("NewResolver() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{
+			name:       "remote address only",
+			remoteAddr: "81.2.69.142:5555",
+			want:       "81.2.69.142",
+		},
+		{
+			name:       "untrusted peer headers are ignored",
+			remoteAddr: "81.2.69.142:5555",
+			headers:    map[string]string{"X-Forwarded-For": "8.8.8.8"},
+			want:       "81.2.69.142",
+		},
+		{
+			name:       "X-Forwarded-For from trusted proxy",
+			remoteAddr: "10.0.0.2:5555",
+			headers:    map[string]string{"X-Forwarded-For": "8.8.8.8"},
+			want:       "8.8.8.8",
+		},
+		{
+			name:       "spoofed X-Forwarded-For entry is skipped",
+			remoteAddr: "10.0.0.2:5555",
+			headers:    map[string]string{"X-Forwarded-For": "1.1.1.1, 81.2.69.142, 10.0.0.3"},
+			want:       "81.2.69.142",
+		},
+		{
+			name:       "X-Real-IP from trusted proxy",
+			remoteAddr: "10.0.0.2:5555",
+			headers:    map[string]string{"X-Real-IP": "8.8.8.8"},
+			want:       "8.8.8.8",
+		},
+		{
+			name:       "Forwarded preferred over X-Forwarded-For",
+			remoteAddr: "[2001:db8::1]:5555",
+			headers: map[string]string{
+				"Forwarded":       `for="[2001:db8:cafe::17]:4711";proto=https, for=10.0.0.3`,
+				"X-Forwarded-For": "8.8.8.8",
+			},
+			want: "2001:db8:cafe::17",
+		},
+		{
+			name:       "unparseable hop stops at last trusted address",
+			remoteAddr: "10.0.0.2:5555",
+			headers:    map[string]string{"Forwarded": "for=unknown, for=10.0.0.3"},
+			want:       "10.0.0.3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/check", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			got, ok := resolver.FromRequest(req)
+			if !ok || got != tt.want {
+				t.Errorf("FromRequest() = %q, %v, want %q, true", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolver_FromContext(t *testing.T) {
+	resolver, err := NewResolver([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("NewResolver() unexpected error: %v", err)
+	}
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 5555}})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-forwarded-for", "8.8.8.8"))
+	if got, ok := resolver.FromContext(ctx); !ok || got != "8.8.8.8" {
+		t.Errorf("FromContext() = %q, %v, want 8.8.8.8, true", got, ok)
+	}
+
+	if _, ok := resolver.FromContext(context.Background()); ok {
+		t.Error("FromContext() without peer = ok, want not ok")
+	}
+}
+
+func TestNewResolver_Invalid(t *testing.T) {
+	if _, err := NewResolver([]string{"10.0.0.0/8", "proxy.internal"}); !errors.Is(err, ErrInvalidTrustedProxy) {
+		t.Errorf("NewResolver() error = %v, want ErrInvalidTrustedProxy", err)
+	}
+}
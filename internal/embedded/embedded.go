@@ -0,0 +1,174 @@
+// Package embedded is the core shared by the in-process adapters geofencehttp and
+// geofencegrpc. It owns the database, builds the Checker for a set of named
+// policies, resolves the client IP and carries the decision in the context.
+package embedded
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/clientip"
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+)
+
+// ErrNoDatabase is returned by New when neither DBPath nor Lookuper is set.
+var ErrNoDatabase = errors.New("DBPath or Lookuper is required")
+
+// ErrUnknownIP is the error a CountryLookuper returns for IPs without a country.
+var ErrUnknownIP = geofence.ErrUnknownIP
+
+// CountryLookuper resolves an IP to an ISO country code. It returns an error
+// wrapping ErrUnknownIP for IPs that have no country.
+type CountryLookuper interface {
+	Lookup(ip net.IP) (string, error)
+}
+
+// LookupFunc adapts a function to a CountryLookuper.
+type LookupFunc func(ip net.IP) (string, error)
+
+// Lookup calls f(ip).
+func (f LookupFunc) Lookup(ip net.IP) (string, error) {
+	return f(ip)
+}
+
+// Policy is a set of geo rules with the same meaning as the fields of a /v1/check
+// request. UnknownIPAction is "deny" (the default), "allow" or "error".
+type Policy struct {
+	AllowedCountries []string
+	DeniedCountries  []string
+	AllowedNetworks  []string
+	DeniedNetworks   []string
+	UnknownIPAction  string
+}
+
+// Compile validates p and converts it to a geofence.Policy.
+func (p Policy) Compile() (geofence.Policy, error) {
+	return geofence.Policy{
+		AllowedCountries: p.AllowedCountries,
+		DeniedCountries:  p.DeniedCountries,
+		AllowedNetworks:  p.AllowedNetworks,
+		DeniedNetworks:   p.DeniedNetworks,
+		UnknownIPAction:  geofence.UnknownIPAction(p.UnknownIPAction),
+	}.Compile()
+}
+
+// Config configures a Fence.
+type Config struct {
+	// DBPath is the database to load. Ignored if Lookuper is set.
+	DBPath string
+	// Provider is the format of DBPath; empty means maxmind.
+	Provider string
+	// Lookuper replaces the database.
+	Lookuper CountryLookuper
+	// Policies are the compiled policies CheckRequest and CheckCall select by name.
+	Policies map[string]geofence.Policy
+	// TrustedProxies are proxy IPs or CIDR prefixes whose forwarding headers are
+	// trusted.
+	TrustedProxies []string
+}
+
+// Decision is the outcome of a check, stored in the request or call context.
+type Decision struct {
+	IPAddress string
+	Allowed   bool
+	Country   string // empty when the IP has no country
+	Reason    string // a reason code such as COUNTRY_ALLOWED or PRIVATE_IP
+}
+
+type decisionKey struct{}
+
+// NewContext returns a copy of ctx carrying d.
+func NewContext(ctx context.Context, d Decision) context.Context {
+	return context.WithValue(ctx, decisionKey{}, d)
+}
+
+// FromContext returns the decision stored in ctx, if any.
+func FromContext(ctx context.Context) (Decision, bool) {
+	d, ok := ctx.Value(decisionKey{}).(Decision)
+	return d, ok
+}
+
+// CountryFromContext returns the country of the decision stored in ctx, or "".
+func CountryFromContext(ctx context.Context) string {
+	d, _ := FromContext(ctx)
+	return d.Country
+}
+
+// Fence checks clients against named policies.
+type Fence struct {
+	checker  *geofence.Checker
+	resolver *clientip.Resolver
+	store    *geofence.GeoStore // nil when Config.Lookuper was used
+}
+
+// New validates cfg and opens the database. Call Close to release it.
+func New(cfg Config) (*Fence, error) {
+	resolver, err := clientip.NewResolver(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Fence{resolver: resolver}
+	lookup := cfg.Lookuper
+	if lookup == nil {
+		if cfg.DBPath == "" {
+			return nil, ErrNoDatabase
+		}
+		if f.store, err = geofence.NewGeoStore(cfg.DBPath, geofence.WithProvider(cfg.Provider)); err != nil {
+			return nil, err
+		}
+		lookup = f.store
+	}
+	f.checker = geofence.NewChecker(lookup, geofence.WithPolicies(cfg.Policies))
+	if err := f.checker.Validate(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// CheckRequest checks the client of an HTTP request against the named policy.
+// The decision carries the client IP even when an error is returned.
+func (f *Fence) CheckRequest(r *http.Request, policy string) (Decision, error) {
+	ipAddress, _ := f.resolver.FromRequest(r)
+	return f.check(r.Context(), ipAddress, policy)
+}
+
+// CheckCall checks the client of a gRPC call against the named policy. The
+// decision carries the client IP even when an error is returned.
+func (f *Fence) CheckCall(ctx context.Context, policy string) (Decision, error) {
+	ipAddress, _ := f.resolver.FromContext(ctx)
+	return f.check(ctx, ipAddress, policy)
+}
+
+func (f *Fence) check(ctx context.Context, ipAddress, policy string) (Decision, error) {
+	result, err := f.checker.Evaluate(ctx, ipAddress, geofence.PolicyRef{Name: policy})
+	if err != nil {
+		return Decision{IPAddress: ipAddress}, err
+	}
+	return Decision{
+		IPAddress: ipAddress,
+		Allowed:   result.Allowed,
+		Country:   result.Country,
+		Reason:    string(result.Reason),
+	}, nil
+}
+
+// Reload re-reads the database from DBPath. It is a no-op when Config.Lookuper was
+// used.
+func (f *Fence) Reload() error {
+	if f.store == nil {
+		return nil
+	}
+	return f.store.Reload()
+}
+
+// Close releases the database. It is a no-op when Config.Lookuper was used.
+func (f *Fence) Close() error {
+	if f.store == nil {
+		return nil
+	}
+	return f.store.Close()
+}
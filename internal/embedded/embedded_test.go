@@ -0,0 +1,75 @@
+package embedded
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+	"google.golang.org/grpc/peer"
+)
+
+func newTestFence(t *testing.T, policy Policy) *Fence {
+	t.Helper()
+	compiled, err := policy.Compile()
+	if err != nil {
+		t.Fatalf("Compile() unexpected error: %v", err)
+	}
+	f, err := New(Config{
+		Lookuper: LookupFunc(func(ip net.IP) (string, error) {
+			if ip.String() == "1.2.3.4" {
+				return "", ErrUnknownIP
+			}
+			return "US", nil
+		}),
+		Policies: map[string]geofence.Policy{"us": compiled},
+	})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	return f
+}
+
+func TestFence_Check(t *testing.T) {
+	f := newTestFence(t, Policy{AllowedCountries: []string{"US"}, UnknownIPAction: "error"})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "8.8.8.8:1234"
+	got, err := f.CheckRequest(req, "us")
+	want := Decision{IPAddress: "8.8.8.8", Allowed: true, Country: "US", Reason: "COUNTRY_ALLOWED"}
+	if err != nil || got != want {
+		t.Errorf("CheckRequest() = %+v, %v, want %+v", got, err, want)
+	}
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("1.2.3.4"), Port: 5555}})
+	got, err = f.CheckCall(ctx, "us")
+	if !errors.Is(err, ErrUnknownIP) || got.IPAddress != "1.2.3.4" {
+		t.Errorf("CheckCall() = %+v, %v, want ErrUnknownIP for 1.2.3.4", got, err)
+	}
+
+	if _, err := f.CheckCall(ctx, "eu"); !errors.Is(err, geofence.ErrUnknownPolicy) {
+		t.Errorf("CheckCall() error = %v, want ErrUnknownPolicy", err)
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	if _, err := New(Config{}); !errors.Is(err, ErrNoDatabase) {
+		t.Errorf("New() error = %v, want ErrNoDatabase", err)
+	}
+	if _, err := New(Config{Lookuper: LookupFunc(nil), TrustedProxies: []string{"proxy"}}); err == nil {
+		t.Error("New() expected error for bad trusted proxy, got nil")
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("FromContext() on empty context = ok, want not ok")
+	}
+	ctx := NewContext(context.Background(), Decision{Allowed: true, Country: "GB"})
+	if got := CountryFromContext(ctx); got != "GB" {
+		t.Errorf("CountryFromContext() = %q, want GB", got)
+	}
+}
@@ -0,0 +1,156 @@
+package geofence
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/bits"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// ErrInvalidAnonymity is returned for a denied_anonymity entry that is not one of
+// the names in AnonymityNames.
+var ErrInvalidAnonymity = errors.New("invalid anonymity flag")
+
+// ErrAnonymityUnavailable is returned for a policy with DeniedAnonymity checked by
+// a Checker that has no anonymous IP database.
+var ErrAnonymityUnavailable = errors.New("denied_anonymity needs an anonymous IP database")
+
+// Anonymity is a set of flags describing how an IP may hide its user's location,
+// as reported by an anonymous IP database.
+type Anonymity uint8
+
+const (
+	// AnonymityAnonymous is set for any kind of anonymizing network: a VPN,
+	// proxy or Tor exit.
+	AnonymityAnonymous Anonymity = 1 << iota
+	// AnonymityVPN is set for networks registered to an anonymous VPN provider.
+	AnonymityVPN
+	// AnonymityHosting is set for hosting and cloud providers, where VPN exits
+	// that are not registered as such usually live.
+	AnonymityHosting
+	// AnonymityPublicProxy is set for public proxies.
+	AnonymityPublicProxy
+	// AnonymityResidentialProxy is set for anonymizing networks on residential
+	// ISPs.
+	AnonymityResidentialProxy
+	// AnonymityTor is set for Tor exit nodes.
+	AnonymityTor
+)
+
+// AnonymityNames are the names of the Anonymity flags, in bit order, as used in
+// policies and responses.
+var AnonymityNames = []string{"anonymous", "vpn", "hosting", "public_proxy", "residential_proxy", "tor"}
+
+// Names returns the names of the flags set in a, in bit order, or nil if none are.
+func (a Anonymity) Names() []string {
+	var names []string
+	for i, name := range AnonymityNames {
+		if a&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// first returns the name of the lowest flag set in a, or "" if none are.
+func (a Anonymity) first() string {
+	if a == 0 {
+		return ""
+	}
+	return AnonymityNames[bits.TrailingZeros8(uint8(a))]
+}
+
+// ParseAnonymity returns the flags named in names, case-insensitively. Every
+// invalid name is reported in the error.
+func ParseAnonymity(names []string) (Anonymity, error) {
+	var a Anonymity
+	var invalid []string
+	for _, name := range names {
+		flag, ok := anonymityFlag(name)
+		if !ok {
+			invalid = append(invalid, fmt.Sprintf("%q", name))
+			continue
+		}
+		a |= flag
+	}
+	if len(invalid) > 0 {
+		return 0, fmt.Errorf("%w: %s (want %s)", ErrInvalidAnonymity, strings.Join(invalid, ", "), strings.Join(AnonymityNames, ", "))
+	}
+	return a, nil
+}
+
+func anonymityFlag(name string) (Anonymity, bool) {
+	for i, n := range AnonymityNames {
+		if strings.EqualFold(name, n) {
+			return 1 << i, true
+		}
+	}
+	return 0, false
+}
+
+// AnonymityLookuper reports whether an IP belongs to an anonymizing network.
+// AnonymityStore implements it. IPs the database does not list have no flags;
+// that is not an error.
+type AnonymityLookuper interface {
+	LookupAnonymity(ip net.IP) (Anonymity, error)
+}
+
+// AnonymityStore holds an anonymous IP database. It reloads and watches its file
+// like a GeoStore, but only answers LookupAnonymity. It is safe for concurrent use.
+type AnonymityStore struct {
+	dbFile
+}
+
+// NewAnonymityStore opens an anonymous IP database, to be passed to a Checker with
+// WithAnonymity. The provider, chosen with WithProvider, is ProviderMaxMind (the
+// default) for a GeoIP2-Anonymous-IP file or ProviderCSV for a local list; see
+// openAnonymityCSV for its format.
+func NewAnonymityStore(path string, opts ...StoreOption) (*AnonymityStore, error) {
+	s := &AnonymityStore{}
+	if err := s.init(path, opts, openAnonymityDatabase); err != nil {
+		return nil, err
+	}
+	slog.Info("anonymous IP database opened successfully", "path", path, "provider", s.provider)
+	return s, nil
+}
+
+// WithASNDatabase resolves the AS numbers listed in an anonymity CSV file, such as
+// those of hosting providers, through a GeoLite2-ASN (or DB-IP ASN Lite) database.
+// The ASN database is re-read whenever the store reloads.
+func WithASNDatabase(path string) StoreOption {
+	return func(f *dbFile) {
+		f.asnPath = path
+	}
+}
+
+// LookupAnonymity returns the anonymity flags for ip.
+func (s *AnonymityStore) LookupAnonymity(ip net.IP) (Anonymity, error) {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return 0, fmt.Errorf("invalid IP: %v", ip)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.db == nil {
+		return 0, ErrStoreClosed
+	}
+	info, _, err := s.db.Lookup(addr.Unmap(), DefaultLocale)
+	if err != nil {
+		return 0, fmt.Errorf("lookup anonymity: %w", err)
+	}
+	return info.Anonymity, nil
+}
+
+// compileAnonymity validates DeniedAnonymity into the set of flags to deny.
+func (p *Policy) compileAnonymity() error {
+	denied, err := ParseAnonymity(p.DeniedAnonymity)
+	if err != nil {
+		return err
+	}
+	p.deniedAnonymity = denied
+	return nil
+}
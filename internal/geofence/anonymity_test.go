@@ -0,0 +1,234 @@
+package geofence
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+)
+
+func TestParseAnonymity(t *testing.T) {
+	got, err := ParseAnonymity([]string{"VPN", "tor", "vpn"})
+	if err != nil {
+		t.Fatalf("ParseAnonymity() unexpected error: %v", err)
+	}
+	if got != AnonymityVPN|AnonymityTor {
+		t.Errorf("ParseAnonymity() = %b, want vpn and tor", got)
+	}
+	if names := got.Names(); !reflect.DeepEqual(names, []string{"vpn", "tor"}) {
+		t.Errorf("Names() = %v, want [vpn tor]", names)
+	}
+
+	_, err = ParseAnonymity([]string{"vpn", "proxy", "tor-exit"})
+	if !errors.Is(err, ErrInvalidAnonymity) || !strings.Contains(err.Error(), `"proxy", "tor-exit"`) {
+		t.Errorf("ParseAnonymity() error = %v, want ErrInvalidAnonymity naming both entries", err)
+	}
+}
+
+func TestNewAnonymityStore(t *testing.T) {
+	asnPath := writeMMDB(t, "GeoLite2-ASN", map[string]mmdbtype.Map{
+		"52.94.0.0/16": {"autonomous_system_number": mmdbtype.Uint32(16509), "autonomous_system_organization": mmdbtype.String("AMAZON-02")},
+	})
+
+	tests := []struct {
+		name     string
+		provider string
+		path     func(t *testing.T) string
+		opts     []StoreOption
+		want     map[string]Anonymity
+	}{
+		{
+			name:     "maxmind",
+			provider: ProviderMaxMind,
+			path: func(t *testing.T) string {
+				return writeMMDB(t, "GeoIP2-Anonymous-IP", map[string]mmdbtype.Map{
+					"185.220.101.0/24": {"is_anonymous": mmdbtype.Bool(true), "is_tor_exit_node": mmdbtype.Bool(true)},
+					"52.94.0.0/16":     {"is_hosting_provider": mmdbtype.Bool(true)},
+				})
+			},
+			want: map[string]Anonymity{
+				"185.220.101.7": AnonymityAnonymous | AnonymityTor,
+				"52.94.1.1":     AnonymityHosting,
+				"8.8.8.8":       0,
+			},
+		},
+		{
+			name:     "csv networks and AS numbers",
+			provider: ProviderCSV,
+			path: func(t *testing.T) string {
+				return writeFile(t, "anonymous.csv", strings.Join([]string{
+					"# Tor exits",
+					"185.220.101.7,tor",
+					"185.220.101.0/24,hosting",
+					"AS16509,hosting",
+					"198.51.100.0/24,vpn,public_proxy",
+					"::ffff:45.83.64.0/120,vpn",
+				}, "\n"))
+			},
+			opts: []StoreOption{WithASNDatabase(asnPath)},
+			want: map[string]Anonymity{
+				"185.220.101.7": AnonymityAnonymous | AnonymityTor | AnonymityHosting,
+				"185.220.101.8": AnonymityHosting,
+				"52.94.1.1":     AnonymityHosting,
+				"198.51.100.1":  AnonymityAnonymous | AnonymityVPN | AnonymityPublicProxy,
+				"45.83.64.7":    AnonymityAnonymous | AnonymityVPN,
+				"8.8.8.8":       0,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewAnonymityStore(tt.path(t), append(tt.opts, WithProvider(tt.provider))...)
+			if err != nil {
+				t.Fatalf("NewAnonymityStore() unexpected error: %v", err)
+			}
+			defer store.Close()
+			for ip, want := range tt.want {
+				got, err := store.LookupAnonymity(net.ParseIP(ip))
+				if err != nil || got != want {
+					t.Errorf("LookupAnonymity(%s) = %v, %v, want %v", ip, got.Names(), err, want.Names())
+				}
+			}
+			if store.BuildEpoch().IsZero() {
+				t.Error("BuildEpoch() is zero")
+			}
+			if _, ok := any(store).(CountryLookuper); ok {
+				t.Error("AnonymityStore is a CountryLookuper; it would answer every country lookup with ErrUnknownIP")
+			}
+		})
+	}
+}
+
+func TestNewAnonymityStore_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		opts    []StoreOption
+		wantErr string
+	}{
+		{name: "unknown flag", content: "185.220.101.7,exit\n", opts: []StoreOption{WithProvider(ProviderCSV)}, wantErr: "line 1"},
+		{name: "missing flags", content: "185.220.101.7\n", opts: []StoreOption{WithProvider(ProviderCSV)}, wantErr: "line 1"},
+		{name: "bad AS number", content: "ASX,hosting\n", opts: []StoreOption{WithProvider(ProviderCSV)}, wantErr: "invalid AS number"},
+		{name: "AS numbers without an ASN database", content: "AS16509,hosting\n", opts: []StoreOption{WithProvider(ProviderCSV)}, wantErr: "no ASN database"},
+		{name: "country-only provider", content: "185.220.101.7,tor\n", opts: []StoreOption{WithProvider(ProviderIPinfo)}, wantErr: ErrUnknownProvider.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewAnonymityStore(writeFile(t, "anonymous.csv", tt.content), tt.opts...)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewAnonymityStore() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// mockAnonymityLookuper reports fixed flags per IP.
+type mockAnonymityLookuper map[string]Anonymity
+
+func (m mockAnonymityLookuper) LookupAnonymity(ip net.IP) (Anonymity, error) {
+	return m[ip.String()], nil
+}
+
+func TestChecker_Anonymity(t *testing.T) {
+	lookup := mockLookuper{lookup: func(ip net.IP) (string, error) {
+		if ip.String() == "1.1.1.1" {
+			return "", ErrUnknownIP
+		}
+		return "US", nil
+	}}
+	anonymity := mockAnonymityLookuper{
+		"185.220.101.7": AnonymityAnonymous | AnonymityTor | AnonymityHosting,
+		"52.94.1.1":     AnonymityHosting,
+		"45.83.64.7":    AnonymityAnonymous | AnonymityVPN,
+		"1.1.1.1":       AnonymityAnonymous | AnonymityVPN,
+	}
+	checker := NewChecker(lookup, WithAnonymity(anonymity))
+
+	tests := []struct {
+		name   string
+		ip     string
+		policy Policy
+		want   CheckResult
+	}{
+		{
+			name:   "flags reported without denying",
+			ip:     "52.94.1.1",
+			policy: Policy{AllowedCountries: []string{"US"}},
+			want:   CheckResult{Allowed: true, Country: "US", DecidedBy: DecidedByCountry, IPStatus: IPStatusGeolocated, Reason: ReasonCountryAllowed, MatchedRule: "US", Anonymity: AnonymityHosting},
+		},
+		{
+			name:   "denied flag",
+			ip:     "185.220.101.7",
+			policy: Policy{AllowedCountries: []string{"US"}, DeniedAnonymity: []string{"vpn", "tor", "hosting"}},
+			want:   CheckResult{Allowed: false, Country: "US", DecidedBy: DecidedByAnonymity, IPStatus: IPStatusGeolocated, Reason: ReasonAnonymousIP, MatchedRule: "hosting", Anonymity: AnonymityAnonymous | AnonymityTor | AnonymityHosting},
+		},
+		{
+			name:   "other flags allowed",
+			ip:     "52.94.1.1",
+			policy: Policy{AllowedCountries: []string{"US"}, DeniedAnonymity: []string{"anonymous"}},
+			want:   CheckResult{Allowed: true, Country: "US", DecidedBy: DecidedByCountry, IPStatus: IPStatusGeolocated, Reason: ReasonCountryAllowed, MatchedRule: "US", Anonymity: AnonymityHosting},
+		},
+		{
+			name:   "country denial keeps its reason",
+			ip:     "45.83.64.7",
+			policy: Policy{AllowedCountries: []string{"CA"}, DeniedAnonymity: []string{"vpn"}},
+			want:   CheckResult{Allowed: false, Country: "US", DecidedBy: DecidedByCountry, IPStatus: IPStatusGeolocated, Reason: ReasonCountryNotInList, Anonymity: AnonymityAnonymous | AnonymityVPN},
+		},
+		{
+			name:   "override decides first",
+			ip:     "45.83.64.7",
+			policy: Policy{AllowedCountries: []string{"US"}, AllowedNetworks: []string{"45.83.64.0/24"}, DeniedAnonymity: []string{"vpn"}},
+			want:   CheckResult{Allowed: true, DecidedBy: DecidedByOverride, Reason: ReasonOverrideRule, MatchedRule: "45.83.64.0/24"},
+		},
+		{
+			name:   "unknown IP allowed by its action",
+			ip:     "1.1.1.1",
+			policy: Policy{AllowedCountries: []string{"US"}, UnknownIPAction: UnknownIPAllow, DeniedAnonymity: []string{"vpn"}},
+			want:   CheckResult{Allowed: false, DecidedBy: DecidedByAnonymity, IPStatus: IPStatusUnknown, Reason: ReasonAnonymousIP, MatchedRule: "vpn", Anonymity: AnonymityAnonymous | AnonymityVPN},
+		},
+		{
+			name:   "private IPs are not looked up",
+			ip:     "10.0.0.1",
+			policy: Policy{AllowedCountries: []string{"US"}, UnknownIPAction: UnknownIPAllow, DeniedAnonymity: []string{"vpn"}},
+			want:   CheckResult{Allowed: true, IPStatus: IPStatusPrivate, Reason: ReasonPrivateIP, MatchedRule: "10.0.0.0/8"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checker.CheckPolicy(context.Background(), tt.ip, tt.policy)
+			if err != nil {
+				t.Fatalf("CheckPolicy() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckPolicy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	result, err := checker.Lookup(context.Background(), "52.94.1.1", "")
+	if err != nil || result.Anonymity != AnonymityHosting {
+		t.Errorf("Lookup() = %+v, %v, want hosting", result, err)
+	}
+}
+
+func TestChecker_AnonymityErrors(t *testing.T) {
+	lookup := mockLookuper{lookup: func(net.IP) (string, error) { return "US", nil }}
+
+	_, err := NewChecker(lookup).CheckPolicy(context.Background(), "8.8.8.8", Policy{AllowedCountries: []string{"US"}, DeniedAnonymity: []string{"vpn"}})
+	if !errors.Is(err, ErrAnonymityUnavailable) {
+		t.Errorf("CheckPolicy() without WithAnonymity error = %v, want ErrAnonymityUnavailable", err)
+	}
+
+	checker := NewChecker(lookup, WithAnonymity(mockAnonymityLookuper{}))
+	_, err = checker.CheckPolicy(context.Background(), "8.8.8.8", Policy{AllowedCountries: []string{"US"}, DeniedAnonymity: []string{"proxy"}})
+	if !errors.Is(err, ErrInvalidAnonymity) {
+		t.Errorf("CheckPolicy() error = %v, want ErrInvalidAnonymity", err)
+	}
+}
@@ -0,0 +1,64 @@
+package geofence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MaxBatchSize caps the number of items accepted by CheckBatch.
+const MaxBatchSize = 1000
+
+// ErrEmptyBatch is returned when a batch contains no items.
+var ErrEmptyBatch = errors.New("batch must contain at least one item")
+
+// ErrBatchTooLarge is returned when a batch contains more than MaxBatchSize items.
+var ErrBatchTooLarge = fmt.Errorf("batch must not contain more than %d items", MaxBatchSize)
+
+// BatchItem is a single IP to check in a batch. Policy overrides the batch-wide
+// policy when set.
+type BatchItem struct {
+	IP     string
+	Policy PolicyRef
+}
+
+// BatchResult is the outcome of one BatchItem. Err holds the same errors Evaluate
+// would return for that item, so one bad IP does not fail the whole batch.
+type BatchResult struct {
+	CheckResult
+	Err error
+}
+
+// CheckBatch evaluates every item and returns results in input order. Items without
+// their own policy use shared, keeping the item's UnknownIPAction if it set one.
+// Only batch-level problems (empty or oversized batch) are returned as an error;
+// per-item failures are reported in BatchResult.Err.
+func (c *Checker) CheckBatch(ctx context.Context, items []BatchItem, shared PolicyRef) ([]BatchResult, error) {
+	if len(items) == 0 {
+		return nil, ErrEmptyBatch
+	}
+	if len(items) > MaxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+
+	ctx, span := tracer.Start(ctx, "geofence.CheckBatch", trace.WithAttributes(attribute.Int("geofence.batch_size", len(items))))
+	defer span.End()
+
+	results := make([]BatchResult, len(items))
+	for i, item := range items {
+		ref := item.Policy
+		if ref.IsZero() {
+			action := ref.Inline.UnknownIPAction
+			ref = shared
+			if action != "" {
+				ref.Inline.UnknownIPAction = action
+			}
+		}
+		result, err := c.Evaluate(ctx, item.IP, ref)
+		results[i] = BatchResult{CheckResult: result, Err: err}
+	}
+	return results, nil
+}
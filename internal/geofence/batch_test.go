@@ -0,0 +1,76 @@
+package geofence
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestChecker_CheckBatch(t *testing.T) {
+	lookup := mockLookuper{lookup: func(ip net.IP) (string, error) {
+		switch ip.String() {
+		case "8.8.8.8":
+			return "US", nil
+		case "81.2.69.142":
+			return "GB", nil
+		default:
+			return "", ErrUnknownIP
+		}
+	}}
+	checker := NewChecker(lookup)
+
+	items := []BatchItem{
+		{IP: "8.8.8.8"},
+		{IP: "81.2.69.142"},
+		{IP: "81.2.69.142", Policy: PolicyRef{Inline: Policy{AllowedCountries: []string{"GB"}}}},
+		{IP: "not-an-ip"},
+		{IP: "192.168.1.1"},
+	}
+	results, err := checker.CheckBatch(context.Background(), items, PolicyRef{Inline: Policy{AllowedCountries: []string{"US"}}})
+	if err != nil {
+		t.Fatalf("CheckBatch: %v", err)
+	}
+	if len(results) != len(items) {
+		t.Fatalf("len(results) = %d, want %d", len(results), len(items))
+	}
+
+	tests := []struct {
+		wantAllowed bool
+		wantCountry string
+		wantErr     error
+	}{
+		{wantAllowed: true, wantCountry: "US"},
+		{wantAllowed: false, wantCountry: "GB"},
+		{wantAllowed: true, wantCountry: "GB"},
+		{wantErr: ErrInvalidIP},
+		{wantAllowed: false, wantCountry: ""},
+	}
+	for i, tt := range tests {
+		got := results[i]
+		if tt.wantErr != nil {
+			if !errors.Is(got.Err, tt.wantErr) {
+				t.Errorf("results[%d].Err = %v, want %v", i, got.Err, tt.wantErr)
+			}
+			continue
+		}
+		if got.Err != nil {
+			t.Errorf("results[%d].Err = %v, want nil", i, got.Err)
+		}
+		if got.Allowed != tt.wantAllowed || got.Country != tt.wantCountry {
+			t.Errorf("results[%d] = {%v %q}, want {%v %q}", i, got.Allowed, got.Country, tt.wantAllowed, tt.wantCountry)
+		}
+	}
+}
+
+func TestChecker_CheckBatch_Limits(t *testing.T) {
+	checker := NewChecker(mockLookuper{lookup: func(net.IP) (string, error) { return "US", nil }})
+
+	shared := PolicyRef{Inline: Policy{AllowedCountries: []string{"US"}}}
+	if _, err := checker.CheckBatch(context.Background(), nil, shared); !errors.Is(err, ErrEmptyBatch) {
+		t.Errorf("empty batch: err = %v, want ErrEmptyBatch", err)
+	}
+	if _, err := checker.CheckBatch(context.Background(), make([]BatchItem, MaxBatchSize+1), shared); !errors.Is(err, ErrBatchTooLarge) {
+		t.Errorf("oversized batch: err = %v, want ErrBatchTooLarge", err)
+	}
+}
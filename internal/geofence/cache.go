@@ -0,0 +1,298 @@
+package geofence
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultCacheTTL is how long CachedLookuper keeps an entry when no TTL is given.
+const DefaultCacheTTL = time.Hour
+
+// NetworkLookuper is a CountryLookuper that also reports the network its answer
+// applies to, so the answer can be reused for every address in that network.
+// GeoStore implements it. For IPs missing from the database it returns
+// ErrUnknownIP together with the network that is missing.
+type NetworkLookuper interface {
+	LookupNetwork(ip net.IP) (country string, network netip.Prefix, err error)
+}
+
+// contextNetworkLookuper is a NetworkLookuper whose lookups take the caller's
+// context, as GeoStore's do.
+type contextNetworkLookuper interface {
+	LookupNetworkContext(ctx context.Context, ip net.IP) (country string, network netip.Prefix, err error)
+}
+
+// CacheStats are counters reported by CachedLookuper.Stats.
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+}
+
+// CachedLookuper is a CountryLookuper that caches another lookuper's answers in a
+// bounded cache. When the underlying lookuper is a NetworkLookuper, entries are
+// keyed by the network the database matched, so one entry answers for a whole /24
+// or larger block; otherwise they are keyed by address. IPs missing from the
+// database are cached too. It is safe for concurrent use.
+//
+// Eviction uses CLOCK, an approximation of LRU: a hit only sets the entry's
+// referenced bit, so hits share a read lock, and a full cache evicts the first
+// entry the clock hand finds that has not been referenced since the hand last
+// passed it.
+//
+// Cached answers outlive database reloads until Purge is called; GeoStore.OnReload
+// can do that automatically.
+type CachedLookuper struct {
+	next CountryLookuper
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.RWMutex
+	entries map[netip.Prefix]*clockEntry
+	clock   []*clockEntry // at most size entries, in slot order
+	hand    int           // next slot the clock examines for eviction
+	// lengths counts entries per prefix length for IPv4 and IPv6, so a lookup
+	// only probes the lengths that are actually cached.
+	lengths    [2][129]int
+	generation uint64 // incremented by Purge
+
+	hits, misses, evictions atomic.Uint64
+}
+
+type cacheEntry struct {
+	network netip.Prefix
+	country string
+	unknown bool // the network is not in the database
+	expires time.Time
+}
+
+// clockEntry is a cached entry and its CLOCK state. The entry is only written
+// under the write lock; referenced is set by hits holding the read lock.
+type clockEntry struct {
+	cacheEntry
+	referenced atomic.Bool
+}
+
+// NewCachedLookuper returns a cache of at most size entries in front of next.
+// Entries expire after ttl, or DefaultCacheTTL if ttl is zero.
+func NewCachedLookuper(next CountryLookuper, size int, ttl time.Duration) *CachedLookuper {
+	if size < 1 {
+		size = 1
+	}
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	return &CachedLookuper{
+		next:    next,
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[netip.Prefix]*clockEntry, size),
+		clock:   make([]*clockEntry, 0, size),
+	}
+}
+
+// Lookup returns the country for ip from the cache, falling back to the underlying
+// lookuper on a miss. Errors other than ErrUnknownIP are not cached.
+func (c *CachedLookuper) Lookup(ip net.IP) (string, error) {
+	return c.LookupContext(context.Background(), ip)
+}
+
+// LookupContext is Lookup passing ctx to the underlying lookuper on a miss, so a
+// GeoStore traces the database lookup. Hits set geofence.cache_hit on the span in
+// ctx instead.
+func (c *CachedLookuper) LookupContext(ctx context.Context, ip net.IP) (string, error) {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return c.next.Lookup(ip)
+	}
+	addr = addr.Unmap()
+
+	if entry, ok := c.get(addr); ok {
+		c.hits.Add(1)
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("geofence.cache_hit", true))
+		if entry.unknown {
+			return "", ErrUnknownIP
+		}
+		return entry.country, nil
+	}
+	c.misses.Add(1)
+
+	c.mu.RLock()
+	generation := c.generation
+	c.mu.RUnlock()
+
+	country, network, err := c.lookupNetwork(ctx, ip, addr)
+	unknown := errors.Is(err, ErrUnknownIP)
+	if (err == nil || unknown) && network.IsValid() {
+		c.add(generation, cacheEntry{network: network, country: country, unknown: unknown})
+	}
+	return country, err
+}
+
+// lookupNetwork asks the underlying lookuper, keying by addr alone when it cannot
+// report networks.
+func (c *CachedLookuper) lookupNetwork(ctx context.Context, ip net.IP, addr netip.Addr) (string, netip.Prefix, error) {
+	var country string
+	var network netip.Prefix
+	var err error
+	switch next := c.next.(type) {
+	case contextNetworkLookuper:
+		country, network, err = next.LookupNetworkContext(ctx, ip)
+	case NetworkLookuper:
+		country, network, err = next.LookupNetwork(ip)
+	case ContextLookuper:
+		country, err = next.LookupContext(ctx, ip)
+		return country, netip.PrefixFrom(addr, addr.BitLen()), err
+	default:
+		country, err = next.Lookup(ip)
+		return country, netip.PrefixFrom(addr, addr.BitLen()), err
+	}
+	if network.Addr().Is4In6() && network.Bits() >= 96 {
+		network = netip.PrefixFrom(network.Addr().Unmap(), network.Bits()-96)
+	}
+	return country, network.Masked(), err
+}
+
+// LookupInfo passes through to the underlying lookuper so Checker.Lookup keeps
+// returning full records. Lookupers that are not InfoLookupers report only the
+// country.
+func (c *CachedLookuper) LookupInfo(ip net.IP, locale string) (GeoInfo, error) {
+	if info, ok := c.next.(InfoLookuper); ok {
+		return info.LookupInfo(ip, locale)
+	}
+	country, err := c.Lookup(ip)
+	return GeoInfo{Country: Country{ISOCode: country}}, err
+}
+
+// LookupInfoContext is LookupInfo passing ctx to the underlying lookuper.
+func (c *CachedLookuper) LookupInfoContext(ctx context.Context, ip net.IP, locale string) (GeoInfo, error) {
+	if info, ok := c.next.(ContextInfoLookuper); ok {
+		return info.LookupInfoContext(ctx, ip, locale)
+	}
+	if info, ok := c.next.(InfoLookuper); ok {
+		return info.LookupInfo(ip, locale)
+	}
+	country, err := c.LookupContext(ctx, ip)
+	return GeoInfo{Country: Country{ISOCode: country}}, err
+}
+
+// Unwrap returns the underlying lookuper.
+func (c *CachedLookuper) Unwrap() CountryLookuper {
+	return c.next
+}
+
+// Provider reports the underlying lookuper's provider, or "" if it does not
+// report one.
+func (c *CachedLookuper) Provider() string {
+	if p, ok := c.next.(ProviderReporter); ok {
+		return p.Provider()
+	}
+	return ""
+}
+
+func (c *CachedLookuper) get(addr netip.Addr) (cacheEntry, bool) {
+	family := familyIndex(addr)
+	now := c.now()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for bits := addr.BitLen(); bits >= 0; bits-- {
+		if c.lengths[family][bits] == 0 {
+			continue
+		}
+		prefix, _ := addr.Prefix(bits)
+		entry, ok := c.entries[prefix]
+		if !ok {
+			continue
+		}
+		if now.After(entry.expires) {
+			// The lookup that follows the miss replaces the entry.
+			return cacheEntry{}, false
+		}
+		if !entry.referenced.Load() {
+			// Skip the store when the bit is set, so hot entries stay read-only.
+			entry.referenced.Store(true)
+		}
+		return entry.cacheEntry, true
+	}
+	return cacheEntry{}, false
+}
+
+// add stores entry unless the cache was purged since generation was read, which
+// means the answer may come from a database that has since been replaced.
+func (c *CachedLookuper) add(generation uint64, entry cacheEntry) {
+	entry.expires = c.now().Add(c.ttl)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	if existing, ok := c.entries[entry.network]; ok {
+		existing.cacheEntry = entry
+		existing.referenced.Store(true)
+		return
+	}
+
+	added := &clockEntry{cacheEntry: entry}
+	c.entries[entry.network] = added
+	c.lengths[familyIndex(entry.network.Addr())][entry.network.Bits()]++
+	if len(c.clock) < c.size {
+		c.clock = append(c.clock, added)
+		return
+	}
+	// Give referenced entries a second chance; the hand stops at the first entry
+	// that has not been used since it last came round.
+	for c.clock[c.hand].referenced.Swap(false) {
+		c.hand = (c.hand + 1) % len(c.clock)
+	}
+	evicted := c.clock[c.hand]
+	delete(c.entries, evicted.network)
+	c.lengths[familyIndex(evicted.network.Addr())][evicted.network.Bits()]--
+	c.clock[c.hand] = added
+	c.hand = (c.hand + 1) % len(c.clock)
+	c.evictions.Add(1)
+}
+
+// Purge drops every entry, e.g. after the database is reloaded. Lookups that were
+// in flight when Purge was called do not repopulate the cache.
+func (c *CachedLookuper) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[netip.Prefix]*clockEntry, c.size)
+	c.clock = c.clock[:0]
+	c.hand = 0
+	c.lengths = [2][129]int{}
+	c.generation++
+}
+
+// Stats returns the cache's hit, miss and eviction counts and current size.
+func (c *CachedLookuper) Stats() CacheStats {
+	c.mu.RLock()
+	entries := len(c.entries)
+	c.mu.RUnlock()
+	return CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Entries:   entries,
+	}
+}
+
+func familyIndex(addr netip.Addr) int {
+	if addr.Is4() {
+		return 0
+	}
+	return 1
+}
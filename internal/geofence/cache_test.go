@@ -0,0 +1,351 @@
+package geofence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// networkLookuper answers from a fixed table of networks and counts its calls.
+type networkLookuper struct {
+	networks map[netip.Prefix]string
+	calls    atomic.Int64
+}
+
+func (l *networkLookuper) Lookup(ip net.IP) (string, error) {
+	country, _, err := l.LookupNetwork(ip)
+	return country, err
+}
+
+func (l *networkLookuper) LookupNetwork(ip net.IP) (string, netip.Prefix, error) {
+	l.calls.Add(1)
+	addr, _ := netip.AddrFromSlice(ip)
+	addr = addr.Unmap()
+	for network, country := range l.networks {
+		if network.Contains(addr) {
+			if country == "" {
+				return "", network, ErrUnknownIP
+			}
+			return country, network, nil
+		}
+	}
+	return "", netip.Prefix{}, errors.New("no network")
+}
+
+func newNetworkLookuper() *networkLookuper {
+	return &networkLookuper{networks: map[netip.Prefix]string{
+		netip.MustParsePrefix("81.2.69.0/24"):  "GB",
+		netip.MustParsePrefix("8.8.0.0/16"):    "US",
+		netip.MustParsePrefix("2001:db8::/32"): "DE",
+		netip.MustParsePrefix("10.0.0.0/8"):    "",
+	}}
+}
+
+func TestCachedLookuper_Lookup(t *testing.T) {
+	tests := []struct {
+		name        string
+		ips         []string
+		wantCountry string
+		wantErr     error
+		wantCalls   int64
+	}{
+		{
+			name:        "one network shares an entry",
+			ips:         []string{"81.2.69.1", "81.2.69.142", "::ffff:81.2.69.200"},
+			wantCountry: "GB",
+			wantCalls:   1,
+		},
+		{
+			name:        "IPv6 network",
+			ips:         []string{"2001:db8::1", "2001:db8:ffff::1"},
+			wantCountry: "DE",
+			wantCalls:   1,
+		},
+		{
+			name:      "unknown networks are cached",
+			ips:       []string{"10.1.1.1", "10.2.2.2"},
+			wantErr:   ErrUnknownIP,
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := newNetworkLookuper()
+			cache := NewCachedLookuper(next, 10, time.Minute)
+			for _, ip := range tt.ips {
+				country, err := cache.Lookup(net.ParseIP(ip))
+				if !errors.Is(err, tt.wantErr) || country != tt.wantCountry {
+					t.Errorf("Lookup(%s) = %q, %v, want %q, %v", ip, country, err, tt.wantCountry, tt.wantErr)
+				}
+			}
+			if got := next.calls.Load(); got != tt.wantCalls {
+				t.Errorf("underlying lookups = %d, want %d", got, tt.wantCalls)
+			}
+			stats := cache.Stats()
+			if want := uint64(len(tt.ips)) - uint64(tt.wantCalls); stats.Hits != want || stats.Misses != uint64(tt.wantCalls) {
+				t.Errorf("Stats() = %+v, want %d hits and %d misses", stats, want, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestCachedLookuper_ErrorsNotCached(t *testing.T) {
+	next := newNetworkLookuper()
+	cache := NewCachedLookuper(next, 10, time.Minute)
+	for range 2 {
+		if _, err := cache.Lookup(net.ParseIP("192.0.2.1")); err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	}
+	if got := next.calls.Load(); got != 2 {
+		t.Errorf("underlying lookups = %d, want 2", got)
+	}
+}
+
+func TestCachedLookuper_AddressKeys(t *testing.T) {
+	var calls atomic.Int64
+	next := mockLookuper{lookup: func(net.IP) (string, error) {
+		calls.Add(1)
+		return "US", nil
+	}}
+	cache := NewCachedLookuper(next, 10, time.Minute)
+	for _, ip := range []string{"8.8.8.8", "8.8.8.8", "8.8.4.4"} {
+		if country, err := cache.Lookup(net.ParseIP(ip)); err != nil || country != "US" {
+			t.Fatalf("Lookup(%s) = %q, %v", ip, country, err)
+		}
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("underlying lookups = %d, want 2 (one per address)", got)
+	}
+}
+
+func TestCachedLookuper_TTL(t *testing.T) {
+	next := newNetworkLookuper()
+	cache := NewCachedLookuper(next, 10, time.Minute)
+	now := time.Unix(1_700_000_000, 0)
+	cache.now = func() time.Time { return now }
+
+	_, _ = cache.Lookup(net.ParseIP("8.8.8.8"))
+	now = now.Add(59 * time.Second)
+	_, _ = cache.Lookup(net.ParseIP("8.8.4.4"))
+	if got := next.calls.Load(); got != 1 {
+		t.Fatalf("underlying lookups before expiry = %d, want 1", got)
+	}
+	now = now.Add(2 * time.Second)
+	_, _ = cache.Lookup(net.ParseIP("8.8.8.8"))
+	if got := next.calls.Load(); got != 2 {
+		t.Errorf("underlying lookups after expiry = %d, want 2", got)
+	}
+}
+
+func TestCachedLookuper_Eviction(t *testing.T) {
+	next := newNetworkLookuper()
+	cache := NewCachedLookuper(next, 2, time.Minute)
+
+	_, _ = cache.Lookup(net.ParseIP("81.2.69.1")) // GB
+	_, _ = cache.Lookup(net.ParseIP("8.8.8.8"))   // US
+	_, _ = cache.Lookup(net.ParseIP("81.2.69.2")) // GB is referenced again
+	_, _ = cache.Lookup(net.ParseIP("2001:db8::1"))
+
+	stats := cache.Stats()
+	if stats.Entries != 2 || stats.Evictions != 1 {
+		t.Fatalf("Stats() = %+v, want 2 entries and 1 eviction", stats)
+	}
+	calls := next.calls.Load()
+	_, _ = cache.Lookup(net.ParseIP("81.2.69.3"))
+	if next.calls.Load() != calls {
+		t.Error("referenced entry was evicted")
+	}
+	_, _ = cache.Lookup(net.ParseIP("8.8.8.8"))
+	if next.calls.Load() != calls+1 {
+		t.Error("unreferenced entry was not evicted")
+	}
+}
+
+// reloadingLookuper purges the cache in the middle of a lookup, as a database
+// reload racing with a miss would.
+type reloadingLookuper struct {
+	*networkLookuper
+	purge func()
+}
+
+func (l reloadingLookuper) LookupNetwork(ip net.IP) (string, netip.Prefix, error) {
+	l.purge()
+	return l.networkLookuper.LookupNetwork(ip)
+}
+
+func TestCachedLookuper_Purge(t *testing.T) {
+	next := newNetworkLookuper()
+	cache := NewCachedLookuper(next, 10, time.Minute)
+	_, _ = cache.Lookup(net.ParseIP("8.8.8.8"))
+	cache.Purge()
+	if stats := cache.Stats(); stats.Entries != 0 {
+		t.Fatalf("Entries after Purge = %d, want 0", stats.Entries)
+	}
+
+	racing := NewCachedLookuper(nil, 10, time.Minute)
+	racing.next = reloadingLookuper{networkLookuper: newNetworkLookuper(), purge: racing.Purge}
+	if country, err := racing.Lookup(net.ParseIP("8.8.8.8")); err != nil || country != "US" {
+		t.Fatalf("Lookup() = %q, %v", country, err)
+	}
+	if stats := racing.Stats(); stats.Entries != 0 {
+		t.Errorf("lookup in flight during Purge was cached")
+	}
+}
+
+func TestCachedLookuper_Concurrent(t *testing.T) {
+	next := newNetworkLookuper()
+	cache := NewCachedLookuper(next, 8, time.Minute)
+
+	var wg sync.WaitGroup
+	for g := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 500 {
+				ip := net.ParseIP(fmt.Sprintf("81.2.69.%d", (g+i)%256))
+				if i%3 == 0 {
+					ip = net.ParseIP(fmt.Sprintf("8.8.%d.%d", i%256, g))
+				}
+				if i%100 == 0 {
+					cache.Purge()
+				}
+				if _, err := cache.Lookup(ip); err != nil {
+					t.Errorf("Lookup(%s): %v", ip, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := cache.Stats()
+	if stats.Hits+stats.Misses != 16*500 {
+		t.Errorf("hits+misses = %d, want %d", stats.Hits+stats.Misses, 16*500)
+	}
+}
+
+func TestGeoStore_OnReloadPurgesCache(t *testing.T) {
+	dbPath := filepath.Join("..", "..", "data", "GeoLite2-Country.mmdb")
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		t.Skipf("GeoLite2-Country.mmdb not found at %s; skip cache reload test", dbPath)
+	}
+	store, err := NewGeoStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewGeoStore: %v", err)
+	}
+	defer store.Close()
+
+	cache := NewCachedLookuper(store, 10, time.Minute)
+	store.OnReload(cache.Purge)
+	for _, ip := range []string{"81.2.69.142", "81.2.69.143"} {
+		if country, err := cache.Lookup(net.ParseIP(ip)); err != nil || country != "GB" {
+			t.Fatalf("Lookup(%s) = %q, %v, want GB", ip, country, err)
+		}
+	}
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Entries != 1 {
+		t.Errorf("Stats() = %+v, want 1 hit sharing 1 entry", stats)
+	}
+	if err := store.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if stats := cache.Stats(); stats.Entries != 0 {
+		t.Errorf("Entries after reload = %d, want 0", stats.Entries)
+	}
+}
+
+// BenchmarkCachedLookuper_Hit measures concurrent cache hits, which share a read
+// lock.
+func BenchmarkCachedLookuper_Hit(b *testing.B) {
+	cache := NewCachedLookuper(newNetworkLookuper(), 1024, time.Hour)
+	ips := []net.IP{net.ParseIP("8.8.8.8"), net.ParseIP("81.2.69.142"), net.ParseIP("2001:db8::1")}
+	for _, ip := range ips {
+		_, _ = cache.Lookup(ip)
+	}
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			if _, err := cache.Lookup(ips[i%len(ips)]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestGeoStore_Tracing(t *testing.T) {
+	recorder, provider := recordSpans(t)
+
+	store, err := NewGeoStore(writeMMDB(t, "GeoLite2-Country", map[string]mmdbtype.Map{"81.2.69.0/24": geoip2Record("GB", "United Kingdom", "EU")}))
+	if err != nil {
+		t.Fatalf("NewGeoStore() unexpected error: %v", err)
+	}
+	defer store.Close()
+	checker := NewChecker(NewCachedLookuper(store, 10, time.Minute))
+
+	ended := func(name string) []sdktrace.ReadOnlySpan {
+		var spans []sdktrace.ReadOnlySpan
+		for _, span := range recorder.Ended() {
+			if span.Name() == name {
+				spans = append(spans, span)
+			}
+		}
+		return spans
+	}
+	attributes := func(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+		got := make(map[attribute.Key]attribute.Value)
+		for _, kv := range span.Attributes() {
+			got[kv.Key] = kv.Value
+		}
+		return got
+	}
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	for _, ip := range []string{"81.2.69.1", "81.2.69.2"} {
+		if _, err := checker.Check(ctx, ip, []string{"GB"}); err != nil {
+			t.Fatalf("Check(%s) unexpected error: %v", ip, err)
+		}
+	}
+	parent.End()
+
+	checks, lookups := ended("geofence.Check"), ended("geofence.Lookup")
+	if len(checks) != 2 || len(lookups) != 1 {
+		t.Fatalf("ended %d checks and %d lookups, want 2 and 1", len(checks), len(lookups))
+	}
+	if lookups[0].Parent().SpanID() != checks[0].SpanContext().SpanID() {
+		t.Error("geofence.Lookup is not a child of the first geofence.Check")
+	}
+	want := map[attribute.Key]attribute.Value{
+		"geofence.provider": attribute.StringValue(ProviderMaxMind),
+		"geofence.country":  attribute.StringValue("GB"),
+		"geofence.network":  attribute.StringValue("81.2.69.0/24"),
+	}
+	got := attributes(lookups[0])
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("lookup attribute %s = %v, want %v", key, got[key].Emit(), value.Emit())
+		}
+	}
+	if hit := attributes(checks[1])["geofence.cache_hit"]; !hit.AsBool() {
+		t.Error("second geofence.Check does not report geofence.cache_hit")
+	}
+
+	if _, err := store.Lookup(net.ParseIP("81.2.69.3")); err != nil {
+		t.Fatalf("Lookup() unexpected error: %v", err)
+	}
+	if n := len(ended("geofence.Lookup")); n != 1 {
+		t.Errorf("lookup outside a trace ended a span; %d lookup spans, want 1", n)
+	}
+}
@@ -1,14 +1,26 @@
 package geofence
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
-	"strings"
+	"net/netip"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
-// ErrEmptyAllowedCountries is returned when allowed_countries is empty.
-var ErrEmptyAllowedCountries = errors.New("allowed_countries must contain at least one country")
+// tracer records spans for checks and lookups. It uses the global TracerProvider,
+// so spans are dropped until main installs one.
+var tracer = otel.Tracer("github.com/jadenmounteer/avoxi-geo-fence/internal/geofence")
+
+// ErrEmptyAllowedCountries is returned when allowed_countries and allowed_regions
+// are both empty.
+var ErrEmptyAllowedCountries = errors.New("allowed_countries or allowed_regions must contain at least one entry")
 
 // ErrInvalidIP is returned when the IP address string cannot be parsed.
 var ErrInvalidIP = errors.New("invalid IP")
@@ -18,47 +30,502 @@ type CountryLookuper interface {
 	Lookup(ip net.IP) (string, error)
 }
 
+// ContextLookuper is a CountryLookuper whose lookups take the caller's context, so
+// they can be traced under the check that made them. GeoStore and CachedLookuper
+// implement it.
+type ContextLookuper interface {
+	LookupContext(ctx context.Context, ip net.IP) (string, error)
+}
+
+// ProviderReporter is implemented by lookupers that know which GeoIP provider
+// answers their lookups, such as GeoStore. Checks and lookups report it.
+type ProviderReporter interface {
+	Provider() string
+}
+
+// DecisionSource identifies which kind of rule produced a decision.
+type DecisionSource string
+
+const (
+	// DecidedByCountry means the decision came from the country allow/deny lists.
+	DecidedByCountry DecisionSource = "country"
+	// DecidedByOverride means an allowed or denied network matched before the country lookup.
+	DecidedByOverride DecisionSource = "override"
+	// DecidedByAnonymity means the IP's anonymity flags denied it after its country
+	// was allowed.
+	DecidedByAnonymity DecisionSource = "anonymity"
+)
+
+// IPStatus describes what is known about an IP's location.
+type IPStatus string
+
+const (
+	// IPStatusGeolocated means the IP was found in the database.
+	IPStatusGeolocated IPStatus = "geolocated"
+	// IPStatusUnknown means the IP is public but not in the database.
+	IPStatusUnknown IPStatus = "unknown"
+	// IPStatusPrivate means the IP is private, loopback or reserved and was not looked up.
+	IPStatusPrivate IPStatus = "private"
+)
+
+// Reason is a structured explanation of a decision.
+type Reason string
+
+const (
+	// ReasonCountryAllowed means the country matched the allow list (explicitly or by wildcard).
+	ReasonCountryAllowed Reason = "COUNTRY_ALLOWED"
+	// ReasonCountryNotInList means the country matched neither list.
+	ReasonCountryNotInList Reason = "COUNTRY_NOT_IN_LIST"
+	// ReasonCountryDenied means the country matched the deny list (explicitly or by wildcard).
+	ReasonCountryDenied Reason = "COUNTRY_DENIED"
+	// ReasonUnknownIP means the IP is not in the database; UnknownIPAction decided.
+	ReasonUnknownIP Reason = "UNKNOWN_IP"
+	// ReasonPrivateIP means the IP is private, loopback or reserved; UnknownIPAction decided.
+	ReasonPrivateIP Reason = "PRIVATE_IP"
+	// ReasonOverrideRule means an allowed or denied network matched.
+	ReasonOverrideRule Reason = "OVERRIDE_RULE"
+	// ReasonAnonymousIP means the IP has an anonymity flag the policy denies.
+	ReasonAnonymousIP Reason = "ANONYMOUS_IP"
+)
+
 // CheckResult holds the geo-fencing decision and metadata for logging.
 type CheckResult struct {
-	Allowed bool   // true if the IP's country is in the allowed list
-	Country string // the ISO country code found (empty if unknown)
+	Allowed   bool           // true if the IP is admitted by the policy
+	Country   string         // the ISO country code found (empty if unknown or not looked up)
+	DecidedBy DecisionSource // which rule made the decision; empty for unknown IPs
+	IPStatus  IPStatus       // empty when a network override decided before any lookup
+	Reason    Reason         // why the decision was made
+	// MatchedRule is the list entry that decided: a country code, region or "*" for
+	// country reasons, the override prefix for OVERRIDE_RULE, the reserved range for
+	// PRIVATE_IP, or the denied flag for ANONYMOUS_IP. Empty when nothing matched.
+	MatchedRule string
+	// Provider is the GeoIP provider that answered the lookup, e.g. "maxmind".
+	// Empty when no lookup was made or the lookuper does not report one. With a
+	// ConsensusReporter it lists the sources that reported Country, comma-separated.
+	Provider string
+	// Disagreement holds every source's answer when a ConsensusReporter's sources
+	// reported different countries for the IP, and is nil otherwise.
+	Disagreement []SourceAnswer
+	// Anonymity holds the IP's anonymity flags. It is only looked up, for public
+	// IPs not decided by an override, when the Checker has WithAnonymity.
+	Anonymity Anonymity
 }
 
-// Checker validates IP addresses against an allowed list of countries.
+// Checker validates IP addresses against allowed and denied lists of countries.
 type Checker struct {
-	lookup CountryLookuper
+	lookup    CountryLookuper
+	consensus ConsensusReporter // lookup, when it combines several databases
+	lookupErr error             // ErrWrappedConsensus, reported by every check
+	anonymity AnonymityLookuper
+	policies  map[string]Policy
+	groups    Groups
+	lenient   bool
+	compiled  compiledCache // regions and overrides of inline policies
+}
+
+// Option configures optional Checker behavior.
+type Option func(*Checker)
+
+// WithPolicies registers named policies that requests can select by name.
+// Policies returned by LoadPolicies are already compiled; others are compiled here,
+// and any that fail to compile report the error when they are used.
+func WithPolicies(policies map[string]Policy) Option {
+	return func(c *Checker) {
+		c.policies = make(map[string]Policy, len(policies))
+		for name, policy := range policies {
+			if compiled, err := policy.compile(); err == nil {
+				policy = compiled
+			}
+			c.policies[name] = policy
+		}
+	}
+}
+
+// WithGroups registers the regions policies and requests can name in
+// AllowedRegions and DeniedRegions.
+func WithGroups(groups Groups) Option {
+	return func(c *Checker) {
+		c.groups = groups
+	}
+}
+
+// WithLenientCountries makes country lists also accept ISO 3166-1 alpha-3 codes
+// and common country names ("USA", "United States", "South Korea"), mapping them
+// to alpha-2 codes. By default anything but an alpha-2 code is rejected.
+func WithLenientCountries() Option {
+	return func(c *Checker) {
+		c.lenient = true
+	}
+}
+
+// WithAnonymity looks up every public IP's anonymity flags in lookup, reports them
+// on CheckResult and LookupResult, and lets policies deny them with
+// DeniedAnonymity.
+func WithAnonymity(lookup AnonymityLookuper) Option {
+	return func(c *Checker) {
+		c.anonymity = lookup
+	}
 }
 
 // NewChecker creates a Checker with the given country lookup dependency.
-func NewChecker(lookup CountryLookuper) *Checker {
-	return &Checker{lookup: lookup}
+func NewChecker(lookup CountryLookuper, opts ...Option) *Checker {
+	c := &Checker{lookup: lookup}
+	c.consensus, c.lookupErr = findConsensus(lookup)
+	for _, opt := range opts {
+		opt(c)
+	}
+	// Prepare named policies once; any that fail report the error when used.
+	for name, policy := range c.policies {
+		if prepared, err := c.Prepare(policy); err == nil {
+			c.policies[name] = prepared
+		}
+	}
+	return c
+}
+
+// Prepare validates policy as CheckPolicy would and does the work CheckPolicy
+// would otherwise repeat on every call: country lists are checked against ISO
+// 3166-1 (and rewritten to alpha-2 codes under WithLenientCountries) and compiled
+// into sets, anonymity flags are checked, network overrides are parsed and regions
+// expanded. Use it for policies that are built once and checked many times. The
+// result is tied to this Checker and its lists must not be modified; prepare a
+// copy instead.
+func (c *Checker) Prepare(policy Policy) (Policy, error) {
+	if policy.prepared {
+		return policy, nil
+	}
+	if policy.allowsNothing() {
+		return Policy{}, ErrEmptyAllowedCountries
+	}
+	if err := policy.UnknownIPAction.validate(); err != nil {
+		return Policy{}, err
+	}
+	if err := c.compiled.compileCountries(&policy, c.lenient); err != nil {
+		return Policy{}, err
+	}
+	if err := policy.compileAnonymity(); err != nil {
+		return Policy{}, err
+	}
+	if policy.deniedAnonymity != 0 && c.anonymity == nil {
+		return Policy{}, ErrAnonymityUnavailable
+	}
+	var err error
+	if policy.hasOverrides() && policy.overrides == nil {
+		if policy.overrides, err = c.compiled.prefixTrie(policy); err != nil {
+			return Policy{}, err
+		}
+	}
+	if len(policy.AllowedRegions) > 0 {
+		if policy.allowedRegions, err = c.compiled.regionIndex(c.groups, policy.AllowedRegions); err != nil {
+			return Policy{}, err
+		}
+	}
+	if len(policy.DeniedRegions) > 0 {
+		if policy.deniedRegions, err = c.compiled.regionIndex(c.groups, policy.DeniedRegions); err != nil {
+			return Policy{}, err
+		}
+	}
+	policy.prepared = true
+	return policy, nil
+}
+
+// Groups returns the regions registered with WithGroups.
+func (c *Checker) Groups() Groups {
+	return c.groups
+}
+
+// Validate reports the first named policy that cannot be used, e.g. because it
+// names a region that is not configured, or ErrWrappedConsensus if the lookuper
+// cannot be checked through at all.
+func (c *Checker) Validate() error {
+	if c.lookupErr != nil {
+		return c.lookupErr
+	}
+	for name, policy := range c.policies {
+		if _, err := c.Prepare(policy); err != nil {
+			return fmt.Errorf("policy %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Resolve returns the policy selected by ref. A named policy must exist and cannot be
+// combined with inline rules, though an inline UnknownIPAction overrides the policy's.
+// Otherwise the inline policy is returned as-is.
+func (c *Checker) Resolve(ref PolicyRef) (Policy, error) {
+	if err := ref.Inline.UnknownIPAction.validate(); err != nil {
+		return Policy{}, err
+	}
+	if ref.Name == "" {
+		return ref.Inline, nil
+	}
+	if !ref.Inline.isZero() {
+		return Policy{}, ErrPolicyConflict
+	}
+	policy, ok := c.policies[ref.Name]
+	if !ok {
+		return Policy{}, fmt.Errorf("%w: %s", ErrUnknownPolicy, ref.Name)
+	}
+	if ref.Inline.UnknownIPAction != "" {
+		policy.UnknownIPAction = ref.Inline.UnknownIPAction
+	}
+	return policy, nil
 }
 
 // Check determines whether the given IP address is in one of the allowed countries.
 // It parses IPv4 and IPv6 addresses, looks up the country, and compares case-insensitively.
-// Returns an error for malformed IP strings, empty allowed list, or when the IP is not found in the database.
-func (c *Checker) Check(ipStr string, allowedCountries []string) (CheckResult, error) {
-	if len(allowedCountries) == 0 {
-		return CheckResult{}, ErrEmptyAllowedCountries
+// Returns an error for malformed IP strings or an empty allowed list. IPs without a
+// country are denied.
+func (c *Checker) Check(ctx context.Context, ipStr string, allowedCountries []string) (CheckResult, error) {
+	return c.CheckPolicy(ctx, ipStr, Policy{AllowedCountries: allowedCountries})
+}
+
+// Evaluate resolves ref and checks the IP against the resulting policy.
+func (c *Checker) Evaluate(ctx context.Context, ipStr string, ref PolicyRef) (CheckResult, error) {
+	policy, err := c.Resolve(ref)
+	if err != nil {
+		return CheckResult{}, err
+	}
+	return c.CheckPolicy(ctx, ipStr, policy)
+}
+
+// CheckPolicy checks the IP against an already resolved policy. Network overrides
+// are evaluated first. Private and reserved addresses are then handled by the
+// policy's UnknownIPAction without a lookup, as are IPs missing from the database.
+// Everything else is decided by the allow/deny precedence described on Policy.
+// Returns ErrUnknownIP only when the action is UnknownIPError.
+func (c *Checker) CheckPolicy(ctx context.Context, ipStr string, policy Policy) (CheckResult, error) {
+	ctx, span := tracer.Start(ctx, "geofence.Check")
+	defer span.End()
+
+	result, err := c.checkPolicy(ctx, ipStr, policy)
+	span.SetAttributes(
+		attribute.Bool("geofence.allowed", result.Allowed),
+		attribute.String("geofence.country", result.Country),
+		attribute.String("geofence.decided_by", string(result.DecidedBy)),
+		attribute.String("geofence.ip_status", string(result.IPStatus)),
+		attribute.String("geofence.reason", string(result.Reason)),
+		attribute.String("geofence.matched_rule", result.MatchedRule),
+		attribute.String("geofence.provider", result.Provider),
+		attribute.StringSlice("geofence.anonymity", result.Anonymity.Names()),
+	)
+	recordError(span, err)
+	return result, err
+}
+
+func (c *Checker) checkPolicy(ctx context.Context, ipStr string, policy Policy) (CheckResult, error) {
+	policy, err := c.Prepare(policy)
+	if err != nil {
+		return CheckResult{}, err
 	}
 	ip := net.ParseIP(ipStr)
 	if ip == nil {
 		return CheckResult{}, fmt.Errorf("%w: %s", ErrInvalidIP, ipStr)
 	}
 
-	country, err := c.lookup.Lookup(ip)
+	addr, _ := netip.AddrFromSlice(ip)
+	addr = addr.Unmap()
+	reserved, private := reservedNetwork(addr)
+
+	if policy.hasOverrides() {
+		if rule, ok := policy.overrides.match(addr); ok {
+			result := CheckResult{
+				Allowed:     rule.allow,
+				DecidedBy:   DecidedByOverride,
+				Reason:      ReasonOverrideRule,
+				MatchedRule: rule.prefix.String(),
+			}
+			if private {
+				result.IPStatus = IPStatusPrivate
+			}
+			return result, nil
+		}
+	}
+
+	if private {
+		result := CheckResult{IPStatus: IPStatusPrivate, Reason: ReasonPrivateIP, MatchedRule: reserved.String()}
+		return unknownIP(ipStr, policy.UnknownIPAction, result)
+	}
+
+	result, err := c.checkCountry(ctx, ipStr, ip, policy)
+	if err != nil {
+		return result, err
+	}
+	return c.checkAnonymity(ctx, ip, policy, result)
+}
+
+// checkCountry looks up a public IP's country and decides policy from it.
+func (c *Checker) checkCountry(ctx context.Context, ipStr string, ip net.IP, policy Policy) (CheckResult, error) {
+	if c.lookupErr != nil {
+		return CheckResult{}, fmt.Errorf("lookup: %w", c.lookupErr)
+	}
+	if c.consensus != nil {
+		return c.checkConsensus(ctx, ipStr, ip, policy)
+	}
+
+	country, err := c.lookupCountry(ctx, ip)
 	if err != nil {
 		if errors.Is(err, ErrUnknownIP) {
-			return CheckResult{Allowed: false, Country: ""}, fmt.Errorf("lookup: %w", err)
+			result := CheckResult{IPStatus: IPStatusUnknown, Reason: ReasonUnknownIP, Provider: c.provider()}
+			return unknownIP(ipStr, policy.UnknownIPAction, result)
 		}
 		return CheckResult{}, fmt.Errorf("lookup: %w", err)
 	}
 
-	countryUpper := strings.ToUpper(country)
-	for _, allowed := range allowedCountries {
-		if strings.ToUpper(allowed) == countryUpper {
-			return CheckResult{Allowed: true, Country: country}, nil
+	allowed, reason, rule := policy.decide(country)
+	return CheckResult{
+		Allowed:     allowed,
+		Country:     country,
+		DecidedBy:   DecidedByCountry,
+		IPStatus:    IPStatusGeolocated,
+		Reason:      reason,
+		MatchedRule: rule,
+		Provider:    c.provider(),
+	}, nil
+}
+
+// checkConsensus decides policy from the combined answer of lookup's sources.
+// Under StrategyDenyAny an allowed IP is denied when the policy denies any other
+// country a source reports; the result then carries that country.
+func (c *Checker) checkConsensus(ctx context.Context, ipStr string, ip net.IP, policy Policy) (CheckResult, error) {
+	consensus, err := c.lookupConsensus(ctx, ip)
+	if err != nil {
+		if errors.Is(err, ErrUnknownIP) {
+			result := CheckResult{IPStatus: IPStatusUnknown, Reason: ReasonUnknownIP, Provider: consensus.provider("")}
+			return unknownIP(ipStr, policy.UnknownIPAction, result)
 		}
+		return CheckResult{}, fmt.Errorf("lookup: %w", err)
+	}
+
+	result := CheckResult{
+		Country:   consensus.Country,
+		DecidedBy: DecidedByCountry,
+		IPStatus:  IPStatusGeolocated,
+	}
+	result.Allowed, result.Reason, result.MatchedRule = policy.decide(consensus.Country)
+	if consensus.Strategy == StrategyDenyAny && result.Allowed {
+		for _, a := range consensus.Answers {
+			if a.Country == "" || a.Country == consensus.Country {
+				continue
+			}
+			if allowed, reason, rule := policy.decide(a.Country); !allowed {
+				result.Allowed, result.Reason, result.MatchedRule = false, reason, rule
+				result.Country = a.Country
+				break
+			}
+		}
+	}
+	result.Provider = consensus.provider(result.Country)
+	if consensus.Disagree() {
+		result.Disagreement = consensus.Answers
+	}
+	return result, nil
+}
+
+// checkAnonymity adds the IP's anonymity flags to result and denies an allowed IP
+// that has a flag the policy denies. The lowest denied flag is the matched rule.
+func (c *Checker) checkAnonymity(ctx context.Context, ip net.IP, policy Policy, result CheckResult) (CheckResult, error) {
+	if c.anonymity == nil {
+		return result, nil
+	}
+	flags, err := c.lookupAnonymity(ctx, ip)
+	if err != nil {
+		return CheckResult{}, fmt.Errorf("lookup anonymity: %w", err)
+	}
+	result.Anonymity = flags
+	if denied := flags & policy.deniedAnonymity; denied != 0 && result.Allowed {
+		result.Allowed = false
+		result.DecidedBy = DecidedByAnonymity
+		result.Reason = ReasonAnonymousIP
+		result.MatchedRule = denied.first()
+	}
+	return result, nil
+}
+
+// provider returns the name of the provider behind the lookuper, if it reports one.
+func (c *Checker) provider() string {
+	if p, ok := c.lookup.(ProviderReporter); ok {
+		return p.Provider()
+	}
+	return ""
+}
+
+// lookupCountry calls the CountryLookuper with ctx when it is a ContextLookuper,
+// which traces its own database lookups. Other lookupers are called inside a span
+// here, so database latency still shows up separately from the rest of the check.
+func (c *Checker) lookupCountry(ctx context.Context, ip net.IP) (string, error) {
+	if cl, ok := c.lookup.(ContextLookuper); ok {
+		return cl.LookupContext(ctx, ip)
+	}
+	_, span := tracer.Start(ctx, "geofence.Lookup")
+	defer span.End()
+
+	country, err := c.lookup.Lookup(ip)
+	span.SetAttributes(attribute.String("geofence.country", country))
+	if !errors.Is(err, ErrUnknownIP) {
+		recordError(span, err)
+	}
+	return country, err
+}
+
+// lookupConsensus is lookupCountry for a ConsensusReporter.
+func (c *Checker) lookupConsensus(ctx context.Context, ip net.IP) (Consensus, error) {
+	_, span := tracer.Start(ctx, "geofence.Lookup")
+	defer span.End()
+
+	consensus, err := c.consensus.LookupConsensus(ip)
+	span.SetAttributes(
+		attribute.String("geofence.country", consensus.Country),
+		attribute.String("geofence.strategy", string(consensus.Strategy)),
+		attribute.Bool("geofence.disagreement", consensus.Disagree()),
+	)
+	if !errors.Is(err, ErrUnknownIP) {
+		recordError(span, err)
+	}
+	return consensus, err
+}
+
+// lookupAnonymity calls the AnonymityLookuper inside its own span.
+func (c *Checker) lookupAnonymity(ctx context.Context, ip net.IP) (Anonymity, error) {
+	_, span := tracer.Start(ctx, "geofence.LookupAnonymity")
+	defer span.End()
+
+	flags, err := c.anonymity.LookupAnonymity(ip)
+	span.SetAttributes(attribute.StringSlice("geofence.anonymity", flags.Names()))
+	recordError(span, err)
+	return flags, err
+}
+
+// startLookupSpan starts a geofence.Lookup span for a database lookup under the
+// span in ctx. Without one it starts nothing, so lookups made outside a check, e.g.
+// by a ConsensusLookuper's sources, do not each begin a trace of their own.
+func startLookupSpan(ctx context.Context, provider string) trace.Span {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return noop.Span{}
+	}
+	_, span := tracer.Start(ctx, "geofence.Lookup", trace.WithAttributes(attribute.String("geofence.provider", provider)))
+	return span
+}
+
+// recordError marks span as failed when err is non-nil.
+func recordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// unknownIP applies action to result, an IP that has no country.
+func unknownIP(ipStr string, action UnknownIPAction, result CheckResult) (CheckResult, error) {
+	switch action {
+	case UnknownIPAllow:
+		result.Allowed = true
+		return result, nil
+	case UnknownIPError:
+		return result, fmt.Errorf("lookup %s (%s): %w", ipStr, result.IPStatus, ErrUnknownIP)
+	default:
+		result.Allowed = false
+		return result, nil
 	}
-	return CheckResult{Allowed: false, Country: country}, nil
 }
@@ -1,9 +1,16 @@
 package geofence
 
 import (
+	"context"
 	"errors"
 	"net"
+	"sync"
 	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 type mockLookuper struct {
@@ -54,14 +61,22 @@ func TestChecker_Check(t *testing.T) {
 			expectErrInvalidIP: true,
 		},
 		{
-			name:             "unknown IP",
-			ipStr:            "192.168.1.1",
+			name:             "unknown IP is denied",
+			ipStr:            "1.2.3.4",
 			allowedCountries: []string{"US"},
 			mockLookup:       func(net.IP) (string, error) { return "", ErrUnknownIP },
 			wantAllowed:      false,
 			wantCountry:      "",
-			wantErr:          true,
-			expectErrUnknown: true,
+			wantErr:          false,
+		},
+		{
+			name:             "private IP is denied without lookup",
+			ipStr:            "192.168.1.1",
+			allowedCountries: []string{"US"},
+			mockLookup:       func(net.IP) (string, error) { return "", errors.New("unexpected lookup") },
+			wantAllowed:      false,
+			wantCountry:      "",
+			wantErr:          false,
 		},
 		{
 			name:                   "empty allowed list",
@@ -91,7 +106,7 @@ func TestChecker_Check(t *testing.T) {
 				lookup = mockLookuper{lookup: func(net.IP) (string, error) { return "", nil }}
 			}
 			checker := NewChecker(lookup)
-			result, err := checker.Check(tt.ipStr, tt.allowedCountries)
+			result, err := checker.Check(context.Background(), tt.ipStr, tt.allowedCountries)
 			if tt.wantErr {
 				if err == nil {
 					t.Fatal("expected error, got nil")
@@ -119,3 +134,393 @@ func TestChecker_Check(t *testing.T) {
 		})
 	}
 }
+
+func TestChecker_CheckPolicy_DenyList(t *testing.T) {
+	lookup := mockLookuper{lookup: func(net.IP) (string, error) { return "US", nil }}
+	checker := NewChecker(lookup)
+
+	tests := []struct {
+		name        string
+		policy      Policy
+		wantAllowed bool
+		wantErr     error
+	}{
+		{
+			name:        "wildcard allow",
+			policy:      Policy{AllowedCountries: []string{"*"}},
+			wantAllowed: true,
+		},
+		{
+			name:        "wildcard allow with deny list hit",
+			policy:      Policy{AllowedCountries: []string{"*"}, DeniedCountries: []string{"IR", "us"}},
+			wantAllowed: false,
+		},
+		{
+			name:        "wildcard allow with deny list miss",
+			policy:      Policy{AllowedCountries: []string{"*"}, DeniedCountries: []string{"IR", "KP"}},
+			wantAllowed: true,
+		},
+		{
+			name:        "explicit deny beats explicit allow",
+			policy:      Policy{AllowedCountries: []string{"US"}, DeniedCountries: []string{"US"}},
+			wantAllowed: false,
+		},
+		{
+			name:        "explicit allow beats wildcard deny",
+			policy:      Policy{AllowedCountries: []string{"US"}, DeniedCountries: []string{"*"}},
+			wantAllowed: true,
+		},
+		{
+			name:        "wildcard deny beats wildcard allow",
+			policy:      Policy{AllowedCountries: []string{"*"}, DeniedCountries: []string{"*"}},
+			wantAllowed: false,
+		},
+		{
+			name:    "deny list without allow list",
+			policy:  Policy{DeniedCountries: []string{"IR"}},
+			wantErr: ErrEmptyAllowedCountries,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := checker.CheckPolicy(context.Background(), "8.8.8.8", tt.policy)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Allowed != tt.wantAllowed {
+				t.Errorf("Allowed = %v, want %v", result.Allowed, tt.wantAllowed)
+			}
+		})
+	}
+}
+
+func TestChecker_CheckPolicy_NetworkOverrides(t *testing.T) {
+	lookups := 0
+	lookup := mockLookuper{lookup: func(ip net.IP) (string, error) {
+		lookups++
+		if ip.String() == "10.0.0.1" {
+			return "", ErrUnknownIP
+		}
+		return "IR", nil
+	}}
+	checker := NewChecker(lookup)
+	policy := Policy{
+		AllowedCountries: []string{"US"},
+		AllowedNetworks:  []string{"203.0.113.0/24", "10.0.0.1", "::ffff:45.83.64.0/120"},
+		DeniedNetworks:   []string{"203.0.113.128/25"},
+	}
+
+	tests := []struct {
+		name          string
+		ip            string
+		wantAllowed   bool
+		wantDecidedBy DecisionSource
+		wantLookup    bool
+	}{
+		{name: "partner range in blocked country", ip: "203.0.113.10", wantAllowed: true, wantDecidedBy: DecidedByOverride},
+		{name: "more specific deny wins", ip: "203.0.113.200", wantAllowed: false, wantDecidedBy: DecidedByOverride},
+		{name: "host override for unmapped IP", ip: "10.0.0.1", wantAllowed: true, wantDecidedBy: DecidedByOverride},
+		{name: "IPv4-mapped prefix matches IPv4", ip: "45.83.64.7", wantAllowed: true, wantDecidedBy: DecidedByOverride},
+		{name: "IPv4-mapped prefix matches mapped IP", ip: "::ffff:45.83.64.7", wantAllowed: true, wantDecidedBy: DecidedByOverride},
+		{name: "no override falls through to country", ip: "8.8.8.8", wantAllowed: false, wantDecidedBy: DecidedByCountry, wantLookup: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookups = 0
+			result, err := checker.CheckPolicy(context.Background(), tt.ip, policy)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Allowed != tt.wantAllowed || result.DecidedBy != tt.wantDecidedBy {
+				t.Errorf("got {%v %q}, want {%v %q}", result.Allowed, result.DecidedBy, tt.wantAllowed, tt.wantDecidedBy)
+			}
+			if (lookups > 0) != tt.wantLookup {
+				t.Errorf("lookups = %d, want lookup=%v", lookups, tt.wantLookup)
+			}
+		})
+	}
+
+	_, err := checker.CheckPolicy(context.Background(), "8.8.8.8", Policy{AllowedCountries: []string{"US"}, DeniedNetworks: []string{"bogus"}})
+	if !errors.Is(err, ErrInvalidNetwork) {
+		t.Errorf("invalid network: err = %v, want ErrInvalidNetwork", err)
+	}
+}
+
+func TestChecker_CheckPolicy_UnknownIPAction(t *testing.T) {
+	lookup := mockLookuper{lookup: func(ip net.IP) (string, error) {
+		if ip.String() == "1.2.3.4" {
+			return "", ErrUnknownIP
+		}
+		return "", errors.New("unexpected lookup")
+	}}
+	checker := NewChecker(lookup)
+
+	tests := []struct {
+		name        string
+		ip          string
+		action      UnknownIPAction
+		wantAllowed bool
+		wantStatus  IPStatus
+		wantErr     error
+	}{
+		{name: "default denies unknown", ip: "1.2.3.4", wantStatus: IPStatusUnknown},
+		{name: "deny unknown", ip: "1.2.3.4", action: UnknownIPDeny, wantStatus: IPStatusUnknown},
+		{name: "allow unknown", ip: "1.2.3.4", action: UnknownIPAllow, wantAllowed: true, wantStatus: IPStatusUnknown},
+		{name: "error on unknown", ip: "1.2.3.4", action: UnknownIPError, wantStatus: IPStatusUnknown, wantErr: ErrUnknownIP},
+		{name: "allow private", ip: "10.1.2.3", action: UnknownIPAllow, wantAllowed: true, wantStatus: IPStatusPrivate},
+		{name: "deny loopback", ip: "::1", action: UnknownIPDeny, wantStatus: IPStatusPrivate},
+		{name: "error on reserved", ip: "240.0.0.1", action: UnknownIPError, wantStatus: IPStatusPrivate, wantErr: ErrUnknownIP},
+		{name: "invalid action", ip: "1.2.3.4", action: "maybe", wantErr: ErrInvalidUnknownIPAction},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref := PolicyRef{Inline: Policy{AllowedCountries: []string{"US"}, UnknownIPAction: tt.action}}
+			result, err := checker.Evaluate(context.Background(), tt.ip, ref)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Allowed != tt.wantAllowed {
+				t.Errorf("Allowed = %v, want %v", result.Allowed, tt.wantAllowed)
+			}
+			if result.IPStatus != tt.wantStatus {
+				t.Errorf("IPStatus = %q, want %q", result.IPStatus, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestChecker_CheckPolicy_Reason(t *testing.T) {
+	lookup := mockLookuper{lookup: func(ip net.IP) (string, error) {
+		if ip.String() == "1.2.3.4" {
+			return "", ErrUnknownIP
+		}
+		return "US", nil
+	}}
+	checker := NewChecker(lookup)
+
+	tests := []struct {
+		name        string
+		ip          string
+		policy      Policy
+		wantAllowed bool
+		wantReason  Reason
+		wantRule    string
+	}{
+		{
+			name:        "explicit allow",
+			ip:          "8.8.8.8",
+			policy:      Policy{AllowedCountries: []string{"us"}},
+			wantAllowed: true,
+			wantReason:  ReasonCountryAllowed,
+			wantRule:    "US",
+		},
+		{
+			name:        "wildcard allow",
+			ip:          "8.8.8.8",
+			policy:      Policy{AllowedCountries: []string{"*"}},
+			wantAllowed: true,
+			wantReason:  ReasonCountryAllowed,
+			wantRule:    "*",
+		},
+		{
+			name:       "not in list",
+			ip:         "8.8.8.8",
+			policy:     Policy{AllowedCountries: []string{"GB"}},
+			wantReason: ReasonCountryNotInList,
+		},
+		{
+			name:       "explicit deny",
+			ip:         "8.8.8.8",
+			policy:     Policy{AllowedCountries: []string{"*"}, DeniedCountries: []string{"US"}},
+			wantReason: ReasonCountryDenied,
+			wantRule:   "US",
+		},
+		{
+			name:       "wildcard deny",
+			ip:         "8.8.8.8",
+			policy:     Policy{AllowedCountries: []string{"GB"}, DeniedCountries: []string{"*"}},
+			wantReason: ReasonCountryDenied,
+			wantRule:   "*",
+		},
+		{
+			name:       "unknown IP",
+			ip:         "1.2.3.4",
+			policy:     Policy{AllowedCountries: []string{"US"}},
+			wantReason: ReasonUnknownIP,
+		},
+		{
+			name:       "private IP",
+			ip:         "172.20.1.1",
+			policy:     Policy{AllowedCountries: []string{"US"}},
+			wantReason: ReasonPrivateIP,
+			wantRule:   "172.16.0.0/12",
+		},
+		{
+			name:        "override",
+			ip:          "8.8.8.8",
+			policy:      Policy{AllowedCountries: []string{"GB"}, AllowedNetworks: []string{"8.8.0.0/16"}},
+			wantAllowed: true,
+			wantReason:  ReasonOverrideRule,
+			wantRule:    "8.8.0.0/16",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := checker.CheckPolicy(context.Background(), tt.ip, tt.policy)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Allowed != tt.wantAllowed {
+				t.Errorf("Allowed = %v, want %v", result.Allowed, tt.wantAllowed)
+			}
+			if result.Reason != tt.wantReason {
+				t.Errorf("Reason = %q, want %q", result.Reason, tt.wantReason)
+			}
+			if result.MatchedRule != tt.wantRule {
+				t.Errorf("MatchedRule = %q, want %q", result.MatchedRule, tt.wantRule)
+			}
+		})
+	}
+}
+
+// testTracerProvider is installed as the global TracerProvider once: the package
+// tracer binds to the first provider set, so tests must share it.
+var testTracerProvider = sync.OnceValue(func() *sdktrace.TracerProvider {
+	provider := sdktrace.NewTracerProvider()
+	otel.SetTracerProvider(provider)
+	return provider
+})
+
+// recordSpans returns a recorder of the spans ended during the test and the
+// provider they are recorded from.
+func recordSpans(t *testing.T) (*tracetest.SpanRecorder, *sdktrace.TracerProvider) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := testTracerProvider()
+	provider.RegisterSpanProcessor(recorder)
+	t.Cleanup(func() { provider.UnregisterSpanProcessor(recorder) })
+	return recorder, provider
+}
+
+func TestChecker_Tracing(t *testing.T) {
+	recorder, provider := recordSpans(t)
+
+	checker := NewChecker(mockLookuper{lookup: func(net.IP) (string, error) { return "US", nil }})
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	if _, err := checker.Check(ctx, "8.8.8.8", []string{"GB"}); err != nil {
+		t.Fatalf("Check() unexpected error: %v", err)
+	}
+	parent.End()
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	check, lookup := spans["geofence.Check"], spans["geofence.Lookup"]
+	if check == nil || lookup == nil {
+		t.Fatalf("ended spans = %v, want geofence.Check and geofence.Lookup", recorder.Ended())
+	}
+	if check.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("geofence.Check is not a child of the incoming span")
+	}
+	if lookup.Parent().SpanID() != check.SpanContext().SpanID() {
+		t.Error("geofence.Lookup is not a child of geofence.Check")
+	}
+
+	want := map[attribute.Key]attribute.Value{
+		"geofence.allowed": attribute.BoolValue(false),
+		"geofence.country": attribute.StringValue("US"),
+		"geofence.reason":  attribute.StringValue(string(ReasonCountryNotInList)),
+	}
+	got := make(map[attribute.Key]attribute.Value)
+	for _, kv := range check.Attributes() {
+		got[kv.Key] = kv.Value
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("attribute %s = %v, want %v", key, got[key].Emit(), value.Emit())
+		}
+	}
+}
+
+// eea is a realistic long allow list; NO sorts last so a linear scan reads it all.
+var eea = []string{
+	"AT", "BE", "BG", "CY", "CZ", "DE", "DK", "EE", "ES", "FI", "FR", "GR", "HR", "HU", "IE",
+	"IS", "IT", "LI", "LT", "LU", "LV", "MT", "NL", "PL", "PT", "RO", "SE", "SI", "SK", "NO",
+}
+
+// policyBenchmarks are the policies the checker benchmarks evaluate for a lookup
+// that returns NO.
+var policyBenchmarks = []struct {
+	name string
+	ref  PolicyRef
+}{
+	{"inline short", PolicyRef{Inline: Policy{AllowedCountries: []string{"US", "CA", "NO"}}}},
+	{"inline long", PolicyRef{Inline: Policy{AllowedCountries: eea}}},
+	{"inline lower case", PolicyRef{Inline: Policy{AllowedCountries: []string{"us", "ca", "no"}}}},
+	{"inline deny list", PolicyRef{Inline: Policy{AllowedCountries: []string{AllCountries}, DeniedCountries: []string{"CU", "IR", "KP", "SY"}}}},
+	{"inline region", PolicyRef{Inline: Policy{AllowedRegions: []string{"EEA"}}}},
+	{"inline networks", PolicyRef{Inline: Policy{AllowedCountries: eea, DeniedNetworks: []string{"203.0.113.0/24"}}}},
+	{"named", PolicyRef{Name: "eea"}},
+}
+
+func newBenchmarkChecker() *Checker {
+	lookup := mockLookuper{lookup: func(net.IP) (string, error) { return "NO", nil }}
+	return NewChecker(lookup,
+		WithGroups(Groups{"EEA": eea}),
+		WithPolicies(map[string]Policy{"eea": {AllowedCountries: eea}}),
+	)
+}
+
+// BenchmarkChecker_Evaluate measures a whole check, including tracing and parsing.
+func BenchmarkChecker_Evaluate(b *testing.B) {
+	checker := newBenchmarkChecker()
+	ctx := context.Background()
+	for _, bm := range policyBenchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := checker.Evaluate(ctx, "8.8.8.8", bm.ref); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkChecker_Decide measures only the policy work of a check: resolving,
+// preparing and deciding.
+func BenchmarkChecker_Decide(b *testing.B) {
+	checker := newBenchmarkChecker()
+	for _, bm := range policyBenchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				policy, err := checker.Resolve(bm.ref)
+				if err == nil {
+					policy, err = checker.Prepare(policy)
+				}
+				if err != nil {
+					b.Fatal(err)
+				}
+				if allowed, _, _ := policy.decide("NO"); !allowed {
+					b.Fatal("NO denied")
+				}
+			}
+		})
+	}
+}
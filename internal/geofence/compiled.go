@@ -0,0 +1,181 @@
+package geofence
+
+import (
+	"encoding/binary"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// maxCompiledEntries bounds each map in a compiledCache. Inline lists come from
+// requests, so the cache is cleared rather than allowed to grow without limit.
+const maxCompiledEntries = 1024
+
+// compiledCache keeps the country sets, region indexes and override tries built
+// for inline policies, keyed by the canonical form of the lists they were built
+// from, so a list repeated across requests is compiled once. Cached values are
+// never modified and are shared between policies. It is safe for concurrent use.
+type compiledCache struct {
+	mu        sync.RWMutex
+	countries map[string]*countryLists
+	regions   map[string]*regionIndex
+	overrides map[string]*prefixTrie
+}
+
+// countryLists are a policy's compiled country lists.
+type countryLists struct {
+	allowed, denied         countrySet
+	allowedList, deniedList []string
+}
+
+// compileCountries compiles policy's country lists as Policy.compileCountries
+// does, reusing the sets built for the same lists before. Errors are not cached.
+func (c *compiledCache) compileCountries(policy *Policy, lenient bool) error {
+	var buf [256]byte
+	key := buf[:0]
+	if lenient {
+		key = append(key, 1)
+	} else {
+		key = append(key, 0)
+	}
+	key = appendSetKey(key, policy.AllowedCountries)
+	key = appendSetKey(key, policy.DeniedCountries)
+
+	c.mu.RLock()
+	lists, ok := c.countries[string(key)]
+	c.mu.RUnlock()
+	if !ok {
+		if err := policy.compileCountries(lenient); err != nil {
+			return err
+		}
+		lists = &countryLists{
+			allowed:     policy.allowed,
+			denied:      policy.denied,
+			allowedList: policy.AllowedCountries,
+			deniedList:  policy.DeniedCountries,
+		}
+		c.mu.Lock()
+		if c.countries == nil || len(c.countries) >= maxCompiledEntries {
+			c.countries = make(map[string]*countryLists)
+		}
+		c.countries[string(key)] = lists
+		c.mu.Unlock()
+		return nil
+	}
+	policy.allowed, policy.denied = lists.allowed, lists.denied
+	policy.AllowedCountries, policy.DeniedCountries = lists.allowedList, lists.deniedList
+	return nil
+}
+
+// regionIndex returns the index for regions, building it on a miss. Errors are not
+// cached.
+func (c *compiledCache) regionIndex(groups Groups, regions []string) (*regionIndex, error) {
+	var buf [128]byte
+	key := appendListKey(buf[:0], regions, true)
+
+	c.mu.RLock()
+	index, ok := c.regions[string(key)]
+	c.mu.RUnlock()
+	if ok {
+		return index, nil
+	}
+
+	index, err := indexRegions(groups, regions)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if c.regions == nil || len(c.regions) >= maxCompiledEntries {
+		c.regions = make(map[string]*regionIndex)
+	}
+	c.regions[string(key)] = index
+	c.mu.Unlock()
+	return index, nil
+}
+
+// prefixTrie returns the override trie for policy's networks, building it on a
+// miss. Errors are not cached.
+func (c *compiledCache) prefixTrie(policy Policy) (*prefixTrie, error) {
+	var buf [256]byte
+	key := appendListKey(buf[:0], policy.AllowedNetworks, false)
+	key = appendListKey(key, policy.DeniedNetworks, false)
+
+	c.mu.RLock()
+	trie, ok := c.overrides[string(key)]
+	c.mu.RUnlock()
+	if ok {
+		return trie, nil
+	}
+
+	compiled, err := policy.compile()
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if c.overrides == nil || len(c.overrides) >= maxCompiledEntries {
+		c.overrides = make(map[string]*prefixTrie)
+	}
+	c.overrides[string(key)] = compiled.overrides
+	c.mu.Unlock()
+	return compiled.overrides, nil
+}
+
+// appendListKey appends list to key: its length, then each entry prefixed by its
+// length, so no two lists share a key. Region names are case-insensitive and
+// upper-cased when upper is set.
+func appendListKey(key []byte, list []string, upper bool) []byte {
+	key = binary.AppendUvarint(key, uint64(len(list)))
+	for _, entry := range list {
+		key = binary.AppendUvarint(key, uint64(len(entry)))
+		if !upper {
+			key = append(key, entry...)
+			continue
+		}
+		for i := range len(entry) {
+			b := entry[i]
+			if 'a' <= b && b <= 'z' {
+				b -= 'a' - 'A'
+			}
+			key = append(key, b)
+		}
+	}
+	return key
+}
+
+// appendSetKey appends the canonical form of a country list to key, so lists that
+// differ only in case, order or repetition share a key: the two-letter entries
+// upper-cased, sorted and deduplicated, then the other entries likewise. Two-letter
+// entries, the usual case, are sorted on the stack without allocating.
+func appendSetKey(key []byte, list []string) []byte {
+	var buf [64]uint16
+	codes := buf[:0]
+	var other []string
+	for _, entry := range list {
+		if len(entry) == 2 && isASCIILetter(entry[0]) && isASCIILetter(entry[1]) {
+			codes = append(codes, uint16(upperASCII(entry[0]))<<8|uint16(upperASCII(entry[1])))
+			continue
+		}
+		other = append(other, strings.ToUpper(entry))
+	}
+	slices.Sort(codes)
+	codes = slices.Compact(codes)
+	slices.Sort(other)
+	other = slices.Compact(other)
+
+	key = binary.AppendUvarint(key, uint64(len(codes)))
+	for _, code := range codes {
+		key = append(key, byte(code>>8), byte(code))
+	}
+	return appendListKey(key, other, false)
+}
+
+func isASCIILetter(b byte) bool {
+	return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+func upperASCII(b byte) byte {
+	if 'a' <= b && b <= 'z' {
+		return b - ('a' - 'A')
+	}
+	return b
+}
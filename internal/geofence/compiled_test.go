@@ -0,0 +1,143 @@
+package geofence
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestCompiledCache_Regions(t *testing.T) {
+	groups := Groups{"NA": {"CA", "MX", "US"}, "EU27": {"DE", "FR"}}
+	var cache compiledCache
+
+	first, err := cache.regionIndex(groups, []string{"NA", "EU27"})
+	if err != nil {
+		t.Fatalf("regionIndex() unexpected error: %v", err)
+	}
+	again, _ := cache.regionIndex(groups, []string{"na", "eu27"})
+	if again != first {
+		t.Error("same regions in another case were compiled again")
+	}
+	other, _ := cache.regionIndex(groups, []string{"EU27", "NA"})
+	if other == first {
+		t.Error("regions in another order share an index")
+	}
+
+	i, _ := countryIndex("US")
+	if region, ok := first.find(i); !ok || region != "NA" {
+		t.Errorf("find(US) = %q, %v, want NA", region, ok)
+	}
+	if _, err := cache.regionIndex(groups, []string{"MARS"}); !errors.Is(err, ErrUnknownRegion) {
+		t.Errorf("regionIndex(MARS) error = %v, want ErrUnknownRegion", err)
+	}
+	if len(cache.regions) != 2 {
+		t.Errorf("cached indexes = %d, want 2", len(cache.regions))
+	}
+}
+
+func TestCompiledCache_Overrides(t *testing.T) {
+	var cache compiledCache
+	policy := Policy{AllowedNetworks: []string{"203.0.113.0/24"}, DeniedNetworks: []string{"198.51.100.7"}}
+
+	first, err := cache.prefixTrie(policy)
+	if err != nil {
+		t.Fatalf("prefixTrie() unexpected error: %v", err)
+	}
+	if again, _ := cache.prefixTrie(policy); again != first {
+		t.Error("same networks were compiled again")
+	}
+	swapped := Policy{AllowedNetworks: policy.DeniedNetworks, DeniedNetworks: policy.AllowedNetworks}
+	if other, _ := cache.prefixTrie(swapped); other == first {
+		t.Error("swapped allow and deny lists share a trie")
+	}
+	joined := Policy{AllowedNetworks: []string{"203.0.113.0/24198.51.100.7"}}
+	if _, err := cache.prefixTrie(joined); !errors.Is(err, ErrInvalidNetwork) {
+		t.Errorf("prefixTrie(joined) error = %v, want ErrInvalidNetwork", err)
+	}
+}
+
+func TestCompiledCache_Countries(t *testing.T) {
+	var cache compiledCache
+	policy := Policy{AllowedCountries: []string{"USA", "ca"}, DeniedCountries: []string{"KP"}}
+
+	if err := cache.compileCountries(&policy, true); err != nil {
+		t.Fatalf("compileCountries() unexpected error: %v", err)
+	}
+	again := Policy{AllowedCountries: []string{"USA", "ca"}, DeniedCountries: []string{"KP"}}
+	if err := cache.compileCountries(&again, true); err != nil {
+		t.Fatalf("compileCountries() unexpected error: %v", err)
+	}
+	if &again.AllowedCountries[0] != &policy.AllowedCountries[0] || again.allowed != policy.allowed || again.denied != policy.denied {
+		t.Error("same country lists were compiled again")
+	}
+	if again.AllowedCountries[0] != "US" {
+		t.Errorf("AllowedCountries = %v, want the lenient rewrite to US", again.AllowedCountries)
+	}
+	strict := Policy{AllowedCountries: []string{"USA", "ca"}, DeniedCountries: []string{"KP"}}
+	if err := cache.compileCountries(&strict, false); !errors.As(err, new(*CountryCodeError)) {
+		t.Errorf("compileCountries() without lenient error = %v, want CountryCodeError", err)
+	}
+	swapped := Policy{AllowedCountries: []string{"KP"}, DeniedCountries: []string{"USA", "ca"}}
+	if err := cache.compileCountries(&swapped, true); err != nil || swapped.allowed == policy.allowed {
+		t.Errorf("swapped allow and deny lists share a set (err %v)", err)
+	}
+	if len(cache.countries) != 2 {
+		t.Errorf("cached lists = %d, want 2", len(cache.countries))
+	}
+
+	for _, allowed := range [][]string{{"ca", "USA"}, {"CA", "usa", "Ca"}, {"usa", "CA"}} {
+		variant := Policy{AllowedCountries: allowed, DeniedCountries: []string{"kp"}}
+		if err := cache.compileCountries(&variant, true); err != nil {
+			t.Fatalf("compileCountries(%v) unexpected error: %v", allowed, err)
+		}
+		if variant.allowed != policy.allowed || variant.denied != policy.denied {
+			t.Errorf("compileCountries(%v) compiled different sets", allowed)
+		}
+	}
+	if len(cache.countries) != 2 {
+		t.Errorf("cached lists = %d after case, order and duplicate variants, want 2", len(cache.countries))
+	}
+}
+
+func TestCompiledCache_Bounded(t *testing.T) {
+	groups := Groups{"NA": {"US"}}
+	var cache compiledCache
+	for n := range maxCompiledEntries + 10 {
+		regions := make([]string, 0, n%5+1)
+		for range n%5 + 1 {
+			regions = append(regions, "NA")
+		}
+		policy := Policy{DeniedNetworks: []string{fmt.Sprintf("10.%d.%d.0/24", n/256, n%256)}}
+		if _, err := cache.regionIndex(groups, regions); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cache.prefixTrie(policy); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(cache.overrides) > maxCompiledEntries {
+		t.Errorf("cached tries = %d, want at most %d", len(cache.overrides), maxCompiledEntries)
+	}
+	if len(cache.regions) != 5 {
+		t.Errorf("cached indexes = %d, want 5", len(cache.regions))
+	}
+}
+
+func TestChecker_PrepareReusesCompiledLists(t *testing.T) {
+	lookup := mockLookuper{lookup: func(net.IP) (string, error) { return "US", nil }}
+	checker := NewChecker(lookup, WithGroups(Groups{"NA": {"CA", "US"}}))
+	inline := Policy{AllowedRegions: []string{"NA"}, DeniedNetworks: []string{"192.0.2.0/24"}}
+
+	first, err := checker.Prepare(inline)
+	if err != nil {
+		t.Fatalf("Prepare() unexpected error: %v", err)
+	}
+	second, _ := checker.Prepare(inline)
+	if first.allowedRegions != second.allowedRegions || first.overrides != second.overrides {
+		t.Error("repeated inline policy was compiled again")
+	}
+	if again, _ := checker.Prepare(first); again.allowedRegions != first.allowedRegions {
+		t.Error("prepared policy was prepared again")
+	}
+}
@@ -0,0 +1,263 @@
+package geofence
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+)
+
+// ErrUnknownStrategy is returned for a consensus strategy that does not exist.
+var ErrUnknownStrategy = errors.New("unknown consensus strategy")
+
+// ErrWrappedConsensus is returned by a Checker whose lookuper wraps a
+// ConsensusReporter without implementing ConsensusReporter itself. Checking
+// through such a wrapper would silently drop StrategyDenyAny and disagreement
+// reporting, so the Checker refuses to check at all.
+var ErrWrappedConsensus = errors.New("lookuper hides a consensus lookuper; wrappers must implement ConsensusReporter")
+
+// ErrNoSources is returned when a ConsensusLookuper is given no sources, or two
+// sources share a name.
+var ErrNoSources = errors.New("consensus needs at least one uniquely named source")
+
+// Strategy selects how a ConsensusLookuper combines its sources' answers.
+type Strategy string
+
+const (
+	// StrategyFirst uses the first source, in order, that knows the IP, so later
+	// sources fill in IPs the earlier ones are missing. It is the default.
+	StrategyFirst Strategy = "first"
+	// StrategyMajority uses the country most sources agree on. Ties go to the
+	// country of the earliest source in the tie.
+	StrategyMajority Strategy = "majority"
+	// StrategyDenyAny is StrategyFirst, except that a check is denied when the
+	// policy denies the country any source reports. Lookups without a policy
+	// answer as StrategyFirst.
+	StrategyDenyAny Strategy = "deny_any"
+)
+
+// Strategies lists the valid strategies.
+var Strategies = []Strategy{StrategyFirst, StrategyMajority, StrategyDenyAny}
+
+// ParseStrategy validates s. An empty string is StrategyFirst.
+func ParseStrategy(s string) (Strategy, error) {
+	if s == "" {
+		return StrategyFirst, nil
+	}
+	if !slices.Contains(Strategies, Strategy(s)) {
+		return "", fmt.Errorf("%w: %q (want one of %v)", ErrUnknownStrategy, s, Strategies)
+	}
+	return Strategy(s), nil
+}
+
+// Source is one database consulted by a ConsensusLookuper.
+type Source struct {
+	Name   string // reported as the provider, e.g. "maxmind"
+	Lookup CountryLookuper
+}
+
+// SourceAnswer is one source's answer for an IP. Country is empty when the source
+// does not know the IP.
+type SourceAnswer struct {
+	Source  string
+	Country string
+	// Agrees reports whether Country is the country the strategy chose. A check
+	// denied under StrategyDenyAny reports another source's country, so this is
+	// what says which sources disagreed with the strategy.
+	Agrees bool
+}
+
+// Consensus is the combined answer of a ConsensusLookuper's sources for one IP.
+type Consensus struct {
+	Strategy Strategy
+	// Country is the country the strategy chose, empty when no source knows the IP.
+	Country string
+	// Answers holds every source's answer, in source order.
+	Answers []SourceAnswer
+}
+
+// Disagree reports whether two sources gave different countries. Sources that do
+// not know the IP do not count as disagreeing.
+func (c Consensus) Disagree() bool {
+	for _, a := range c.Answers {
+		if a.Country != "" && a.Country != c.Country {
+			return true
+		}
+	}
+	return false
+}
+
+// provider names the sources that reported country, comma-separated, or every
+// source when country is empty.
+func (c Consensus) provider(country string) string {
+	var names []string
+	for _, a := range c.Answers {
+		if country == "" || a.Country == country {
+			names = append(names, a.Source)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// ConsensusReporter is implemented by lookupers that combine several databases,
+// such as ConsensusLookuper. Checker uses it to apply StrategyDenyAny and report
+// disagreements, so a decorator around a ConsensusLookuper (for tracing, metrics
+// and the like) must implement it by forwarding to the lookuper it wraps.
+type ConsensusReporter interface {
+	CountryLookuper
+	// LookupConsensus asks every source about ip, as ConsensusLookuper.LookupConsensus.
+	LookupConsensus(ip net.IP) (Consensus, error)
+	// LookupConsensusInfo returns the full record for the country consensus chose.
+	LookupConsensusInfo(ip net.IP, locale string, consensus Consensus) (GeoInfo, error)
+}
+
+// Unwrapper is implemented by lookupers that decorate another lookuper, such as
+// CachedLookuper. Checker follows Unwrap to detect a ConsensusReporter hidden
+// behind a decorator that does not forward it.
+type Unwrapper interface {
+	Unwrap() CountryLookuper
+}
+
+// findConsensus returns lookup as a ConsensusReporter, or nil if it does not
+// combine databases. A ConsensusReporter reached only through Unwrap is
+// ErrWrappedConsensus.
+func findConsensus(lookup CountryLookuper) (ConsensusReporter, error) {
+	if consensus, ok := lookup.(ConsensusReporter); ok {
+		return consensus, nil
+	}
+	for {
+		wrapper, ok := lookup.(Unwrapper)
+		if !ok {
+			return nil, nil
+		}
+		lookup = wrapper.Unwrap()
+		if _, ok := lookup.(ConsensusReporter); ok {
+			return nil, ErrWrappedConsensus
+		}
+	}
+}
+
+// ConsensusLookuper is a CountryLookuper that asks several databases about every
+// IP and combines their answers according to a Strategy. All sources are asked
+// even when the first one knows the IP, so disagreements are always seen; they are
+// logged at debug level, and Checker reports them on CheckResult and LookupResult.
+// Put any CachedLookuper in front of the individual sources rather than this
+// lookuper: it does not cache consensus, so a Checker given a cache in front of a
+// ConsensusLookuper fails with ErrWrappedConsensus.
+type ConsensusLookuper struct {
+	strategy Strategy
+	sources  []Source
+}
+
+// NewConsensusLookuper returns a lookuper that combines sources, in order of
+// preference, with strategy. Source names must be unique.
+func NewConsensusLookuper(strategy Strategy, sources ...Source) (*ConsensusLookuper, error) {
+	strategy, err := ParseStrategy(string(strategy))
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		return nil, ErrNoSources
+	}
+	for i, s := range sources {
+		if slices.ContainsFunc(sources[:i], func(o Source) bool { return o.Name == s.Name }) {
+			return nil, fmt.Errorf("%w: %q appears twice", ErrNoSources, s.Name)
+		}
+	}
+	return &ConsensusLookuper{strategy: strategy, sources: sources}, nil
+}
+
+// Strategy returns the strategy the lookuper was created with.
+func (c *ConsensusLookuper) Strategy() Strategy {
+	return c.strategy
+}
+
+// Lookup returns the country the strategy chose. Returns ErrUnknownIP if no source
+// knows the IP.
+func (c *ConsensusLookuper) Lookup(ip net.IP) (string, error) {
+	consensus, err := c.LookupConsensus(ip)
+	return consensus.Country, err
+}
+
+// LookupConsensus asks every source about ip and combines the answers. Returns
+// ErrUnknownIP, along with the answers, if no source knows the IP. Any other
+// error from a source fails the lookup.
+func (c *ConsensusLookuper) LookupConsensus(ip net.IP) (Consensus, error) {
+	consensus := Consensus{Strategy: c.strategy, Answers: make([]SourceAnswer, len(c.sources))}
+	for i, s := range c.sources {
+		country, err := s.Lookup.Lookup(ip)
+		if err != nil {
+			if !errors.Is(err, ErrUnknownIP) {
+				return Consensus{}, fmt.Errorf("%s: %w", s.Name, err)
+			}
+			country = ""
+		}
+		consensus.Answers[i] = SourceAnswer{Source: s.Name, Country: country}
+	}
+
+	consensus.Country = c.choose(consensus.Answers)
+	if consensus.Country == "" {
+		return consensus, ErrUnknownIP
+	}
+	for i := range consensus.Answers {
+		consensus.Answers[i].Agrees = consensus.Answers[i].Country == consensus.Country
+	}
+	if consensus.Disagree() {
+		slog.Debug("geoip sources disagree", "ip", ip.String(), "strategy", c.strategy, "country", consensus.Country, "answers", consensus.Answers)
+	}
+	return consensus, nil
+}
+
+// choose picks the country from answers according to the strategy.
+func (c *ConsensusLookuper) choose(answers []SourceAnswer) string {
+	if c.strategy != StrategyMajority {
+		for _, a := range answers {
+			if a.Country != "" {
+				return a.Country
+			}
+		}
+		return ""
+	}
+	var best string
+	var bestVotes int
+	for i, a := range answers {
+		if a.Country == "" || a.Country == best {
+			continue
+		}
+		votes := 0
+		for _, b := range answers[i:] {
+			if b.Country == a.Country {
+				votes++
+			}
+		}
+		if votes > bestVotes {
+			best, bestVotes = a.Country, votes
+		}
+	}
+	return best
+}
+
+// LookupInfo returns the full record from the first source that reported the
+// chosen country and can return records, or just the country if none can.
+func (c *ConsensusLookuper) LookupInfo(ip net.IP, locale string) (GeoInfo, error) {
+	consensus, err := c.LookupConsensus(ip)
+	if err != nil {
+		return GeoInfo{}, err
+	}
+	return c.LookupConsensusInfo(ip, locale, consensus)
+}
+
+// LookupConsensusInfo returns the full record from the first source that reported
+// consensus.Country and can return records, or just the country if none can.
+func (c *ConsensusLookuper) LookupConsensusInfo(ip net.IP, locale string, consensus Consensus) (GeoInfo, error) {
+	for i, s := range c.sources {
+		info, ok := s.Lookup.(InfoLookuper)
+		if !ok || consensus.Answers[i].Country != consensus.Country {
+			continue
+		}
+		return info.LookupInfo(ip, locale)
+	}
+	return GeoInfo{Country: Country{ISOCode: consensus.Country}}, nil
+}
@@ -0,0 +1,257 @@
+package geofence
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// staticSource returns a source that answers country for every IP, or
+// ErrUnknownIP when country is empty.
+func staticSource(name, country string) Source {
+	return Source{Name: name, Lookup: mockLookuper{lookup: func(net.IP) (string, error) {
+		if country == "" {
+			return "", ErrUnknownIP
+		}
+		return country, nil
+	}}}
+}
+
+func TestNewConsensusLookuper(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy Strategy
+		sources  []Source
+		wantErr  error
+	}{
+		{name: "default strategy", sources: []Source{staticSource("a", "US")}},
+		{name: "unknown strategy", strategy: "any", sources: []Source{staticSource("a", "US")}, wantErr: ErrUnknownStrategy},
+		{name: "no sources", strategy: StrategyMajority, wantErr: ErrNoSources},
+		{name: "duplicate names", strategy: StrategyFirst, sources: []Source{staticSource("a", "US"), staticSource("a", "CA")}, wantErr: ErrNoSources},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookup, err := NewConsensusLookuper(tt.strategy, tt.sources...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewConsensusLookuper() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && lookup.Strategy() != StrategyFirst {
+				t.Errorf("Strategy() = %q, want %q", lookup.Strategy(), StrategyFirst)
+			}
+		})
+	}
+}
+
+func TestConsensusLookuper_LookupConsensus(t *testing.T) {
+	tests := []struct {
+		name         string
+		strategy     Strategy
+		countries    []string // one source per entry, named a, b, c...
+		wantCountry  string
+		wantDisagree bool
+		wantErr      error
+	}{
+		{name: "first: agreement", strategy: StrategyFirst, countries: []string{"US", "US"}, wantCountry: "US"},
+		{name: "first: fallback", strategy: StrategyFirst, countries: []string{"", "CA"}, wantCountry: "CA"},
+		{name: "first: disagreement", strategy: StrategyFirst, countries: []string{"US", "CA", "CA"}, wantCountry: "US", wantDisagree: true},
+		{name: "first: unknown everywhere", strategy: StrategyFirst, countries: []string{"", ""}, wantErr: ErrUnknownIP},
+		{name: "majority: outvoted first source", strategy: StrategyMajority, countries: []string{"US", "CA", "CA"}, wantCountry: "CA", wantDisagree: true},
+		{name: "majority: tie goes to the earlier source", strategy: StrategyMajority, countries: []string{"", "CA", "US"}, wantCountry: "CA", wantDisagree: true},
+		{name: "majority: single answer", strategy: StrategyMajority, countries: []string{"", "", "GB"}, wantCountry: "GB"},
+		{name: "deny_any: answers as first", strategy: StrategyDenyAny, countries: []string{"US", "CA"}, wantCountry: "US", wantDisagree: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sources []Source
+			for i, country := range tt.countries {
+				sources = append(sources, staticSource(string(rune('a'+i)), country))
+			}
+			lookup, err := NewConsensusLookuper(tt.strategy, sources...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			consensus, err := lookup.LookupConsensus(net.ParseIP("8.8.8.8"))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("LookupConsensus() error = %v, want %v", err, tt.wantErr)
+			}
+			if consensus.Country != tt.wantCountry || consensus.Disagree() != tt.wantDisagree {
+				t.Errorf("LookupConsensus() = %q, disagree %v, want %q, disagree %v", consensus.Country, consensus.Disagree(), tt.wantCountry, tt.wantDisagree)
+			}
+			if len(consensus.Answers) != len(tt.countries) {
+				t.Errorf("LookupConsensus() returned %d answers, want %d", len(consensus.Answers), len(tt.countries))
+			}
+		})
+	}
+}
+
+func TestConsensusLookuper_SourceError(t *testing.T) {
+	broken := Source{Name: "broken", Lookup: mockLookuper{lookup: func(net.IP) (string, error) { return "", ErrStoreClosed }}}
+	lookup, err := NewConsensusLookuper(StrategyFirst, staticSource("a", "US"), broken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lookup.Lookup(net.ParseIP("8.8.8.8")); !errors.Is(err, ErrStoreClosed) {
+		t.Errorf("Lookup() error = %v, want ErrStoreClosed", err)
+	}
+}
+
+func TestChecker_Consensus(t *testing.T) {
+	policy := Policy{AllowedCountries: []string{"US", "CA"}, DeniedCountries: []string{"IR"}}
+
+	tests := []struct {
+		name      string
+		strategy  Strategy
+		countries []string
+		want      CheckResult
+	}{
+		{
+			name:      "first: agreement",
+			strategy:  StrategyFirst,
+			countries: []string{"US", "US"},
+			want:      CheckResult{Allowed: true, Country: "US", DecidedBy: DecidedByCountry, IPStatus: IPStatusGeolocated, Reason: ReasonCountryAllowed, MatchedRule: "US", Provider: "a,b"},
+		},
+		{
+			name:      "first: fallback to the second source",
+			strategy:  StrategyFirst,
+			countries: []string{"", "CA"},
+			want:      CheckResult{Allowed: true, Country: "CA", DecidedBy: DecidedByCountry, IPStatus: IPStatusGeolocated, Reason: ReasonCountryAllowed, MatchedRule: "CA", Provider: "b"},
+		},
+		{
+			name:      "first: disagreement is reported",
+			strategy:  StrategyFirst,
+			countries: []string{"US", "IR"},
+			want: CheckResult{
+				Allowed: true, Country: "US", DecidedBy: DecidedByCountry, IPStatus: IPStatusGeolocated, Reason: ReasonCountryAllowed, MatchedRule: "US", Provider: "a",
+				Disagreement: []SourceAnswer{{Source: "a", Country: "US", Agrees: true}, {Source: "b", Country: "IR"}},
+			},
+		},
+		{
+			name:      "majority",
+			strategy:  StrategyMajority,
+			countries: []string{"IR", "US", "US"},
+			want: CheckResult{
+				Allowed: true, Country: "US", DecidedBy: DecidedByCountry, IPStatus: IPStatusGeolocated, Reason: ReasonCountryAllowed, MatchedRule: "US", Provider: "b,c",
+				Disagreement: []SourceAnswer{{Source: "a", Country: "IR"}, {Source: "b", Country: "US", Agrees: true}, {Source: "c", Country: "US", Agrees: true}},
+			},
+		},
+		{
+			name:      "deny_any: denied by the second source",
+			strategy:  StrategyDenyAny,
+			countries: []string{"US", "IR"},
+			want: CheckResult{
+				Allowed: false, Country: "IR", DecidedBy: DecidedByCountry, IPStatus: IPStatusGeolocated, Reason: ReasonCountryDenied, MatchedRule: "IR", Provider: "b",
+				Disagreement: []SourceAnswer{{Source: "a", Country: "US", Agrees: true}, {Source: "b", Country: "IR"}},
+			},
+		},
+		{
+			name:      "deny_any: not in the allow list",
+			strategy:  StrategyDenyAny,
+			countries: []string{"CA", "US", "GB"},
+			want: CheckResult{
+				Allowed: false, Country: "GB", DecidedBy: DecidedByCountry, IPStatus: IPStatusGeolocated, Reason: ReasonCountryNotInList, Provider: "c",
+				Disagreement: []SourceAnswer{{Source: "a", Country: "CA", Agrees: true}, {Source: "b", Country: "US"}, {Source: "c", Country: "GB"}},
+			},
+		},
+		{
+			name:      "deny_any: every source allowed",
+			strategy:  StrategyDenyAny,
+			countries: []string{"CA", "", "US"},
+			want: CheckResult{
+				Allowed: true, Country: "CA", DecidedBy: DecidedByCountry, IPStatus: IPStatusGeolocated, Reason: ReasonCountryAllowed, MatchedRule: "CA", Provider: "a",
+				Disagreement: []SourceAnswer{{Source: "a", Country: "CA", Agrees: true}, {Source: "b"}, {Source: "c", Country: "US"}},
+			},
+		},
+		{
+			name:      "unknown everywhere",
+			strategy:  StrategyMajority,
+			countries: []string{"", ""},
+			want:      CheckResult{IPStatus: IPStatusUnknown, Reason: ReasonUnknownIP, Provider: "a,b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sources []Source
+			for i, country := range tt.countries {
+				sources = append(sources, staticSource(string(rune('a'+i)), country))
+			}
+			lookup, err := NewConsensusLookuper(tt.strategy, sources...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := NewChecker(lookup).CheckPolicy(context.Background(), "8.8.8.8", policy)
+			if err != nil {
+				t.Fatalf("CheckPolicy() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckPolicy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChecker_LookupConsensus(t *testing.T) {
+	info := mockInfoLookuper{
+		mockLookuper: mockLookuper{lookup: func(net.IP) (string, error) { return "CA", nil }},
+		info: func(net.IP, string) (GeoInfo, error) {
+			return GeoInfo{Country: Country{ISOCode: "CA", Name: "Canada"}}, nil
+		},
+	}
+	lookup, err := NewConsensusLookuper(StrategyMajority,
+		staticSource("a", "US"),
+		Source{Name: "b", Lookup: info},
+		staticSource("c", "CA"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewChecker(lookup).Lookup(context.Background(), "8.8.8.8", "")
+	if err != nil {
+		t.Fatalf("Lookup() unexpected error: %v", err)
+	}
+	want := LookupResult{
+		GeoInfo:      GeoInfo{Country: Country{ISOCode: "CA", Name: "Canada"}},
+		IPStatus:     IPStatusGeolocated,
+		Provider:     "b,c",
+		Disagreement: []SourceAnswer{{Source: "a", Country: "US"}, {Source: "b", Country: "CA", Agrees: true}, {Source: "c", Country: "CA", Agrees: true}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lookup() = %+v, want %+v", got, want)
+	}
+}
+
+// tracedConsensus is a decorator that forwards ConsensusReporter by embedding.
+type tracedConsensus struct {
+	*ConsensusLookuper
+}
+
+func TestChecker_WrappedConsensus(t *testing.T) {
+	lookup, err := NewConsensusLookuper(StrategyDenyAny, staticSource("a", "US"), staticSource("b", "IR"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := Policy{AllowedCountries: []string{"*"}, DeniedCountries: []string{"IR"}}
+
+	got, err := NewChecker(tracedConsensus{lookup}).CheckPolicy(context.Background(), "8.8.8.8", policy)
+	if err != nil || got.Allowed || got.Country != "IR" {
+		t.Errorf("CheckPolicy() through a forwarding decorator = %+v, %v, want denied for IR", got, err)
+	}
+
+	checker := NewChecker(NewCachedLookuper(lookup, 10, 0))
+	if err := checker.Validate(); !errors.Is(err, ErrWrappedConsensus) {
+		t.Errorf("Validate() error = %v, want ErrWrappedConsensus", err)
+	}
+	if _, err := checker.CheckPolicy(context.Background(), "8.8.8.8", policy); !errors.Is(err, ErrWrappedConsensus) {
+		t.Errorf("CheckPolicy() error = %v, want ErrWrappedConsensus", err)
+	}
+	if _, err := checker.Lookup(context.Background(), "8.8.8.8", ""); !errors.Is(err, ErrWrappedConsensus) {
+		t.Errorf("Lookup() error = %v, want ErrWrappedConsensus", err)
+	}
+}
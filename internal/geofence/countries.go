@@ -0,0 +1,191 @@
+package geofence
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidCountryCode is returned when a country list holds an entry that is not
+// an ISO 3166-1 alpha-2 code. The error is a *CountryCodeError.
+var ErrInvalidCountryCode = errors.New("invalid country code")
+
+// CountryCodeError lists the entries of a policy's country lists that are not
+// ISO 3166-1 alpha-2 codes, in the order they were given.
+type CountryCodeError struct {
+	AllowedCountries []string
+	DeniedCountries  []string
+}
+
+func (e *CountryCodeError) Error() string {
+	var fields []string
+	for _, f := range []struct {
+		name  string
+		codes []string
+	}{{"allowed_countries", e.AllowedCountries}, {"denied_countries", e.DeniedCountries}} {
+		if len(f.codes) == 0 {
+			continue
+		}
+		quoted := make([]string, len(f.codes))
+		for i, code := range f.codes {
+			quoted[i] = fmt.Sprintf("%q", code)
+		}
+		fields = append(fields, f.name+": "+strings.Join(quoted, ", "))
+	}
+	return fmt.Sprintf("%v: %s", ErrInvalidCountryCode, strings.Join(fields, "; "))
+}
+
+func (e *CountryCodeError) Unwrap() error {
+	return ErrInvalidCountryCode
+}
+
+// Fields returns the invalid entries keyed by the request field they came from.
+func (e *CountryCodeError) Fields() map[string][]string {
+	fields := make(map[string][]string, 2)
+	if len(e.AllowedCountries) > 0 {
+		fields["allowed_countries"] = e.AllowedCountries
+	}
+	if len(e.DeniedCountries) > 0 {
+		fields["denied_countries"] = e.DeniedCountries
+	}
+	return fields
+}
+
+// countrySet is a set of alpha-2 codes with one bit per possible code (26×26),
+// plus the AllCountries wildcard, so membership is a shift and a mask instead of
+// string comparisons. The zero value is empty.
+type countrySet struct {
+	bits [(26*26 + 63) / 64]uint64
+	all  bool
+}
+
+// countryIndex maps a two-letter code, in any case, to its bit in a countrySet.
+func countryIndex(code string) (int, bool) {
+	if len(code) != 2 {
+		return 0, false
+	}
+	a, b := code[0]|0x20, code[1]|0x20 // ASCII lower case
+	if a < 'a' || a > 'z' || b < 'a' || b > 'z' {
+		return 0, false
+	}
+	return int(a-'a')*26 + int(b-'a'), true
+}
+
+func (s *countrySet) add(i int) {
+	s.bits[i/64] |= 1 << (i % 64)
+}
+
+func (s *countrySet) has(i int) bool {
+	return s.bits[i/64]&(1<<(i%64)) != 0
+}
+
+var (
+	validCodes     countrySet
+	countryNames   [26 * 26]string // ISO short names by countryIndex
+	alpha3ToAlpha2 = make(map[string]string, len(iso3166))
+	nameToAlpha2   = make(map[string]string, len(iso3166)+len(countryAliases))
+)
+
+func init() {
+	for _, c := range iso3166 {
+		i, _ := countryIndex(c.alpha2)
+		validCodes.add(i)
+		countryNames[i] = c.name
+		alpha3ToAlpha2[c.alpha3] = c.alpha2
+		nameToAlpha2[countryNameKey(c.name)] = c.alpha2
+	}
+	for name, code := range countryAliases {
+		nameToAlpha2[name] = code
+	}
+}
+
+// validIndex returns the countrySet bit for code if it is an ISO 3166-1 alpha-2
+// code, in any case.
+func validIndex(code string) (int, bool) {
+	i, ok := countryIndex(code)
+	return i, ok && validCodes.has(i)
+}
+
+// isCountryCode reports whether code is an ISO 3166-1 alpha-2 code, in any case.
+func isCountryCode(code string) bool {
+	_, ok := validIndex(code)
+	return ok
+}
+
+// countryName returns the ISO 3166-1 short name for an alpha-2 code, or "".
+func countryName(code string) string {
+	if i, ok := validIndex(code); ok {
+		return countryNames[i]
+	}
+	return ""
+}
+
+// normalizeCountry returns the alpha-2 code for s. Alpha-2 codes are accepted in
+// any case; alpha-3 codes and country names ("United States", "south korea") only
+// when lenient.
+func normalizeCountry(s string, lenient bool) (string, bool) {
+	if isCountryCode(s) {
+		return strings.ToUpper(s), true
+	}
+	if !lenient {
+		return "", false
+	}
+	if alpha2, ok := alpha3ToAlpha2[strings.ToUpper(strings.TrimSpace(s))]; ok {
+		return alpha2, true
+	}
+	alpha2, ok := nameToAlpha2[countryNameKey(s)]
+	return alpha2, ok
+}
+
+func countryNameKey(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// compileCountries checks the country lists against ISO 3166-1, allowing the
+// AllCountries wildcard, and builds their sets. When lenient, entries that are not
+// alpha-2 codes are rewritten to them. Every invalid entry is reported in one
+// *CountryCodeError, and p is left unchanged. It updates p in place because a
+// Policy is large enough that copying it shows up in the cost of a check.
+func (p *Policy) compileCountries(lenient bool) error {
+	var allowed, denied countrySet
+	allowedList, badAllowed := compileCountryList(&allowed, p.AllowedCountries, lenient)
+	deniedList, badDenied := compileCountryList(&denied, p.DeniedCountries, lenient)
+	if len(badAllowed) > 0 || len(badDenied) > 0 {
+		return &CountryCodeError{AllowedCountries: badAllowed, DeniedCountries: badDenied}
+	}
+	p.AllowedCountries, p.DeniedCountries = allowedList, deniedList
+	p.allowed, p.denied = allowed, denied
+	return nil
+}
+
+// compileCountryList adds list to set and collects the entries that are not
+// countries. Alpha-2 codes take a fast path without allocating; the list is only
+// copied if lenient matching rewrites an entry, so the input is never modified.
+func compileCountryList(set *countrySet, list []string, lenient bool) (normalized, invalid []string) {
+	normalized = list
+	copied := false
+	for i, entry := range list {
+		if entry == AllCountries {
+			set.all = true
+			continue
+		}
+		if idx, ok := validIndex(entry); ok {
+			set.add(idx)
+			continue
+		}
+		code, ok := normalizeCountry(entry, lenient)
+		if !ok {
+			invalid = append(invalid, entry)
+			continue
+		}
+		idx, _ := countryIndex(code)
+		set.add(idx)
+		if !copied {
+			normalized = slices.Clone(list)
+			copied = true
+		}
+		normalized[i] = code
+	}
+	return normalized, invalid
+}
@@ -0,0 +1,221 @@
+package geofence
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeCountry(t *testing.T) {
+	tests := []struct {
+		in      string
+		lenient bool
+		want    string
+		wantOK  bool
+	}{
+		{in: "US", want: "US", wantOK: true},
+		{in: "us", want: "US", wantOK: true},
+		{in: "XK", want: "XK", wantOK: true},
+		{in: "USA"},
+		{in: "United States"},
+		{in: "U S"},
+		{in: "ZZ"},
+		{in: "USA", lenient: true, want: "US", wantOK: true},
+		{in: "gbr", lenient: true, want: "GB", wantOK: true},
+		{in: "United States", lenient: true, want: "US", wantOK: true},
+		{in: "  south   KOREA ", lenient: true, want: "KR", wantOK: true},
+		{in: "Korea, Republic of", lenient: true, want: "KR", wantOK: true},
+		{in: "Cote d'Ivoire", lenient: true, want: "CI", wantOK: true},
+		{in: "UK", lenient: true, want: "GB", wantOK: true},
+		{in: "U S", lenient: true},
+		{in: "Atlantis", lenient: true},
+	}
+
+	for _, tt := range tests {
+		got, ok := normalizeCountry(tt.in, tt.lenient)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("normalizeCountry(%q, %v) = %q, %v, want %q, %v", tt.in, tt.lenient, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestISO3166Table(t *testing.T) {
+	alpha3 := make(map[string]bool)
+	for _, c := range iso3166 {
+		if len(c.alpha2) != 2 || len(c.alpha3) != 3 || c.name == "" {
+			t.Errorf("malformed entry %+v", c)
+		}
+		if alpha3[c.alpha3] {
+			t.Errorf("duplicate alpha-3 code %s", c.alpha3)
+		}
+		alpha3[c.alpha3] = true
+	}
+	for name, code := range countryAliases {
+		if !isCountryCode(code) {
+			t.Errorf("alias %q maps to unknown code %s", name, code)
+		}
+		if name != countryNameKey(name) {
+			t.Errorf("alias %q is not a normalized key", name)
+		}
+	}
+}
+
+func TestChecker_CountryValidation(t *testing.T) {
+	lookup := mockLookuper{lookup: func(net.IP) (string, error) { return "US", nil }}
+	strict := NewChecker(lookup)
+	lenient := NewChecker(lookup, WithLenientCountries())
+
+	tests := []struct {
+		name        string
+		checker     *Checker
+		policy      Policy
+		wantAllowed bool
+		wantErr     *CountryCodeError
+	}{
+		{
+			name:        "alpha-2 codes",
+			checker:     strict,
+			policy:      Policy{AllowedCountries: []string{"us", "CA"}},
+			wantAllowed: true,
+		},
+		{
+			name:    "strict rejects every bad entry",
+			checker: strict,
+			policy:  Policy{AllowedCountries: []string{"USA", "CA", "United States"}, DeniedCountries: []string{"U S"}},
+			wantErr: &CountryCodeError{AllowedCountries: []string{"USA", "United States"}, DeniedCountries: []string{"U S"}},
+		},
+		{
+			name:        "wildcard",
+			checker:     strict,
+			policy:      Policy{AllowedCountries: []string{AllCountries}, DeniedCountries: []string{"KP"}},
+			wantAllowed: true,
+		},
+		{
+			name:        "lenient maps names and alpha-3",
+			checker:     lenient,
+			policy:      Policy{AllowedCountries: []string{"Canada", "USA"}},
+			wantAllowed: true,
+		},
+		{
+			name:    "lenient still rejects unknown names",
+			checker: lenient,
+			policy:  Policy{AllowedCountries: []string{"United States", "Narnia"}},
+			wantErr: &CountryCodeError{AllowedCountries: []string{"Narnia"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.checker.CheckPolicy(context.Background(), "8.8.8.8", tt.policy)
+			if tt.wantErr != nil {
+				var codeErr *CountryCodeError
+				if !errors.As(err, &codeErr) || !errors.Is(err, ErrInvalidCountryCode) {
+					t.Fatalf("CheckPolicy() error = %v, want CountryCodeError", err)
+				}
+				if !slices.Equal(codeErr.AllowedCountries, tt.wantErr.AllowedCountries) ||
+					!slices.Equal(codeErr.DeniedCountries, tt.wantErr.DeniedCountries) {
+					t.Errorf("CheckPolicy() error = %+v, want %+v", codeErr, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CheckPolicy() unexpected error: %v", err)
+			}
+			if got.Allowed != tt.wantAllowed {
+				t.Errorf("Allowed = %v, want %v", got.Allowed, tt.wantAllowed)
+			}
+		})
+	}
+}
+
+func TestCountryCodeError(t *testing.T) {
+	err := &CountryCodeError{AllowedCountries: []string{"USA", "United States"}, DeniedCountries: []string{"U S"}}
+	want := `invalid country code: allowed_countries: "USA", "United States"; denied_countries: "U S"`
+	if err.Error() != want {
+		t.Errorf("Error() = %s, want %s", err.Error(), want)
+	}
+	fields := err.Fields()
+	if len(fields) != 2 || !slices.Equal(fields["denied_countries"], []string{"U S"}) {
+		t.Errorf("Fields() = %v", fields)
+	}
+}
+
+func TestPolicy_CompileRejectsInvalidCountries(t *testing.T) {
+	if _, err := (Policy{AllowedCountries: []string{"USA"}}).Compile(); !errors.Is(err, ErrInvalidCountryCode) {
+		t.Errorf("Compile() error = %v, want ErrInvalidCountryCode", err)
+	}
+}
+
+func TestChecker_PrepareLenientNamedPolicy(t *testing.T) {
+	lookup := mockLookuper{lookup: func(net.IP) (string, error) { return "GB", nil }}
+	checker := NewChecker(lookup, WithLenientCountries(), WithPolicies(map[string]Policy{
+		"uk": {AllowedCountries: []string{"United Kingdom"}},
+	}))
+	if err := checker.Validate(); err != nil {
+		t.Fatalf("Validate() unexpected error: %v", err)
+	}
+	policy, err := checker.Resolve(PolicyRef{Name: "uk"})
+	if err != nil {
+		t.Fatalf("Resolve() unexpected error: %v", err)
+	}
+	if !slices.Equal(policy.AllowedCountries, []string{"GB"}) {
+		t.Errorf("AllowedCountries = %v, want [GB]", policy.AllowedCountries)
+	}
+
+	if err := NewChecker(lookup, WithPolicies(map[string]Policy{
+		"uk": {AllowedCountries: []string{"United Kingdom"}},
+	})).Validate(); !errors.Is(err, ErrInvalidCountryCode) {
+		t.Errorf("strict Validate() error = %v, want ErrInvalidCountryCode", err)
+	}
+}
+
+func TestCountryIndex(t *testing.T) {
+	seen := make(map[int]string)
+	for _, c := range iso3166 {
+		i, ok := countryIndex(c.alpha2)
+		if !ok || i < 0 || i >= 26*26 {
+			t.Fatalf("countryIndex(%s) = %d, %v", c.alpha2, i, ok)
+		}
+		if other, dup := seen[i]; dup {
+			t.Errorf("%s and %s share index %d", c.alpha2, other, i)
+		}
+		seen[i] = c.alpha2
+		if lower, _ := countryIndex(strings.ToLower(c.alpha2)); lower != i {
+			t.Errorf("countryIndex(%s) is case-sensitive", c.alpha2)
+		}
+	}
+	for _, code := range []string{"", "U", "USA", "U1", "@A", "[A", "`A", "{A", "é"} {
+		if _, ok := countryIndex(code); ok {
+			t.Errorf("countryIndex(%q) accepted", code)
+		}
+	}
+}
+
+func TestCompileCountryList(t *testing.T) {
+	list := []string{"us", AllCountries, "Canada"}
+	var set countrySet
+	normalized, invalid := compileCountryList(&set, list, true)
+	if len(invalid) != 0 {
+		t.Fatalf("invalid = %v", invalid)
+	}
+	if !slices.Equal(normalized, []string{"us", AllCountries, "CA"}) || list[2] != "Canada" {
+		t.Errorf("normalized = %v, input = %v", normalized, list)
+	}
+	if !set.all {
+		t.Error("wildcard not recorded")
+	}
+	for code, want := range map[string]bool{"US": true, "CA": true, "MX": false} {
+		i, _ := countryIndex(code)
+		if set.has(i) != want {
+			t.Errorf("has(%s) = %v, want %v", code, !want, want)
+		}
+	}
+
+	unchanged := []string{"US", "GB"}
+	if normalized, _ := compileCountryList(&countrySet{}, unchanged, true); &normalized[0] != &unchanged[0] {
+		t.Error("list without rewrites was copied")
+	}
+}
@@ -1,65 +1,231 @@
 package geofence
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net"
 	"net/netip"
+	"sync"
+	"time"
 
-	"github.com/oschwald/geoip2-golang/v2"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // ErrUnknownIP is returned when an IP address is not found in the GeoIP database
 // (e.g., private ranges like 192.168.x.x or reserved addresses).
 var ErrUnknownIP = errors.New("ip not found in database")
 
+// ErrStoreClosed is returned by lookups made after the GeoStore has been closed.
+var ErrStoreClosed = errors.New("geoip database closed")
+
 // DefaultDBPath is the default path to the MaxMind GeoLite2-Country database.
 const DefaultDBPath = "data/GeoLite2-Country.mmdb"
 
-// GeoStore encapsulates the MaxMind GeoIP reader and provides a clean interface
-// for country lookups. It is safe for concurrent use.
+// probeAddr is looked up when validating a newly opened database. Any address works;
+// the probe only proves the file supports country lookups and its search tree is readable.
+var probeAddr = netip.MustParseAddr("8.8.8.8")
+
+// GeoStore holds the GeoIP database and provides a clean interface for country
+// lookups. The database is read by the adapter for its provider, MaxMind unless
+// WithProvider says otherwise. It is safe for concurrent use.
+//
+// The database can be replaced at runtime with Reload. Lookups hold a read lock for
+// the duration of the lookup, so a reload waits for in-flight lookups on the old
+// database to drain before closing it.
 type GeoStore struct {
-	reader *geoip2.Reader
+	dbFile
+}
+
+// dbFile is the database file behind a GeoStore or AnonymityStore. It opens the
+// file, swaps in a fresh copy on Reload and closes it; the stores add lookups,
+// which hold mu for reading.
+type dbFile struct {
+	mu       sync.RWMutex
+	db       Database
+	provider string
+	path     string
+	asnPath  string // see WithASNDatabase
+	openDB   func(provider, path, asnPath string) (Database, error)
+	onReload []func()
+}
+
+// StoreOption configures optional GeoStore and AnonymityStore behavior.
+type StoreOption func(*dbFile)
+
+// WithProvider selects the adapter used to read the database file: one of
+// ProviderMaxMind (the default), ProviderDBIP, ProviderIPinfo or ProviderCSV, or
+// for NewAnonymityStore ProviderMaxMind or ProviderCSV. An empty name keeps the
+// default.
+func WithProvider(provider string) StoreOption {
+	return func(f *dbFile) {
+		if provider != "" {
+			f.provider = provider
+		}
+	}
 }
 
 // NewGeoStore opens the GeoIP database at the given path and returns a GeoStore.
-// It fails fast if the file is missing or corrupted.
-func NewGeoStore(dbPath string) (*GeoStore, error) {
-	reader, err := geoip2.Open(dbPath)
+// It fails fast if the file is missing or corrupted, or the provider is unknown.
+func NewGeoStore(dbPath string, opts ...StoreOption) (*GeoStore, error) {
+	g := &GeoStore{}
+	err := g.init(dbPath, opts, func(provider, path, _ string) (Database, error) {
+		return openDatabase(provider, path)
+	})
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("GeoIP database opened successfully", "path", dbPath, "provider", g.provider)
+	return g, nil
+}
+
+// init applies opts and opens the file at path with openDB, which Reload uses
+// again later.
+func (f *dbFile) init(path string, opts []StoreOption, openDB func(provider, path, asnPath string) (Database, error)) error {
+	f.provider, f.path, f.openDB = ProviderMaxMind, path, openDB
+	for _, opt := range opts {
+		opt(f)
+	}
+	db, err := f.open()
 	if err != nil {
-		return nil, fmt.Errorf("open geoip database: %w", err)
+		return err
 	}
-	slog.Info("GeoIP database opened successfully", "path", dbPath)
-	return &GeoStore{reader: reader}, nil
+	f.db = db
+	return nil
+}
+
+// open opens the store's file with its provider's adapter.
+func (f *dbFile) open() (Database, error) {
+	return f.openDB(f.provider, f.path, f.asnPath)
+}
+
+// Path returns the database path the store was opened with.
+func (f *dbFile) Path() string {
+	return f.path
+}
+
+// Provider returns the name of the provider whose database the store reads.
+func (f *dbFile) Provider() string {
+	return f.provider
 }
 
 // Lookup returns the ISO 3166-1 alpha-2 country code (e.g., "US", "FR") for the
 // given IP address. Returns ErrUnknownIP if the IP is not in the database.
 func (g *GeoStore) Lookup(ip net.IP) (string, error) {
+	return g.LookupContext(context.Background(), ip)
+}
+
+// LookupContext is Lookup traced as a geofence.Lookup span under the span in ctx.
+func (g *GeoStore) LookupContext(ctx context.Context, ip net.IP) (string, error) {
+	country, _, err := g.LookupNetworkContext(ctx, ip)
+	return country, err
+}
+
+// LookupNetwork is Lookup that also returns the largest network containing ip that
+// shares its answer. IPv4 networks are reported as IPv4 prefixes. For IPs missing
+// from the database the network is returned along with ErrUnknownIP.
+func (g *GeoStore) LookupNetwork(ip net.IP) (string, netip.Prefix, error) {
+	return g.LookupNetworkContext(context.Background(), ip)
+}
+
+// LookupNetworkContext is LookupNetwork traced as a geofence.Lookup span under the
+// span in ctx.
+func (g *GeoStore) LookupNetworkContext(ctx context.Context, ip net.IP) (string, netip.Prefix, error) {
+	span := startLookupSpan(ctx, g.Provider())
+	defer span.End()
+
+	country, network, err := g.lookupNetwork(ip)
+	span.SetAttributes(
+		attribute.String("geofence.country", country),
+		attribute.String("geofence.network", network.String()),
+	)
+	if !errors.Is(err, ErrUnknownIP) {
+		recordError(span, err)
+	}
+	return country, network, err
+}
+
+func (g *GeoStore) lookupNetwork(ip net.IP) (string, netip.Prefix, error) {
 	if ip == nil {
-		return "", fmt.Errorf("invalid IP: nil address")
+		return "", netip.Prefix{}, fmt.Errorf("invalid IP: nil address")
 	}
 	addr, ok := netip.AddrFromSlice(ip)
 	if !ok {
-		return "", fmt.Errorf("invalid IP: %s", ip.String())
+		return "", netip.Prefix{}, fmt.Errorf("invalid IP: %s", ip.String())
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.db == nil {
+		return "", netip.Prefix{}, ErrStoreClosed
+	}
+	info, found, err := g.db.Lookup(addr.Unmap(), DefaultLocale)
+	if err != nil {
+		return "", netip.Prefix{}, fmt.Errorf("lookup country: %w", err)
 	}
+	if !found {
+		return "", info.Network, ErrUnknownIP
+	}
+	return info.Country.ISOCode, info.Network, nil
+}
+
+// OnReload registers fn to be called after every successful Reload, e.g. to purge
+// a CachedLookuper.
+func (f *dbFile) OnReload(fn func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.onReload = append(f.onReload, fn)
+}
 
-	record, err := g.reader.Country(addr)
+// BuildEpoch returns when the currently loaded database was built, as recorded in
+// its metadata. It returns the zero time once the store is closed.
+func (f *dbFile) BuildEpoch() time.Time {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.db == nil {
+		return time.Time{}
+	}
+	return f.db.BuildTime()
+}
+
+// Reload re-opens the database at the store's path and swaps it in. If the new
+// file fails to open or validate, the current database stays in place and the error
+// is returned. The old database is closed only after in-flight lookups have finished.
+func (f *dbFile) Reload() error {
+	db, err := f.open()
 	if err != nil {
-		return "", fmt.Errorf("lookup country: %w", err)
+		return err
 	}
-	if !record.HasData() {
-		return "", ErrUnknownIP
+
+	f.mu.Lock()
+	old := f.db
+	f.db = db
+	callbacks := f.onReload
+	f.mu.Unlock()
+
+	for _, fn := range callbacks {
+		fn()
+	}
+
+	if old != nil {
+		if err := old.Close(); err != nil {
+			slog.Warn("close previous GeoIP database", "err", err)
+		}
 	}
-	return record.Country.ISOCode, nil
+	slog.Info("database reloaded", "path", f.path, "provider", f.provider, "build_epoch", db.BuildTime().Unix())
+	return nil
 }
 
-// Close releases the underlying database reader and any memory-mapped resources.
-// Callers should invoke Close when the GeoStore is no longer needed (e.g., defer store.Close()).
-func (g *GeoStore) Close() error {
-	if g.reader == nil {
+// Close releases the underlying database and any memory-mapped resources.
+// Callers should invoke Close when the store is no longer needed (e.g., defer store.Close()).
+func (f *dbFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.db == nil {
 		return nil
 	}
-	return g.reader.Close()
+	err := f.db.Close()
+	f.db = nil
+	return err
 }
@@ -1,11 +1,14 @@
 package geofence
 
 import (
+	"context"
 	"errors"
 	"net"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestNewGeoStore_InvalidPath(t *testing.T) {
@@ -81,3 +84,142 @@ func TestGeoStore_Lookup(t *testing.T) {
 		})
 	}
 }
+
+// copyTestDB copies the GeoLite2 database into a temp dir so tests can overwrite it.
+func copyTestDB(t *testing.T) string {
+	t.Helper()
+	src := filepath.Join("..", "..", "data", "GeoLite2-Country.mmdb")
+	data, err := os.ReadFile(src)
+	if os.IsNotExist(err) {
+		t.Skipf("GeoLite2-Country.mmdb not found at %s; skip reload tests", src)
+	}
+	if err != nil {
+		t.Fatalf("read test database: %v", err)
+	}
+	dst := filepath.Join(t.TempDir(), "GeoLite2-Country.mmdb")
+	if err := os.WriteFile(dst, data, 0o644); err != nil {
+		t.Fatalf("write test database: %v", err)
+	}
+	return dst
+}
+
+func TestGeoStore_Reload(t *testing.T) {
+	dbPath := copyTestDB(t)
+	valid, err := os.ReadFile(dbPath)
+	if err != nil {
+		t.Fatalf("read test database: %v", err)
+	}
+
+	store, err := NewGeoStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewGeoStore: %v", err)
+	}
+	defer store.Close()
+
+	// A corrupt replacement must be rejected and the old reader kept.
+	if err := os.WriteFile(dbPath, []byte("not a database"), 0o644); err != nil {
+		t.Fatalf("write corrupt database: %v", err)
+	}
+	if err := store.Reload(); err == nil {
+		t.Fatal("expected error reloading corrupt database, got nil")
+	}
+	if country, err := store.Lookup(net.ParseIP("8.8.8.8")); err != nil || country != "US" {
+		t.Fatalf("after failed reload: Lookup = %q, %v; want US, nil", country, err)
+	}
+
+	// A valid replacement is swapped in.
+	if err := os.WriteFile(dbPath, valid, 0o644); err != nil {
+		t.Fatalf("restore database: %v", err)
+	}
+	if err := store.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if country, err := store.Lookup(net.ParseIP("8.8.8.8")); err != nil || country != "US" {
+		t.Fatalf("after reload: Lookup = %q, %v; want US, nil", country, err)
+	}
+}
+
+func TestGeoStore_ReloadConcurrentLookups(t *testing.T) {
+	dbPath := copyTestDB(t)
+	store, err := NewGeoStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewGeoStore: %v", err)
+	}
+	defer store.Close()
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if _, err := store.Lookup(net.ParseIP("8.8.8.8")); err != nil {
+					t.Errorf("Lookup during reload: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		if err := store.Reload(); err != nil {
+			t.Errorf("Reload: %v", err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func TestGeoStore_Watch(t *testing.T) {
+	dbPath := copyTestDB(t)
+	store, err := NewGeoStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewGeoStore: %v", err)
+	}
+	defer store.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- store.Watch(ctx, 10*time.Millisecond) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Watch: %v", err)
+		}
+	}()
+
+	store.mu.RLock()
+	before := store.db
+	store.mu.RUnlock()
+
+	// Give the watcher time to register, then replace the file atomically.
+	time.Sleep(50 * time.Millisecond)
+	data, err := os.ReadFile(dbPath)
+	if err != nil {
+		t.Fatalf("read database: %v", err)
+	}
+	tmp := dbPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		t.Fatalf("write temp database: %v", err)
+	}
+	if err := os.Rename(tmp, dbPath); err != nil {
+		t.Fatalf("rename database: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		store.mu.RLock()
+		swapped := store.db != before
+		store.mu.RUnlock()
+		if swapped {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("database was not reloaded after file replacement")
+}
@@ -0,0 +1,116 @@
+package geofence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// ErrUnknownRegion is returned when a policy names a region that is not configured.
+var ErrUnknownRegion = errors.New("unknown region")
+
+// Groups maps region names, such as continent codes ("NA", "EU") or named groups
+// ("EU27", "OFAC-SANCTIONED"), to the ISO country codes they contain. Keys are
+// upper case, as LoadGroups returns them; lookups are case-insensitive.
+type Groups map[string][]string
+
+// Members returns the countries in region, or false if it is not defined.
+func (g Groups) Members(region string) ([]string, bool) {
+	members, ok := g[strings.ToUpper(region)]
+	return members, ok
+}
+
+// Names returns the region names in sorted order.
+func (g Groups) Names() []string {
+	return slices.Sorted(maps.Keys(g))
+}
+
+// groupsFile is the on-disk format read by LoadGroups.
+type groupsFile struct {
+	Groups map[string][]string `json:"groups"`
+}
+
+// LoadGroups reads region groups from a JSON file of the form
+//
+//	{"groups": {
+//	  "NA": ["US", "CA", "MX", ...],
+//	  "EU27": ["AT", "BE", "BG", ...],
+//	  "OFAC-SANCTIONED": ["CU", "IR", "KP", "SY"]
+//	}}
+//
+// Members are ISO 3166-1 alpha-2 codes; groups do not reference each other, so a
+// continent code such as "NA" can never be confused with a country code inside a
+// group. Names and codes are normalized to upper case and members sorted.
+func LoadGroups(path string) (Groups, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read groups: %w", err)
+	}
+	var file groupsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse groups: %w", err)
+	}
+
+	groups := make(Groups, len(file.Groups))
+	for name, members := range file.Groups {
+		key := strings.ToUpper(strings.TrimSpace(name))
+		if key == "" {
+			return nil, fmt.Errorf("parse groups: group name must not be empty")
+		}
+		if _, dup := groups[key]; dup {
+			return nil, fmt.Errorf("group %q: defined more than once", name)
+		}
+		if len(members) == 0 {
+			return nil, fmt.Errorf("group %q: must contain at least one country", name)
+		}
+		codes := make([]string, 0, len(members))
+		for _, m := range members {
+			code := strings.ToUpper(strings.TrimSpace(m))
+			if !isCountryCode(code) {
+				return nil, fmt.Errorf("group %q: %q is not an ISO country code", name, m)
+			}
+			codes = append(codes, code)
+		}
+		slices.Sort(codes)
+		groups[key] = slices.Compact(codes)
+	}
+	return groups, nil
+}
+
+// regionIndex maps each country in a policy's regions to the first region that
+// listed it, so decisions can report the region as the matched rule. A nil index
+// matches nothing.
+type regionIndex struct {
+	names  []string        // upper case, in policy order
+	region [26 * 26]uint16 // per countryIndex, 1 + index into names; 0 if none
+}
+
+func indexRegions(groups Groups, regions []string) (*regionIndex, error) {
+	index := &regionIndex{names: make([]string, 0, len(regions))}
+	for _, region := range regions {
+		members, ok := groups.Members(region)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownRegion, region)
+		}
+		index.names = append(index.names, strings.ToUpper(region))
+		for _, country := range members {
+			i, _ := countryIndex(country)
+			if index.region[i] == 0 {
+				index.region[i] = uint16(len(index.names))
+			}
+		}
+	}
+	return index, nil
+}
+
+// find returns the region containing the country at countrySet index i.
+func (r *regionIndex) find(i int) (string, bool) {
+	if r == nil || r.region[i] == 0 {
+		return "", false
+	}
+	return r.names[r.region[i]-1], true
+}
@@ -0,0 +1,232 @@
+package geofence
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoadGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Groups
+		wantErr bool
+	}{
+		{
+			name:    "normalized",
+			content: `{"groups":{"na":["us","CA","MX","US"],"OFAC-SANCTIONED":["CU","IR"]}}`,
+			want:    Groups{"NA": {"CA", "MX", "US"}, "OFAC-SANCTIONED": {"CU", "IR"}},
+		},
+		{
+			name:    "malformed JSON",
+			content: `{"groups":`,
+			wantErr: true,
+		},
+		{
+			name:    "empty group",
+			content: `{"groups":{"NA":[]}}`,
+			wantErr: true,
+		},
+		{
+			name:    "group names another group",
+			content: `{"groups":{"EU27":["DE"],"EEA":["EU27","NO"]}}`,
+			wantErr: true,
+		},
+		{
+			name:    "duplicate after normalizing",
+			content: `{"groups":{"NA":["US"],"na":["CA"]}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "groups.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("write groups: %v", err)
+			}
+			groups, err := LoadGroups(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(groups) != len(tt.want) {
+				t.Fatalf("LoadGroups() = %v, want %v", groups, tt.want)
+			}
+			for name, want := range tt.want {
+				if got, _ := groups.Members(name); !slices.Equal(got, want) {
+					t.Errorf("Members(%q) = %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadGroups_ConfigFile(t *testing.T) {
+	groups, err := LoadGroups(filepath.Join("..", "..", "config", "groups.json"))
+	if err != nil {
+		t.Fatalf("LoadGroups: %v", err)
+	}
+	sizes := map[string]int{"EU27": 27, "EEA": 30, "GDPR": 30}
+	for name, want := range sizes {
+		if members, _ := groups.Members(name); len(members) != want {
+			t.Errorf("%s has %d members, want %d", name, len(members), want)
+		}
+	}
+	// Every country belongs to exactly one continent.
+	seen := make(map[string]string)
+	for _, continent := range []string{"AF", "AN", "AS", "EU", "NA", "OC", "SA"} {
+		members, ok := groups.Members(continent)
+		if !ok {
+			t.Fatalf("continent %s missing", continent)
+		}
+		for _, country := range members {
+			if other, dup := seen[country]; dup {
+				t.Errorf("%s is in both %s and %s", country, other, continent)
+			}
+			seen[country] = continent
+		}
+	}
+}
+
+func TestChecker_CheckRegions(t *testing.T) {
+	countries := map[string]string{
+		"1.1.1.1": "US",
+		"2.2.2.2": "DE",
+		"3.3.3.3": "CU",
+		"4.4.4.4": "JP",
+	}
+	lookup := mockLookuper{lookup: func(ip net.IP) (string, error) {
+		return countries[ip.String()], nil
+	}}
+	groups := Groups{
+		"NA":              {"CA", "CU", "MX", "US"},
+		"EU27":            {"DE", "FR"},
+		"OFAC-SANCTIONED": {"CU", "IR"},
+	}
+	checker := NewChecker(lookup, WithGroups(groups), WithPolicies(map[string]Policy{
+		"north-america": {AllowedRegions: []string{"NA"}, DeniedRegions: []string{"OFAC-SANCTIONED"}},
+		"broken":        {AllowedRegions: []string{"MARS"}},
+	}))
+
+	tests := []struct {
+		name    string
+		ip      string
+		ref     PolicyRef
+		allowed bool
+		reason  Reason
+		rule    string
+		wantErr error
+	}{
+		{
+			name:    "allowed by region",
+			ip:      "1.1.1.1",
+			ref:     PolicyRef{Name: "north-america"},
+			allowed: true,
+			reason:  ReasonCountryAllowed,
+			rule:    "NA",
+		},
+		{
+			name:   "denied region beats allowed region",
+			ip:     "3.3.3.3",
+			ref:    PolicyRef{Name: "north-america"},
+			reason: ReasonCountryDenied,
+			rule:   "OFAC-SANCTIONED",
+		},
+		{
+			name:   "outside every region",
+			ip:     "4.4.4.4",
+			ref:    PolicyRef{Name: "north-america"},
+			reason: ReasonCountryNotInList,
+		},
+		{
+			name:    "explicit country reported over its region",
+			ip:      "2.2.2.2",
+			ref:     PolicyRef{Inline: Policy{AllowedCountries: []string{"DE"}, AllowedRegions: []string{"eu27"}}},
+			allowed: true,
+			reason:  ReasonCountryAllowed,
+			rule:    "DE",
+		},
+		{
+			name:   "explicit deny beats allowed region",
+			ip:     "2.2.2.2",
+			ref:    PolicyRef{Inline: Policy{AllowedRegions: []string{"EU27"}, DeniedCountries: []string{"DE"}}},
+			reason: ReasonCountryDenied,
+			rule:   "DE",
+		},
+		{
+			name:    "region beats wildcard deny",
+			ip:      "2.2.2.2",
+			ref:     PolicyRef{Inline: Policy{AllowedRegions: []string{"EU27"}, DeniedCountries: []string{AllCountries}}},
+			allowed: true,
+			reason:  ReasonCountryAllowed,
+			rule:    "EU27",
+		},
+		{
+			name:   "denied region beats wildcard allow",
+			ip:     "3.3.3.3",
+			ref:    PolicyRef{Inline: Policy{AllowedCountries: []string{AllCountries}, DeniedRegions: []string{"OFAC-SANCTIONED"}}},
+			reason: ReasonCountryDenied,
+			rule:   "OFAC-SANCTIONED",
+		},
+		{
+			name:    "unknown inline region",
+			ip:      "1.1.1.1",
+			ref:     PolicyRef{Inline: Policy{AllowedRegions: []string{"MARS"}}},
+			wantErr: ErrUnknownRegion,
+		},
+		{
+			name:    "unknown region in named policy",
+			ip:      "1.1.1.1",
+			ref:     PolicyRef{Name: "broken"},
+			wantErr: ErrUnknownRegion,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checker.Evaluate(context.Background(), tt.ip, tt.ref)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Evaluate() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Evaluate() unexpected error: %v", err)
+			}
+			if got.Allowed != tt.allowed || got.Reason != tt.reason || got.MatchedRule != tt.rule {
+				t.Errorf("Evaluate() = %+v, want allowed=%v reason=%s rule=%q", got, tt.allowed, tt.reason, tt.rule)
+			}
+		})
+	}
+}
+
+func TestChecker_Validate(t *testing.T) {
+	lookup := mockLookuper{lookup: func(net.IP) (string, error) { return "US", nil }}
+	groups := Groups{"NA": {"CA", "US"}}
+
+	valid := NewChecker(lookup, WithGroups(groups), WithPolicies(map[string]Policy{
+		"regions-only": {AllowedRegions: []string{"na"}},
+	}))
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() unexpected error: %v", err)
+	}
+
+	missing := NewChecker(lookup, WithPolicies(map[string]Policy{
+		"regions-only": {AllowedRegions: []string{"NA"}},
+	}))
+	if err := missing.Validate(); !errors.Is(err, ErrUnknownRegion) {
+		t.Errorf("Validate() without groups error = %v, want ErrUnknownRegion", err)
+	}
+}
@@ -0,0 +1,251 @@
+package geofence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+
+	"github.com/oschwald/geoip2-golang/v2"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrUnsupportedLocale is returned for a locale the database has no names in.
+var ErrUnsupportedLocale = errors.New("unsupported locale")
+
+// DefaultLocale is the locale names are returned in when none is requested. Names
+// missing in another locale fall back to it.
+const DefaultLocale = "en"
+
+// Locales are the locales GeoLite2 databases carry names in.
+var Locales = []string{"de", "en", "es", "fr", "ja", "pt-BR", "ru", "zh-CN"}
+
+// Country is a country record with its name in the requested locale.
+type Country struct {
+	ISOCode           string
+	Name              string
+	IsInEuropeanUnion bool
+}
+
+// RepresentedCountry is the country represented by, for example, a military base
+// or embassy. Type is the kind of entity, currently only "military".
+type RepresentedCountry struct {
+	Country
+	Type string
+}
+
+// Continent is a continent record with its name in the requested locale.
+type Continent struct {
+	Code string // two-letter code such as "NA" or "EU"
+	Name string
+}
+
+// GeoInfo is everything the database holds about an IP. Records the database has
+// no data for are left zero.
+type GeoInfo struct {
+	Country            Country            // where the IP is believed to be located
+	RegisteredCountry  Country            // where the ISP registered the block
+	RepresentedCountry RepresentedCountry // set for e.g. military bases
+	Continent          Continent
+	// Network is the largest prefix containing the IP that shares the same data.
+	Network netip.Prefix
+	// Anonymity is only reported by anonymous IP databases.
+	Anonymity Anonymity
+}
+
+// InfoLookuper is a CountryLookuper that can also return full records. GeoStore
+// implements it; Checker.Lookup falls back to the country code for lookupers that
+// do not.
+type InfoLookuper interface {
+	LookupInfo(ip net.IP, locale string) (GeoInfo, error)
+}
+
+// ContextInfoLookuper is an InfoLookuper whose lookups take the caller's context,
+// the LookupInfo counterpart of ContextLookuper.
+type ContextInfoLookuper interface {
+	LookupInfoContext(ctx context.Context, ip net.IP, locale string) (GeoInfo, error)
+}
+
+// LookupResult is the outcome of Checker.Lookup. For private IPs Network is the
+// reserved range the IP is in. Provider and Disagreement are set as on
+// CheckResult.
+type LookupResult struct {
+	GeoInfo
+	IPStatus     IPStatus
+	Provider     string
+	Disagreement []SourceAnswer
+}
+
+// Lookup returns what is known about an IP's location without evaluating any
+// policy. Names are in locale, or DefaultLocale when it is empty. Private and
+// reserved IPs are not looked up, and IPs missing from the database are reported
+// with IPStatusUnknown rather than an error. Anonymity flags are reported for
+// public IPs when the Checker has WithAnonymity.
+func (c *Checker) Lookup(ctx context.Context, ipStr, locale string) (LookupResult, error) {
+	if locale == "" {
+		locale = DefaultLocale
+	}
+	if !slices.Contains(Locales, locale) {
+		return LookupResult{}, fmt.Errorf("%w: %s", ErrUnsupportedLocale, locale)
+	}
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return LookupResult{}, fmt.Errorf("%w: %s", ErrInvalidIP, ipStr)
+	}
+
+	addr, _ := netip.AddrFromSlice(ip)
+	if reserved, private := reservedNetwork(addr.Unmap()); private {
+		return LookupResult{IPStatus: IPStatusPrivate, GeoInfo: GeoInfo{Network: reserved}}, nil
+	}
+
+	result, err := c.lookupPublic(ctx, ip, locale)
+	if err != nil || c.anonymity == nil {
+		return result, err
+	}
+	if result.Anonymity, err = c.lookupAnonymity(ctx, ip); err != nil {
+		return LookupResult{}, fmt.Errorf("lookup anonymity: %w", err)
+	}
+	return result, nil
+}
+
+// lookupPublic returns what the country database knows about a public IP.
+func (c *Checker) lookupPublic(ctx context.Context, ip net.IP, locale string) (LookupResult, error) {
+	if c.lookupErr != nil {
+		return LookupResult{}, fmt.Errorf("lookup: %w", c.lookupErr)
+	}
+	if c.consensus != nil {
+		return c.lookupConsensusInfo(ctx, ip, locale)
+	}
+
+	info, err := c.lookupInfo(ctx, ip, locale)
+	if err != nil {
+		if errors.Is(err, ErrUnknownIP) {
+			return LookupResult{IPStatus: IPStatusUnknown, Provider: c.provider()}, nil
+		}
+		return LookupResult{}, fmt.Errorf("lookup: %w", err)
+	}
+	return LookupResult{GeoInfo: info, IPStatus: IPStatusGeolocated, Provider: c.provider()}, nil
+}
+
+func (c *Checker) lookupInfo(ctx context.Context, ip net.IP, locale string) (GeoInfo, error) {
+	if info, ok := c.lookup.(ContextInfoLookuper); ok {
+		return info.LookupInfoContext(ctx, ip, locale)
+	}
+	info, ok := c.lookup.(InfoLookuper)
+	if !ok {
+		country, err := c.lookupCountry(ctx, ip)
+		return GeoInfo{Country: Country{ISOCode: country}}, err
+	}
+
+	_, span := tracer.Start(ctx, "geofence.Lookup")
+	defer span.End()
+	result, err := info.LookupInfo(ip, locale)
+	span.SetAttributes(attribute.String("geofence.country", result.Country.ISOCode))
+	if !errors.Is(err, ErrUnknownIP) {
+		recordError(span, err)
+	}
+	return result, err
+}
+
+// lookupConsensusInfo returns the record of a source that reported the country
+// lookup's strategy chose.
+func (c *Checker) lookupConsensusInfo(ctx context.Context, ip net.IP, locale string) (LookupResult, error) {
+	consensus, err := c.lookupConsensus(ctx, ip)
+	if err != nil {
+		if errors.Is(err, ErrUnknownIP) {
+			return LookupResult{IPStatus: IPStatusUnknown, Provider: consensus.provider("")}, nil
+		}
+		return LookupResult{}, fmt.Errorf("lookup: %w", err)
+	}
+	info, err := c.consensus.LookupConsensusInfo(ip, locale, consensus)
+	if err != nil {
+		return LookupResult{}, fmt.Errorf("lookup: %w", err)
+	}
+	result := LookupResult{GeoInfo: info, IPStatus: IPStatusGeolocated, Provider: consensus.provider(consensus.Country)}
+	if consensus.Disagree() {
+		result.Disagreement = consensus.Answers
+	}
+	return result, nil
+}
+
+// LookupInfo returns the full database record for ip with names in locale,
+// falling back to DefaultLocale for names the locale lacks. Returns ErrUnknownIP
+// if the IP is not in the database.
+func (g *GeoStore) LookupInfo(ip net.IP, locale string) (GeoInfo, error) {
+	return g.LookupInfoContext(context.Background(), ip, locale)
+}
+
+// LookupInfoContext is LookupInfo traced as a geofence.Lookup span under the span
+// in ctx.
+func (g *GeoStore) LookupInfoContext(ctx context.Context, ip net.IP, locale string) (GeoInfo, error) {
+	span := startLookupSpan(ctx, g.Provider())
+	defer span.End()
+
+	info, err := g.lookupInfo(ip, locale)
+	span.SetAttributes(
+		attribute.String("geofence.country", info.Country.ISOCode),
+		attribute.String("geofence.network", info.Network.String()),
+	)
+	if !errors.Is(err, ErrUnknownIP) {
+		recordError(span, err)
+	}
+	return info, err
+}
+
+func (g *GeoStore) lookupInfo(ip net.IP, locale string) (GeoInfo, error) {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return GeoInfo{}, fmt.Errorf("invalid IP: %v", ip)
+	}
+	// Unmap so IPv4 lookups report an IPv4 network rather than ::ffff:a.b.c.d/n.
+	addr = addr.Unmap()
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.db == nil {
+		return GeoInfo{}, ErrStoreClosed
+	}
+	info, found, err := g.db.Lookup(addr, locale)
+	if err != nil {
+		return GeoInfo{}, fmt.Errorf("lookup country: %w", err)
+	}
+	if !found {
+		return GeoInfo{}, ErrUnknownIP
+	}
+	return info, nil
+}
+
+func countryInfo(r geoip2.CountryRecord, locale string) Country {
+	return Country{
+		ISOCode:           r.ISOCode,
+		Name:              localName(r.Names, locale),
+		IsInEuropeanUnion: r.IsInEuropeanUnion,
+	}
+}
+
+// localName picks the name for locale, falling back to English.
+func localName(n geoip2.Names, locale string) string {
+	var name string
+	switch locale {
+	case "de":
+		name = n.German
+	case "es":
+		name = n.Spanish
+	case "fr":
+		name = n.French
+	case "ja":
+		name = n.Japanese
+	case "pt-BR":
+		name = n.BrazilianPortuguese
+	case "ru":
+		name = n.Russian
+	case "zh-CN":
+		name = n.SimplifiedChinese
+	}
+	if name == "" {
+		name = n.English
+	}
+	return name
+}
@@ -0,0 +1,154 @@
+package geofence
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type mockInfoLookuper struct {
+	mockLookuper
+	info func(net.IP, string) (GeoInfo, error)
+}
+
+func (m mockInfoLookuper) LookupInfo(ip net.IP, locale string) (GeoInfo, error) {
+	return m.info(ip, locale)
+}
+
+func TestChecker_Lookup(t *testing.T) {
+	gb := GeoInfo{
+		Country:   Country{ISOCode: "GB", Name: "United Kingdom"},
+		Continent: Continent{Code: "EU", Name: "Europe"},
+		Network:   netip.MustParsePrefix("81.2.69.0/24"),
+	}
+	info := mockInfoLookuper{info: func(ip net.IP, locale string) (GeoInfo, error) {
+		if ip.String() != "81.2.69.142" {
+			return GeoInfo{}, ErrUnknownIP
+		}
+		if locale == "de" {
+			result := gb
+			result.Country.Name = "Vereinigtes Königreich"
+			return result, nil
+		}
+		return gb, nil
+	}}
+	countryOnly := mockLookuper{lookup: func(net.IP) (string, error) { return "US", nil }}
+
+	tests := []struct {
+		name    string
+		lookup  CountryLookuper
+		ip      string
+		locale  string
+		want    LookupResult
+		wantErr error
+	}{
+		{
+			name:   "full record",
+			lookup: info,
+			ip:     "81.2.69.142",
+			want:   LookupResult{GeoInfo: gb, IPStatus: IPStatusGeolocated},
+		},
+		{
+			name:   "locale passed through",
+			lookup: info,
+			ip:     "81.2.69.142",
+			locale: "de",
+			want: LookupResult{IPStatus: IPStatusGeolocated, GeoInfo: GeoInfo{
+				Country:   Country{ISOCode: "GB", Name: "Vereinigtes Königreich"},
+				Continent: gb.Continent,
+				Network:   gb.Network,
+			}},
+		},
+		{
+			name:   "unknown IP is not an error",
+			lookup: info,
+			ip:     "8.8.4.4",
+			want:   LookupResult{IPStatus: IPStatusUnknown},
+		},
+		{
+			name:   "private IP reports the reserved range",
+			lookup: info,
+			ip:     "::ffff:10.1.2.3",
+			want:   LookupResult{IPStatus: IPStatusPrivate, GeoInfo: GeoInfo{Network: netip.MustParsePrefix("10.0.0.0/8")}},
+		},
+		{
+			name:   "country-only lookuper",
+			lookup: countryOnly,
+			ip:     "8.8.8.8",
+			want:   LookupResult{IPStatus: IPStatusGeolocated, GeoInfo: GeoInfo{Country: Country{ISOCode: "US"}}},
+		},
+		{
+			name:    "invalid IP",
+			lookup:  info,
+			ip:      "not-an-ip",
+			wantErr: ErrInvalidIP,
+		},
+		{
+			name:    "unsupported locale",
+			lookup:  info,
+			ip:      "81.2.69.142",
+			locale:  "xx",
+			wantErr: ErrUnsupportedLocale,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewChecker(tt.lookup).Lookup(context.Background(), tt.ip, tt.locale)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Lookup() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Lookup() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lookup() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeoStore_LookupInfo(t *testing.T) {
+	dbPath := filepath.Join("..", "..", "data", "GeoLite2-Country.mmdb")
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		t.Skipf("GeoLite2-Country.mmdb not found at %s; skip LookupInfo tests", dbPath)
+	}
+	store, err := NewGeoStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewGeoStore: %v", err)
+	}
+	defer store.Close()
+
+	info, err := store.LookupInfo(net.ParseIP("81.2.69.142"), DefaultLocale)
+	if err != nil {
+		t.Fatalf("LookupInfo: %v", err)
+	}
+	if info.Country.ISOCode != "GB" || info.Country.Name != "United Kingdom" {
+		t.Errorf("Country = %+v, want GB / United Kingdom", info.Country)
+	}
+	de, err := store.LookupInfo(net.ParseIP("81.2.69.142"), "de")
+	if err != nil {
+		t.Fatalf("LookupInfo(de): %v", err)
+	}
+	if de.Country.Name == "" || de.Country.Name == info.Country.Name {
+		t.Errorf("German name = %q, want a localized name", de.Country.Name)
+	}
+	if info.Continent.Code != "EU" {
+		t.Errorf("Continent.Code = %q, want EU", info.Continent.Code)
+	}
+	if !info.Network.Addr().Is4() || !info.Network.Contains(netip.MustParseAddr("81.2.69.142")) {
+		t.Errorf("Network = %v, want a prefix containing the IP", info.Network)
+	}
+
+	if _, err := store.LookupInfo(net.ParseIP("192.168.1.1"), DefaultLocale); !errors.Is(err, ErrUnknownIP) {
+		t.Errorf("LookupInfo(private) error = %v, want ErrUnknownIP", err)
+	}
+}
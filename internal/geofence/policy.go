@@ -0,0 +1,253 @@
+package geofence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// ErrUnknownPolicy is returned when a request names a policy that is not configured.
+var ErrUnknownPolicy = errors.New("unknown policy")
+
+// ErrPolicyConflict is returned when a request names a policy and also sends inline rules.
+var ErrPolicyConflict = errors.New("policy and inline country lists are mutually exclusive")
+
+// ErrInvalidNetwork is returned when an override network is neither an IP address
+// nor a CIDR prefix.
+var ErrInvalidNetwork = errors.New("invalid network")
+
+// ErrInvalidUnknownIPAction is returned for an unknown_ip_action other than allow, deny or error.
+var ErrInvalidUnknownIPAction = errors.New("unknown_ip_action must be one of allow, deny, error")
+
+// UnknownIPAction decides what happens to an IP that has no country: one missing
+// from the database, or a private, loopback or reserved address.
+type UnknownIPAction string
+
+const (
+	// UnknownIPDeny denies the IP. It is the default when no action is set.
+	UnknownIPDeny UnknownIPAction = "deny"
+	// UnknownIPAllow allows the IP.
+	UnknownIPAllow UnknownIPAction = "allow"
+	// UnknownIPError fails the check with ErrUnknownIP.
+	UnknownIPError UnknownIPAction = "error"
+)
+
+// validate reports an error for values other than the defined actions or empty.
+func (a UnknownIPAction) validate() error {
+	switch a {
+	case "", UnknownIPDeny, UnknownIPAllow, UnknownIPError:
+		return nil
+	default:
+		return fmt.Errorf("%w: got %q", ErrInvalidUnknownIPAction, string(a))
+	}
+}
+
+// AllCountries is the wildcard entry matching every country in AllowedCountries or
+// DeniedCountries.
+const AllCountries = "*"
+
+// Policy is a set of geo-fencing rules. Named policies are loaded from a config file;
+// inline policies are built from the fields of a check request.
+//
+// When both lists are given, an explicit country code takes precedence over the
+// AllCountries wildcard, and a country listed explicitly in both lists is denied.
+// A deny-only policy is written as AllowedCountries ["*"] plus the denied codes.
+//
+// AllowedRegions and DeniedRegions name groups of countries, such as continents or
+// "EU27", configured with WithGroups. Each member counts as if its code were listed
+// explicitly in the matching country list.
+//
+// AllowedNetworks and DeniedNetworks are IP or CIDR overrides evaluated before the
+// country lookup. The longest matching prefix decides; if the same prefix is in both
+// lists, deny wins.
+//
+// DeniedAnonymity names Anonymity flags (see AnonymityNames) that deny an IP the
+// country rules would allow, such as "vpn" or "tor". It needs a Checker created
+// with WithAnonymity; network overrides still take precedence.
+//
+// UnknownIPAction applies to IPs without a country; empty means UnknownIPDeny.
+type Policy struct {
+	AllowedCountries []string        `json:"allowed_countries"`
+	DeniedCountries  []string        `json:"denied_countries,omitempty"`
+	AllowedNetworks  []string        `json:"allowed_networks,omitempty"`
+	DeniedNetworks   []string        `json:"denied_networks,omitempty"`
+	UnknownIPAction  UnknownIPAction `json:"unknown_ip_action,omitempty"`
+	AllowedRegions   []string        `json:"allowed_regions,omitempty"`
+	DeniedRegions    []string        `json:"denied_regions,omitempty"`
+	DeniedAnonymity  []string        `json:"denied_anonymity,omitempty"`
+
+	// Built by Checker.Prepare.
+	allowed, denied               countrySet
+	deniedAnonymity               Anonymity
+	overrides                     *prefixTrie
+	allowedRegions, deniedRegions *regionIndex
+	prepared                      bool
+}
+
+// isZero reports whether no country or network rules are set. UnknownIPAction is
+// not a rule on its own: a request may combine it with a named policy.
+func (p Policy) isZero() bool {
+	return len(p.AllowedCountries) == 0 && len(p.DeniedCountries) == 0 &&
+		len(p.AllowedNetworks) == 0 && len(p.DeniedNetworks) == 0 &&
+		len(p.AllowedRegions) == 0 && len(p.DeniedRegions) == 0 &&
+		len(p.DeniedAnonymity) == 0
+}
+
+// allowsNothing reports whether the policy has no allowed countries or regions.
+func (p Policy) allowsNothing() bool {
+	return len(p.AllowedCountries) == 0 && len(p.AllowedRegions) == 0
+}
+
+// hasOverrides reports whether the policy has any network overrides.
+func (p Policy) hasOverrides() bool {
+	return len(p.AllowedNetworks) > 0 || len(p.DeniedNetworks) > 0
+}
+
+// compile parses the network overrides into a prefix trie so they can be matched
+// without re-parsing on every check.
+func (p Policy) compile() (Policy, error) {
+	if !p.hasOverrides() {
+		return p, nil
+	}
+	trie := &prefixTrie{}
+	for _, list := range []struct {
+		networks []string
+		allow    bool
+	}{{p.AllowedNetworks, true}, {p.DeniedNetworks, false}} {
+		for _, network := range list.networks {
+			prefix, err := parseNetwork(network)
+			if err != nil {
+				return Policy{}, err
+			}
+			trie.insert(prefix, list.allow)
+		}
+	}
+	p.overrides = trie
+	return p, nil
+}
+
+// Compile validates the policy and parses its network overrides ahead of time, for
+// policies that are built once and checked many times. The policy must allow at
+// least one country (or the AllCountries wildcard) or region, countries must be
+// ISO 3166-1 alpha-2 codes, and anonymity flags must be known. Regions are expanded
+// later, by the Checker the policy is used with; Checker.Prepare does both.
+func (p Policy) Compile() (Policy, error) {
+	if p.allowsNothing() {
+		return Policy{}, ErrEmptyAllowedCountries
+	}
+	if err := p.UnknownIPAction.validate(); err != nil {
+		return Policy{}, err
+	}
+	if err := p.compileCountries(false); err != nil {
+		return Policy{}, err
+	}
+	if err := p.compileAnonymity(); err != nil {
+		return Policy{}, err
+	}
+	return p.compile()
+}
+
+// parseNetwork accepts a CIDR prefix or a bare IP address (treated as a host route).
+// IPv4-mapped IPv6 prefixes such as ::ffff:10.0.0.0/104 are converted to their IPv4
+// form, since lookups unmap addresses before matching.
+func parseNetwork(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("%w: %s", ErrInvalidNetwork, s)
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		return prefix, nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("%w: %s", ErrInvalidNetwork, s)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// decide reports whether the policy admits country, why, and the list entry that
+// matched. Precedence, highest first: explicit deny, explicit allow, wildcard deny,
+// wildcard allow, where a region counts as explicit and an explicit code is reported
+// in preference to its region. Anything else is denied as not in the list. The
+// policy must have been prepared.
+func (p Policy) decide(country string) (allowed bool, reason Reason, rule string) {
+	if i, ok := countryIndex(country); ok {
+		if p.denied.has(i) {
+			return false, ReasonCountryDenied, country
+		}
+		if region, ok := p.deniedRegions.find(i); ok {
+			return false, ReasonCountryDenied, region
+		}
+		if p.allowed.has(i) {
+			return true, ReasonCountryAllowed, country
+		}
+		if region, ok := p.allowedRegions.find(i); ok {
+			return true, ReasonCountryAllowed, region
+		}
+	}
+	switch {
+	case p.denied.all:
+		return false, ReasonCountryDenied, AllCountries
+	case p.allowed.all:
+		return true, ReasonCountryAllowed, AllCountries
+	default:
+		return false, ReasonCountryNotInList, ""
+	}
+}
+
+// PolicyRef selects the rules a check is evaluated against: either a named
+// server-side policy or an inline policy, but not both. Inline.UnknownIPAction may
+// be set alongside Name to override the named policy's action for one request.
+type PolicyRef struct {
+	Name   string
+	Inline Policy
+}
+
+// IsZero reports whether the reference selects no policy or rules.
+func (r PolicyRef) IsZero() bool {
+	return r.Name == "" && r.Inline.isZero()
+}
+
+// policyFile is the on-disk format read by LoadPolicies.
+type policyFile struct {
+	Policies map[string]Policy `json:"policies"`
+}
+
+// LoadPolicies reads named policies from a JSON file of the form
+//
+//	{"policies": {
+//	  "voice-signup": {"allowed_countries": ["US", "CA", "GB"]},
+//	  "not-sanctioned": {"allowed_countries": ["*"], "denied_countries": ["IR", "KP"]},
+//	  "partners": {"allowed_countries": ["US"], "allowed_networks": ["203.0.113.0/24"]}
+//	}}
+//
+// Every policy must allow at least one country (or the AllCountries wildcard) or
+// region.
+func LoadPolicies(path string) (map[string]Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read policies: %w", err)
+	}
+	var file policyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse policies: %w", err)
+	}
+	for name, policy := range file.Policies {
+		if name == "" {
+			return nil, fmt.Errorf("parse policies: policy name must not be empty")
+		}
+		compiled, err := policy.Compile()
+		if err != nil {
+			return nil, fmt.Errorf("policy %q: %w", name, err)
+		}
+		file.Policies[name] = compiled
+	}
+	return file.Policies, nil
+}
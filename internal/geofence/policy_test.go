@@ -0,0 +1,144 @@
+package geofence
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadPolicies(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantNames []string
+		wantErr   bool
+	}{
+		{
+			name:      "valid file",
+			content:   `{"policies":{"voice-signup":{"allowed_countries":["US","CA","GB"]},"uk-only":{"allowed_countries":["GB"]}}}`,
+			wantNames: []string{"voice-signup", "uk-only"},
+		},
+		{
+			name:      "regions only",
+			content:   `{"policies":{"eea-only":{"allowed_regions":["EEA"]}}}`,
+			wantNames: []string{"eea-only"},
+		},
+		{
+			name:    "malformed JSON",
+			content: `{"policies":`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid override network",
+			content: `{"policies":{"bad":{"allowed_countries":["US"],"allowed_networks":["203.0.113.0/33"]}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid unknown_ip_action",
+			content: `{"policies":{"bad":{"allowed_countries":["US"],"unknown_ip_action":"maybe"}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "policy without countries",
+			content: `{"policies":{"empty":{"allowed_countries":[]}}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policies.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("write policies: %v", err)
+			}
+			policies, err := LoadPolicies(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, name := range tt.wantNames {
+				if _, ok := policies[name]; !ok {
+					t.Errorf("policy %q not loaded", name)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadPolicies_MissingFile(t *testing.T) {
+	if _, err := LoadPolicies(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Fatal("expected error for missing file, got nil")
+	}
+}
+
+func TestChecker_Evaluate(t *testing.T) {
+	lookup := mockLookuper{lookup: func(net.IP) (string, error) { return "US", nil }}
+	checker := NewChecker(lookup, WithPolicies(map[string]Policy{
+		"voice-signup": {AllowedCountries: []string{"US", "CA", "GB"}},
+		"uk-only":      {AllowedCountries: []string{"GB"}},
+	}))
+
+	tests := []struct {
+		name        string
+		ref         PolicyRef
+		wantAllowed bool
+		wantErr     error
+	}{
+		{
+			name:        "named policy allows",
+			ref:         PolicyRef{Name: "voice-signup"},
+			wantAllowed: true,
+		},
+		{
+			name:        "named policy blocks",
+			ref:         PolicyRef{Name: "uk-only"},
+			wantAllowed: false,
+		},
+		{
+			name:        "inline policy",
+			ref:         PolicyRef{Inline: Policy{AllowedCountries: []string{"us"}}},
+			wantAllowed: true,
+		},
+		{
+			name:    "unknown policy",
+			ref:     PolicyRef{Name: "nope"},
+			wantErr: ErrUnknownPolicy,
+		},
+		{
+			name:    "policy and inline list",
+			ref:     PolicyRef{Name: "uk-only", Inline: Policy{AllowedCountries: []string{"US"}}},
+			wantErr: ErrPolicyConflict,
+		},
+		{
+			name:    "neither policy nor inline list",
+			ref:     PolicyRef{},
+			wantErr: ErrEmptyAllowedCountries,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := checker.Evaluate(context.Background(), "8.8.8.8", tt.ref)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Allowed != tt.wantAllowed {
+				t.Errorf("Allowed = %v, want %v", result.Allowed, tt.wantAllowed)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package geofence
+
+import "net/netip"
+
+// networkRule is an allow or deny override attached to a network prefix.
+type networkRule struct {
+	prefix netip.Prefix
+	allow  bool
+}
+
+// prefixTrie is a binary radix trie over netip.Prefix supporting longest-prefix
+// match. IPv4 and IPv6 prefixes live in separate trees. The zero value is empty and
+// ready to use; a built trie is safe for concurrent reads.
+type prefixTrie struct {
+	root4 *trieNode
+	root6 *trieNode
+	size  int
+}
+
+type trieNode struct {
+	child [2]*trieNode
+	rule  *networkRule
+}
+
+// insert adds a rule for prefix. If the same prefix is inserted twice with different
+// actions, deny wins.
+func (t *prefixTrie) insert(prefix netip.Prefix, allow bool) {
+	prefix = prefix.Masked()
+	addr := prefix.Addr()
+	root := &t.root6
+	if addr.Is4() {
+		root = &t.root4
+	}
+	if *root == nil {
+		*root = &trieNode{}
+	}
+	node := *root
+	bytes := addr.As16()
+	offset := 0
+	if addr.Is4() {
+		offset = 96
+	}
+	for i := 0; i < prefix.Bits(); i++ {
+		bit := addrBit(bytes, offset+i)
+		if node.child[bit] == nil {
+			node.child[bit] = &trieNode{}
+		}
+		node = node.child[bit]
+	}
+	if node.rule == nil {
+		t.size++
+		node.rule = &networkRule{prefix: prefix, allow: allow}
+		return
+	}
+	node.rule.allow = node.rule.allow && allow
+}
+
+// match returns the rule for the longest prefix containing addr.
+func (t *prefixTrie) match(addr netip.Addr) (networkRule, bool) {
+	addr = addr.Unmap()
+	node := t.root6
+	bits, offset := 128, 0
+	if addr.Is4() {
+		node = t.root4
+		bits, offset = 32, 96
+	}
+	bytes := addr.As16()
+
+	var best *networkRule
+	for i := 0; node != nil; i++ {
+		if node.rule != nil {
+			best = node.rule
+		}
+		if i == bits {
+			break
+		}
+		node = node.child[addrBit(bytes, offset+i)]
+	}
+	if best == nil {
+		return networkRule{}, false
+	}
+	return *best, true
+}
+
+// len returns the number of distinct prefixes in the trie.
+func (t *prefixTrie) len() int {
+	return t.size
+}
+
+// addrBit returns bit i (0 = most significant) of a 16-byte address.
+func addrBit(b [16]byte, i int) int {
+	return int(b[i/8]>>(7-uint(i%8))) & 1
+}
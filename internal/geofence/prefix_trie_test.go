@@ -0,0 +1,58 @@
+package geofence
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestPrefixTrie_Match(t *testing.T) {
+	var trie prefixTrie
+	trie.insert(netip.MustParsePrefix("10.0.0.0/8"), false)
+	trie.insert(netip.MustParsePrefix("10.1.0.0/16"), true)
+	trie.insert(netip.MustParsePrefix("10.1.2.3/32"), false)
+	trie.insert(netip.MustParsePrefix("2001:db8::/32"), true)
+	trie.insert(netip.MustParsePrefix("198.51.100.7/24"), true) // host bits are masked
+	trie.insert(netip.MustParsePrefix("198.51.100.0/24"), false)
+
+	tests := []struct {
+		addr       string
+		wantOK     bool
+		wantPrefix string
+		wantAllow  bool
+	}{
+		{addr: "10.9.9.9", wantOK: true, wantPrefix: "10.0.0.0/8", wantAllow: false},
+		{addr: "10.1.9.9", wantOK: true, wantPrefix: "10.1.0.0/16", wantAllow: true},
+		{addr: "10.1.2.3", wantOK: true, wantPrefix: "10.1.2.3/32", wantAllow: false},
+		{addr: "::ffff:10.1.9.9", wantOK: true, wantPrefix: "10.1.0.0/16", wantAllow: true},
+		{addr: "2001:db8::1", wantOK: true, wantPrefix: "2001:db8::/32", wantAllow: true},
+		{addr: "198.51.100.1", wantOK: true, wantPrefix: "198.51.100.0/24", wantAllow: false},
+		{addr: "11.0.0.1", wantOK: false},
+		{addr: "2001:db9::1", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			rule, ok := trie.match(netip.MustParseAddr(tt.addr))
+			if ok != tt.wantOK {
+				t.Fatalf("match ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if rule.prefix.String() != tt.wantPrefix || rule.allow != tt.wantAllow {
+				t.Errorf("match = {%s %v}, want {%s %v}", rule.prefix, rule.allow, tt.wantPrefix, tt.wantAllow)
+			}
+		})
+	}
+
+	if got := trie.len(); got != 5 {
+		t.Errorf("len = %d, want 5", got)
+	}
+}
+
+func TestPrefixTrie_Empty(t *testing.T) {
+	var trie prefixTrie
+	if _, ok := trie.match(netip.MustParseAddr("8.8.8.8")); ok {
+		t.Error("empty trie matched")
+	}
+}
@@ -0,0 +1,130 @@
+package geofence
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/netip"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/oschwald/geoip2-golang/v2"
+)
+
+// ErrUnknownProvider is returned for a GeoIP provider that has no adapter.
+var ErrUnknownProvider = errors.New("unknown geoip provider")
+
+// Providers supported by GeoStore, selected with WithProvider.
+const (
+	// ProviderMaxMind reads MaxMind GeoIP2 and GeoLite2 Country or City MMDB files.
+	// It is the default.
+	ProviderMaxMind = "maxmind"
+	// ProviderDBIP reads DB-IP's MMDB downloads, which use MaxMind's schema. DB-IP's
+	// CSV downloads are read with ProviderCSV.
+	ProviderDBIP = "dbip"
+	// ProviderIPinfo reads IPinfo MMDB files, in either the Lite schema
+	// (country_code, country) or the older country schema (country, country_name).
+	ProviderIPinfo = "ipinfo"
+	// ProviderCSV reads a plain CSV file of IP ranges; see openCSV for the format.
+	ProviderCSV = "csv"
+)
+
+// Database is one loaded GeoIP database file. Each provider has an adapter that
+// translates its format into GeoInfo. GeoStore serializes Close against lookups,
+// so adapters need only support concurrent lookups.
+type Database interface {
+	// Lookup returns the record for addr, which is never an IPv4-mapped IPv6
+	// address. found is false when the database has no data for addr. The
+	// record's Network is set in either case when the format records it. Names
+	// are in locale, falling back to English, where the format carries them.
+	Lookup(addr netip.Addr, locale string) (info GeoInfo, found bool, err error)
+	// BuildTime returns when the database was built, or the zero time if unknown.
+	BuildTime() time.Time
+	Close() error
+}
+
+// providers maps provider names to the function that opens their files.
+var providers = map[string]func(path string) (Database, error){
+	ProviderMaxMind: openGeoIP2,
+	ProviderDBIP:    openGeoIP2,
+	ProviderIPinfo:  openIPinfo,
+	ProviderCSV:     openCSV,
+}
+
+// Providers returns the supported provider names in sorted order.
+func Providers() []string {
+	return slices.Sorted(maps.Keys(providers))
+}
+
+// openDatabase opens path with provider's adapter and checks that it can answer a
+// lookup.
+func openDatabase(provider, path string) (Database, error) {
+	open, ok := providers[provider]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q (want one of %v)", ErrUnknownProvider, provider, Providers())
+	}
+	db, err := open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open geoip database: %w", err)
+	}
+	if _, _, err := db.Lookup(probeAddr, DefaultLocale); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("validate geoip database: %w", err)
+	}
+	return db, nil
+}
+
+// geoip2Database adapts a geoip2.Reader.
+type geoip2Database struct {
+	reader *geoip2.Reader
+}
+
+// openGeoIP2 reads the file into memory rather than memory-mapping it so that
+// overwriting it in place cannot corrupt a reader that is still serving lookups.
+func openGeoIP2(path string) (Database, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := geoip2.OpenBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return geoip2Database{reader: reader}, nil
+}
+
+func (d geoip2Database) Lookup(addr netip.Addr, locale string) (GeoInfo, bool, error) {
+	record, err := d.reader.Country(addr)
+	if err != nil {
+		return GeoInfo{}, false, err
+	}
+	if !record.HasData() {
+		return GeoInfo{Network: record.Traits.Network}, false, nil
+	}
+	return GeoInfo{
+		Country:           countryInfo(record.Country, locale),
+		RegisteredCountry: countryInfo(record.RegisteredCountry, locale),
+		RepresentedCountry: RepresentedCountry{
+			Country: Country{
+				ISOCode:           record.RepresentedCountry.ISOCode,
+				Name:              localName(record.RepresentedCountry.Names, locale),
+				IsInEuropeanUnion: record.RepresentedCountry.IsInEuropeanUnion,
+			},
+			Type: record.RepresentedCountry.Type,
+		},
+		Continent: Continent{
+			Code: record.Continent.Code,
+			Name: localName(record.Continent.Names, locale),
+		},
+		Network: record.Traits.Network,
+	}, true, nil
+}
+
+func (d geoip2Database) BuildTime() time.Time {
+	return time.Unix(int64(d.reader.Metadata().BuildEpoch), 0)
+}
+
+func (d geoip2Database) Close() error {
+	return d.reader.Close()
+}
@@ -0,0 +1,242 @@
+package geofence
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oschwald/geoip2-golang/v2"
+)
+
+// anonymityProviders maps provider names to the function that opens their
+// anonymous IP files. asnPath is the WithASNDatabase path, which may be empty.
+var anonymityProviders = map[string]func(path, asnPath string) (Database, error){
+	ProviderMaxMind: openGeoIP2Anonymous,
+	ProviderCSV:     openAnonymityCSV,
+}
+
+// AnonymityProviders returns the providers NewAnonymityStore supports, sorted.
+func AnonymityProviders() []string {
+	return slices.Sorted(maps.Keys(anonymityProviders))
+}
+
+// openAnonymityDatabase is openDatabase for anonymous IP files.
+func openAnonymityDatabase(provider, path, asnPath string) (Database, error) {
+	open, ok := anonymityProviders[provider]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q for anonymity (want one of %v)", ErrUnknownProvider, provider, AnonymityProviders())
+	}
+	db, err := open(path, asnPath)
+	if err != nil {
+		return nil, fmt.Errorf("open anonymity database: %w", err)
+	}
+	if _, _, err := db.Lookup(probeAddr, DefaultLocale); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("validate anonymity database: %w", err)
+	}
+	return db, nil
+}
+
+// geoip2AnonymousDatabase adapts a GeoIP2-Anonymous-IP reader. It only reports
+// Anonymity and Network.
+type geoip2AnonymousDatabase struct {
+	reader *geoip2.Reader
+}
+
+func openGeoIP2Anonymous(path, _ string) (Database, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := geoip2.OpenBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return geoip2AnonymousDatabase{reader: reader}, nil
+}
+
+func (d geoip2AnonymousDatabase) Lookup(addr netip.Addr, _ string) (GeoInfo, bool, error) {
+	record, err := d.reader.AnonymousIP(addr)
+	if err != nil {
+		return GeoInfo{}, false, err
+	}
+	info := GeoInfo{Network: record.Network}
+	for _, f := range []struct {
+		set  bool
+		flag Anonymity
+	}{
+		{record.IsAnonymous, AnonymityAnonymous},
+		{record.IsAnonymousVPN, AnonymityVPN},
+		{record.IsHostingProvider, AnonymityHosting},
+		{record.IsPublicProxy, AnonymityPublicProxy},
+		{record.IsResidentialProxy, AnonymityResidentialProxy},
+		{record.IsTorExitNode, AnonymityTor},
+	} {
+		if f.set {
+			info.Anonymity |= f.flag
+		}
+	}
+	return info, info.Anonymity != 0, nil
+}
+
+func (d geoip2AnonymousDatabase) BuildTime() time.Time {
+	return time.Unix(int64(d.reader.Metadata().BuildEpoch), 0)
+}
+
+func (d geoip2AnonymousDatabase) Close() error {
+	return d.reader.Close()
+}
+
+// anonymityList is a local list of anonymizing networks and AS numbers.
+type anonymityList struct {
+	networks map[netip.Prefix]Anonymity
+	// lengths counts listed networks per prefix length for IPv4 and IPv6, so a
+	// lookup only probes the lengths that are listed.
+	lengths   [2][129]int
+	asns      map[uint]Anonymity
+	asn       *geoip2.Reader // nil when no AS numbers are listed
+	buildTime time.Time
+}
+
+// openAnonymityCSV reads a list of anonymizing networks, one per row: an IP, CIDR
+// prefix or AS number ("AS16509") followed by one or more flag names from
+// AnonymityNames, e.g.
+//
+//	185.220.101.1,tor
+//	203.0.113.0/24,vpn
+//	AS16509,hosting
+//
+// Lines starting with # are comments. The vpn, public_proxy, residential_proxy and
+// tor flags imply anonymous. An IP matching several rows gets all of their flags.
+// AS numbers need an ASN database (see WithASNDatabase). The newer modification
+// time of the two files is reported as the build time.
+func openAnonymityCSV(path, asnPath string) (Database, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	list := &anonymityList{
+		networks:  make(map[netip.Prefix]Anonymity),
+		asns:      make(map[uint]Anonymity),
+		buildTime: stat.ModTime(),
+	}
+	for {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read csv: %w", err)
+		}
+		line, _ := r.FieldPos(0)
+		if err := list.add(row); err != nil {
+			return nil, fmt.Errorf("csv line %d: %w", line, err)
+		}
+	}
+
+	if len(list.asns) > 0 {
+		if asnPath == "" {
+			return nil, fmt.Errorf("%s lists AS numbers but no ASN database is configured", path)
+		}
+		data, err := os.ReadFile(asnPath)
+		if err != nil {
+			return nil, err
+		}
+		if list.asn, err = geoip2.OpenBytes(data); err != nil {
+			return nil, fmt.Errorf("open ASN database: %w", err)
+		}
+		if built := time.Unix(int64(list.asn.Metadata().BuildEpoch), 0); built.After(list.buildTime) {
+			list.buildTime = built
+		}
+	}
+	return list, nil
+}
+
+func (l *anonymityList) add(row []string) error {
+	if len(row) < 2 {
+		return fmt.Errorf("want network or AS number followed by flags; got %d columns", len(row))
+	}
+	flags, err := ParseAnonymity(row[1:])
+	if err != nil {
+		return err
+	}
+	if flags&(AnonymityVPN|AnonymityPublicProxy|AnonymityResidentialProxy|AnonymityTor) != 0 {
+		flags |= AnonymityAnonymous
+	}
+
+	entry := strings.TrimSpace(row[0])
+	if rest, ok := strings.CutPrefix(strings.ToUpper(entry), "AS"); ok {
+		asn, err := strconv.ParseUint(rest, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid AS number: %s", entry)
+		}
+		l.asns[uint(asn)] |= flags
+		return nil
+	}
+	prefix, err := parseNetwork(entry)
+	if err != nil {
+		return err
+	}
+	prefix = prefix.Masked()
+	if _, ok := l.networks[prefix]; !ok {
+		l.lengths[familyIndex(prefix.Addr())][prefix.Bits()]++
+	}
+	l.networks[prefix] |= flags
+	return nil
+}
+
+// Lookup returns the union of the flags of every listed network containing addr
+// and of its AS number. The network reported is the longest one matched.
+func (l *anonymityList) Lookup(addr netip.Addr, _ string) (GeoInfo, bool, error) {
+	var info GeoInfo
+	family := familyIndex(addr)
+	for bits := addr.BitLen(); bits >= 0; bits-- {
+		if l.lengths[family][bits] == 0 {
+			continue
+		}
+		prefix, _ := addr.Prefix(bits)
+		if flags, ok := l.networks[prefix]; ok {
+			info.Anonymity |= flags
+			if !info.Network.IsValid() {
+				info.Network = prefix
+			}
+		}
+	}
+	if l.asn != nil {
+		record, err := l.asn.ASN(addr)
+		if err != nil {
+			return GeoInfo{}, false, err
+		}
+		info.Anonymity |= l.asns[record.AutonomousSystemNumber]
+	}
+	return info, info.Anonymity != 0, nil
+}
+
+func (l *anonymityList) BuildTime() time.Time {
+	return l.buildTime
+}
+
+func (l *anonymityList) Close() error {
+	if l.asn != nil {
+		return l.asn.Close()
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package geofence
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// csvRange is one row of a CSV database: every address from start to end, in one
+// family, is in country. An empty country marks a range as having no country.
+type csvRange struct {
+	start, end netip.Addr
+	country    string
+}
+
+// csvDatabase is an in-memory table of sorted, non-overlapping IP ranges.
+type csvDatabase struct {
+	ranges    []csvRange
+	buildTime time.Time
+}
+
+// openCSV reads a CSV file of IP ranges, one per row, in either of two forms:
+//
+//	start_ip,end_ip,country   e.g. 1.0.0.0,1.0.0.255,AU
+//	network,country           e.g. 2001:db8::/32,DE
+//
+// This covers the DB-IP and IPinfo CSV downloads; columns after the country are
+// ignored. Countries are ISO 3166-1 alpha-2 codes, with "ZZ", "-" or an empty
+// column for ranges that have no country. Lines starting with # are comments, and
+// a header row is skipped. Ranges must not overlap. The file's modification time is
+// reported as the build time.
+func openCSV(path string) (Database, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	db := &csvDatabase{buildTime: stat.ModTime()}
+	for first := true; ; first = false {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read csv: %w", err)
+		}
+		line, _ := r.FieldPos(0)
+		rng, err := parseCSVRange(row)
+		if err != nil {
+			if first {
+				continue // header
+			}
+			return nil, fmt.Errorf("csv line %d: %w", line, err)
+		}
+		db.ranges = append(db.ranges, rng)
+	}
+
+	slices.SortFunc(db.ranges, func(a, b csvRange) int { return a.start.Compare(b.start) })
+	for i := 1; i < len(db.ranges); i++ {
+		if prev := db.ranges[i-1]; db.ranges[i].start.Compare(prev.end) <= 0 {
+			return nil, fmt.Errorf("csv: range starting at %s overlaps %s-%s", db.ranges[i].start, prev.start, prev.end)
+		}
+	}
+	return db, nil
+}
+
+func parseCSVRange(row []string) (csvRange, error) {
+	if len(row) < 2 {
+		return csvRange{}, fmt.Errorf("want start_ip,end_ip,country or network,country; got %d columns", len(row))
+	}
+	var rng csvRange
+	var country string
+	if end, err := netip.ParseAddr(strings.TrimSpace(row[1])); err == nil && len(row) >= 3 {
+		start, err := netip.ParseAddr(strings.TrimSpace(row[0]))
+		if err != nil {
+			return csvRange{}, fmt.Errorf("%w: %s", ErrInvalidNetwork, row[0])
+		}
+		rng.start, rng.end = start.Unmap(), end.Unmap()
+		if rng.start.Is4() != rng.end.Is4() || rng.end.Less(rng.start) {
+			return csvRange{}, fmt.Errorf("%w: %s-%s", ErrInvalidNetwork, row[0], row[1])
+		}
+		country = row[2]
+	} else {
+		prefix, err := parseNetwork(strings.TrimSpace(row[0]))
+		if err != nil {
+			return csvRange{}, err
+		}
+		prefix = prefix.Masked()
+		rng.start, rng.end = prefix.Addr(), lastAddr(prefix)
+		country = row[1]
+	}
+
+	switch country = strings.ToUpper(strings.TrimSpace(country)); country {
+	case "", "-", "ZZ":
+		rng.country = ""
+	default:
+		if !isCountryCode(country) {
+			return csvRange{}, fmt.Errorf("%w: %q", ErrInvalidCountryCode, country)
+		}
+		rng.country = country
+	}
+	return rng, nil
+}
+
+// Lookup finds the range containing addr. The reported network is the largest
+// prefix containing addr that lies within the range, or within the gap between
+// ranges when addr is not in the file.
+func (d *csvDatabase) Lookup(addr netip.Addr, _ string) (GeoInfo, bool, error) {
+	// i is the first range starting after addr.
+	i, _ := slices.BinarySearchFunc(d.ranges, addr, func(r csvRange, addr netip.Addr) int {
+		if r.start.Compare(addr) <= 0 {
+			return -1
+		}
+		return 1
+	})
+
+	lo, hi := familyBounds(addr)
+	if i > 0 {
+		prev := d.ranges[i-1]
+		if prev.end.Compare(addr) >= 0 {
+			info := GeoInfo{Network: rangePrefix(addr, prev.start, prev.end)}
+			if prev.country == "" {
+				return info, false, nil
+			}
+			info.Country = Country{ISOCode: prev.country, Name: countryName(prev.country)}
+			return info, true, nil
+		}
+		if prev.end.Is4() == addr.Is4() {
+			lo = prev.end.Next()
+		}
+	}
+	if i < len(d.ranges) && d.ranges[i].start.Is4() == addr.Is4() {
+		hi = d.ranges[i].start.Prev()
+	}
+	return GeoInfo{Network: rangePrefix(addr, lo, hi)}, false, nil
+}
+
+func (d *csvDatabase) BuildTime() time.Time {
+	return d.buildTime
+}
+
+func (d *csvDatabase) Close() error {
+	return nil
+}
+
+// familyBounds returns the lowest and highest address in addr's family.
+func familyBounds(addr netip.Addr) (netip.Addr, netip.Addr) {
+	if addr.Is4() {
+		return netip.IPv4Unspecified(), netip.AddrFrom4([4]byte{255, 255, 255, 255})
+	}
+	return netip.IPv6Unspecified(), lastAddr(netip.PrefixFrom(netip.IPv6Unspecified(), 0))
+}
+
+// rangePrefix returns the largest prefix containing addr that lies within lo-hi.
+func rangePrefix(addr, lo, hi netip.Addr) netip.Prefix {
+	for bits := 0; bits < addr.BitLen(); bits++ {
+		prefix, _ := addr.Prefix(bits)
+		if prefix.Addr().Compare(lo) >= 0 && lastAddr(prefix).Compare(hi) <= 0 {
+			return prefix
+		}
+	}
+	return netip.PrefixFrom(addr, addr.BitLen())
+}
+
+// lastAddr returns the highest address in prefix.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	b := prefix.Masked().Addr().AsSlice()
+	for i := prefix.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 0x80 >> (i % 8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
@@ -0,0 +1,71 @@
+package geofence
+
+import (
+	"net/netip"
+	"os"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+)
+
+// ipinfoRecord holds the fields of both IPinfo MMDB schemas. The Lite schema has
+// country_code and continent_code with the names in country and continent; the
+// older country schema has the codes in country and continent and the names in
+// country_name and continent_name.
+type ipinfoRecord struct {
+	CountryCode   string `maxminddb:"country_code"`
+	Country       string `maxminddb:"country"`
+	CountryName   string `maxminddb:"country_name"`
+	ContinentCode string `maxminddb:"continent_code"`
+	Continent     string `maxminddb:"continent"`
+	ContinentName string `maxminddb:"continent_name"`
+}
+
+// ipinfoDatabase adapts an IPinfo MMDB file. IPinfo only carries English names.
+type ipinfoDatabase struct {
+	reader *maxminddb.Reader
+}
+
+func openIPinfo(path string) (Database, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := maxminddb.OpenBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return ipinfoDatabase{reader: reader}, nil
+}
+
+func (d ipinfoDatabase) Lookup(addr netip.Addr, _ string) (GeoInfo, bool, error) {
+	result := d.reader.Lookup(addr)
+	if err := result.Err(); err != nil {
+		return GeoInfo{}, false, err
+	}
+	info := GeoInfo{Network: result.Prefix()}
+	if !result.Found() {
+		return info, false, nil
+	}
+	var record ipinfoRecord
+	if err := result.Decode(&record); err != nil {
+		return GeoInfo{}, false, err
+	}
+
+	if record.CountryCode != "" {
+		info.Country = Country{ISOCode: record.CountryCode, Name: record.Country}
+		info.Continent = Continent{Code: record.ContinentCode, Name: record.Continent}
+	} else {
+		info.Country = Country{ISOCode: record.Country, Name: record.CountryName}
+		info.Continent = Continent{Code: record.Continent, Name: record.ContinentName}
+	}
+	return info, info.Country.ISOCode != "", nil
+}
+
+func (d ipinfoDatabase) BuildTime() time.Time {
+	return d.reader.Metadata.BuildTime()
+}
+
+func (d ipinfoDatabase) Close() error {
+	return d.reader.Close()
+}
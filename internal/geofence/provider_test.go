@@ -0,0 +1,295 @@
+package geofence
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maxmind/mmdbwriter"
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+)
+
+// writeMMDB builds a database of dbType with one record per network and returns
+// its path.
+func writeMMDB(t *testing.T, dbType string, records map[string]mmdbtype.Map) string {
+	t.Helper()
+	tree, err := mmdbwriter.New(mmdbwriter.Options{DatabaseType: dbType, BuildEpoch: 1_700_000_000, RecordSize: 24})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for network, record := range records {
+		_, ipNet, err := net.ParseCIDR(network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tree.Insert(ipNet, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := tree.WriteTo(f); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// geoip2Record is a record in MaxMind's schema, which DB-IP also uses.
+func geoip2Record(country, name, continent string) mmdbtype.Map {
+	return mmdbtype.Map{
+		"country": mmdbtype.Map{
+			"iso_code": mmdbtype.String(country),
+			"names":    mmdbtype.Map{"en": mmdbtype.String(name)},
+		},
+		"continent": mmdbtype.Map{"code": mmdbtype.String(continent)},
+	}
+}
+
+func TestGeoStore_Providers(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		path     func(t *testing.T) string
+	}{
+		{
+			name:     "maxmind",
+			provider: ProviderMaxMind,
+			path: func(t *testing.T) string {
+				return writeMMDB(t, "GeoLite2-Country", map[string]mmdbtype.Map{"81.2.69.0/24": geoip2Record("GB", "United Kingdom", "EU")})
+			},
+		},
+		{
+			name:     "dbip",
+			provider: ProviderDBIP,
+			path: func(t *testing.T) string {
+				return writeMMDB(t, "DBIP-Country-Lite", map[string]mmdbtype.Map{"81.2.69.0/24": geoip2Record("GB", "United Kingdom", "EU")})
+			},
+		},
+		{
+			name:     "ipinfo lite schema",
+			provider: ProviderIPinfo,
+			path: func(t *testing.T) string {
+				return writeMMDB(t, "ipinfo lite.mmdb", map[string]mmdbtype.Map{"81.2.69.0/24": {
+					"country_code":   mmdbtype.String("GB"),
+					"country":        mmdbtype.String("United Kingdom"),
+					"continent_code": mmdbtype.String("EU"),
+					"continent":      mmdbtype.String("Europe"),
+				}})
+			},
+		},
+		{
+			name:     "ipinfo country schema",
+			provider: ProviderIPinfo,
+			path: func(t *testing.T) string {
+				return writeMMDB(t, "ipinfo lite.mmdb", map[string]mmdbtype.Map{"81.2.69.0/24": {
+					"country":        mmdbtype.String("GB"),
+					"country_name":   mmdbtype.String("United Kingdom"),
+					"continent":      mmdbtype.String("EU"),
+					"continent_name": mmdbtype.String("Europe"),
+				}})
+			},
+		},
+		{
+			name:     "csv",
+			provider: ProviderCSV,
+			path: func(t *testing.T) string {
+				return writeFile(t, "ranges.csv", "start_ip,end_ip,country\n81.2.69.0,81.2.69.255,GB\n")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewGeoStore(tt.path(t), WithProvider(tt.provider))
+			if err != nil {
+				t.Fatalf("NewGeoStore() unexpected error: %v", err)
+			}
+			defer store.Close()
+			if store.Provider() != tt.provider {
+				t.Errorf("Provider() = %q, want %q", store.Provider(), tt.provider)
+			}
+
+			country, network, err := store.LookupNetwork(net.ParseIP("81.2.69.142"))
+			if err != nil || country != "GB" || network != netip.MustParsePrefix("81.2.69.0/24") {
+				t.Errorf("LookupNetwork() = %q, %s, %v, want GB, 81.2.69.0/24", country, network, err)
+			}
+			info, err := store.LookupInfo(net.ParseIP("81.2.69.142"), DefaultLocale)
+			if err != nil || info.Country.Name == "" {
+				t.Errorf("LookupInfo() = %+v, %v, want a country name", info, err)
+			}
+			if _, err := store.Lookup(net.ParseIP("8.8.8.8")); !errors.Is(err, ErrUnknownIP) {
+				t.Errorf("Lookup(8.8.8.8) error = %v, want ErrUnknownIP", err)
+			}
+			if store.BuildEpoch().IsZero() {
+				t.Error("BuildEpoch() is zero")
+			}
+		})
+	}
+}
+
+func TestNewGeoStore_UnknownProvider(t *testing.T) {
+	path := writeFile(t, "ranges.csv", "")
+	if _, err := NewGeoStore(path, WithProvider("ip2location")); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("NewGeoStore() error = %v, want ErrUnknownProvider", err)
+	}
+}
+
+func TestOpenCSV(t *testing.T) {
+	db, err := openCSV(writeFile(t, "ranges.csv", strings.Join([]string{
+		"# DB-IP style ranges and CIDR rows",
+		"1.0.0.0,1.0.0.255,AU",
+		"1.0.4.0,1.0.7.255,au,Australia",
+		"10.0.0.0/8,ZZ",
+		"2001:db8::/32,de",
+		"::ffff:5.6.7.0/120,NZ",
+		"",
+	}, "\n")))
+	if err != nil {
+		t.Fatalf("openCSV() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		ip          string
+		wantCountry string
+		wantNetwork string
+	}{
+		{ip: "1.0.0.1", wantCountry: "AU", wantNetwork: "1.0.0.0/24"},
+		{ip: "1.0.5.9", wantCountry: "AU", wantNetwork: "1.0.4.0/22"},
+		{ip: "1.0.1.1", wantNetwork: "1.0.1.0/24"},  // gap between the AU ranges
+		{ip: "1.0.2.1", wantNetwork: "1.0.2.0/23"},  // same gap
+		{ip: "10.1.2.3", wantNetwork: "10.0.0.0/8"}, // ZZ: no country
+		{ip: "0.0.0.1", wantNetwork: "0.0.0.0/8"},   // before the first range
+		{ip: "2001:db8::1", wantCountry: "DE", wantNetwork: "2001:db8::/32"},
+		{ip: "2001:db9::1", wantNetwork: "2001:db9::/32"},
+		{ip: "::1", wantNetwork: "::/3"},
+		{ip: "5.6.7.8", wantCountry: "NZ", wantNetwork: "5.6.7.0/24"}, // IPv4-mapped prefix
+	}
+	for _, tt := range tests {
+		info, found, err := db.Lookup(netip.MustParseAddr(tt.ip), DefaultLocale)
+		if err != nil {
+			t.Fatalf("Lookup(%s) unexpected error: %v", tt.ip, err)
+		}
+		if found != (tt.wantCountry != "") || info.Country.ISOCode != tt.wantCountry || info.Network.String() != tt.wantNetwork {
+			t.Errorf("Lookup(%s) = %q, %s, %v, want %q, %s", tt.ip, info.Country.ISOCode, info.Network, found, tt.wantCountry, tt.wantNetwork)
+		}
+	}
+}
+
+func TestOpenCSV_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "overlap", content: "1.0.0.0,1.0.0.255,AU\n1.0.0.128/25,NZ\n", wantErr: "overlaps"},
+		{name: "bad country", content: "1.0.0.0,1.0.0.255,AU\n1.0.1.0,1.0.1.255,Australia\n", wantErr: "line 2"},
+		{name: "reversed range", content: "1.0.0.0,1.0.0.255,AU\n1.0.1.255,1.0.1.0,AU\n", wantErr: "invalid network"},
+		{name: "mixed families", content: "1.0.0.0,1.0.0.255,AU\n1.0.1.0,::1,AU\n", wantErr: "invalid network"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := openCSV(writeFile(t, "ranges.csv", tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("openCSV() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGeoStore_ReloadKeepsProvider(t *testing.T) {
+	path := writeFile(t, "ranges.csv", "81.2.69.0/24,GB\n")
+	store, err := NewGeoStore(path, WithProvider(ProviderCSV))
+	if err != nil {
+		t.Fatalf("NewGeoStore() unexpected error: %v", err)
+	}
+	defer store.Close()
+
+	if err := os.WriteFile(path, []byte("81.2.69.0/24,IE\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, time.Time{}, time.Unix(1_800_000_000, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Reload(); err != nil {
+		t.Fatalf("Reload() unexpected error: %v", err)
+	}
+	if country, err := store.Lookup(net.ParseIP("81.2.69.1")); err != nil || country != "IE" {
+		t.Errorf("Lookup() after reload = %q, %v, want IE", country, err)
+	}
+	if got := store.BuildEpoch(); !got.Equal(time.Unix(1_800_000_000, 0)) {
+		t.Errorf("BuildEpoch() = %v, want the file's modification time", got)
+	}
+}
+
+// providerLookuper is a mockLookuper that reports a provider.
+type providerLookuper struct {
+	mockLookuper
+	provider string
+}
+
+func (l providerLookuper) Provider() string {
+	return l.provider
+}
+
+func TestChecker_ReportsProvider(t *testing.T) {
+	lookup := providerLookuper{
+		mockLookuper: mockLookuper{lookup: func(ip net.IP) (string, error) {
+			if ip.Equal(net.ParseIP("1.1.1.1")) {
+				return "", ErrUnknownIP
+			}
+			return "US", nil
+		}},
+		provider: ProviderIPinfo,
+	}
+	policy := Policy{AllowedCountries: []string{"US"}, DeniedNetworks: []string{"198.51.100.0/24"}}
+
+	tests := []struct {
+		name    string
+		checker *Checker
+		ip      string
+		want    string
+	}{
+		{name: "geolocated", checker: NewChecker(lookup), ip: "8.8.8.8", want: ProviderIPinfo},
+		{name: "unknown", checker: NewChecker(lookup), ip: "1.1.1.1", want: ProviderIPinfo},
+		{name: "through the cache", checker: NewChecker(NewCachedLookuper(lookup, 10, time.Minute)), ip: "8.8.8.8", want: ProviderIPinfo},
+		{name: "override decided first", checker: NewChecker(lookup), ip: "198.51.100.7"},
+		{name: "private", checker: NewChecker(lookup), ip: "10.0.0.1"},
+		{name: "lookuper without a provider", checker: NewChecker(lookup.mockLookuper), ip: "8.8.8.8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.checker.CheckPolicy(context.Background(), tt.ip, policy)
+			if err != nil {
+				t.Fatalf("CheckPolicy() unexpected error: %v", err)
+			}
+			if result.Provider != tt.want {
+				t.Errorf("Provider = %q, want %q", result.Provider, tt.want)
+			}
+		})
+	}
+
+	result, err := NewChecker(lookup).Lookup(context.Background(), "8.8.8.8", "")
+	if err != nil || result.Provider != ProviderIPinfo {
+		t.Errorf("Lookup() = %+v, %v, want provider %q", result, err, ProviderIPinfo)
+	}
+}
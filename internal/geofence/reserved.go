@@ -0,0 +1,44 @@
+package geofence
+
+import "net/netip"
+
+// reservedNetworks are special-purpose ranges (RFC 6890 and successors) that are
+// never geolocated: private, loopback, link-local, CGNAT, documentation, benchmarking,
+// multicast and reserved space.
+var reservedNetworks = func() *prefixTrie {
+	trie := &prefixTrie{}
+	for _, s := range []string{
+		"0.0.0.0/8",
+		"10.0.0.0/8",
+		"100.64.0.0/10",
+		"127.0.0.0/8",
+		"169.254.0.0/16",
+		"172.16.0.0/12",
+		"192.0.0.0/24",
+		"192.0.2.0/24",
+		"192.168.0.0/16",
+		"198.18.0.0/15",
+		"198.51.100.0/24",
+		"203.0.113.0/24",
+		"224.0.0.0/4",
+		"240.0.0.0/4",
+		"::/128",
+		"::1/128",
+		"64:ff9b:1::/48",
+		"100::/64",
+		"2001:db8::/32",
+		"fc00::/7",
+		"fe80::/10",
+		"ff00::/8",
+	} {
+		trie.insert(netip.MustParsePrefix(s), false)
+	}
+	return trie
+}()
+
+// reservedNetwork returns the private, loopback or otherwise reserved range containing
+// addr, if any. A GeoIP database will not contain these addresses.
+func reservedNetwork(addr netip.Addr) (netip.Prefix, bool) {
+	rule, ok := reservedNetworks.match(addr)
+	return rule.prefix, ok
+}
@@ -0,0 +1,67 @@
+package geofence
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultReloadDebounce is how long Watch waits after the last change event before
+// reloading, so a file that is still being written is not opened half-way through.
+const DefaultReloadDebounce = 2 * time.Second
+
+// Watch monitors the store's database file and calls Reload whenever it is written,
+// created, or renamed into place. It watches the parent directory rather than the
+// file itself so atomic replacements (write to temp file, then rename) are detected.
+// Failed reloads are logged and the previous database keeps serving.
+// Watch blocks until ctx is cancelled.
+func (f *dbFile) Watch(ctx context.Context, debounce time.Duration) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("create watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	dir := filepath.Dir(f.path)
+	if err := watcher.Add(dir); err != nil {
+		return fmt.Errorf("watch %s: %w", dir, err)
+	}
+	target := filepath.Clean(f.path)
+	slog.Info("watching GeoIP database for changes", "path", f.path)
+
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) != target {
+				continue
+			}
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) {
+				continue
+			}
+			slog.Debug("GeoIP database changed", "path", event.Name, "op", event.Op.String())
+			timer.Reset(debounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Error("database watcher error", "err", err)
+		case <-timer.C:
+			if err := f.Reload(); err != nil {
+				slog.Error("database reload failed; keeping previous database", "path", f.path, "err", err)
+			}
+		}
+	}
+}
@@ -21,12 +21,271 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Reason is a structured explanation of a decision.
+type Reason int32
+
+const (
+	Reason_REASON_UNSPECIFIED Reason = 0
+	// The country matched allowed_countries (explicitly or by "*").
+	Reason_REASON_COUNTRY_ALLOWED Reason = 1
+	// The country matched neither list.
+	Reason_REASON_COUNTRY_NOT_IN_LIST Reason = 2
+	// The country matched denied_countries (explicitly or by "*").
+	Reason_REASON_COUNTRY_DENIED Reason = 3
+	// The IP is not in the database; unknown_ip_action decided.
+	Reason_REASON_UNKNOWN_IP Reason = 4
+	// The IP is private, loopback or reserved; unknown_ip_action decided.
+	Reason_REASON_PRIVATE_IP Reason = 5
+	// An allowed or denied network override matched.
+	Reason_REASON_OVERRIDE_RULE Reason = 6
+	// The IP has an anonymity flag listed in denied_anonymity.
+	Reason_REASON_ANONYMOUS_IP Reason = 7
+)
+
+// Enum value maps for Reason.
+var (
+	Reason_name = map[int32]string{
+		0: "REASON_UNSPECIFIED",
+		1: "REASON_COUNTRY_ALLOWED",
+		2: "REASON_COUNTRY_NOT_IN_LIST",
+		3: "REASON_COUNTRY_DENIED",
+		4: "REASON_UNKNOWN_IP",
+		5: "REASON_PRIVATE_IP",
+		6: "REASON_OVERRIDE_RULE",
+		7: "REASON_ANONYMOUS_IP",
+	}
+	Reason_value = map[string]int32{
+		"REASON_UNSPECIFIED":         0,
+		"REASON_COUNTRY_ALLOWED":     1,
+		"REASON_COUNTRY_NOT_IN_LIST": 2,
+		"REASON_COUNTRY_DENIED":      3,
+		"REASON_UNKNOWN_IP":          4,
+		"REASON_PRIVATE_IP":          5,
+		"REASON_OVERRIDE_RULE":       6,
+		"REASON_ANONYMOUS_IP":        7,
+	}
+)
+
+func (x Reason) Enum() *Reason {
+	p := new(Reason)
+	*p = x
+	return p
+}
+
+func (x Reason) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Reason) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_geofence_proto_enumTypes[0].Descriptor()
+}
+
+func (Reason) Type() protoreflect.EnumType {
+	return &file_proto_geofence_proto_enumTypes[0]
+}
+
+func (x Reason) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Reason.Descriptor instead.
+func (Reason) EnumDescriptor() ([]byte, []int) {
+	return file_proto_geofence_proto_rawDescGZIP(), []int{0}
+}
+
+// UnknownIPAction decides what happens to IPs missing from the database and to
+// private, loopback and reserved addresses.
+type UnknownIPAction int32
+
+const (
+	UnknownIPAction_UNKNOWN_IP_ACTION_UNSPECIFIED UnknownIPAction = 0
+	UnknownIPAction_UNKNOWN_IP_ACTION_DENY        UnknownIPAction = 1
+	UnknownIPAction_UNKNOWN_IP_ACTION_ALLOW       UnknownIPAction = 2
+	// Fail the check with NOT_FOUND.
+	UnknownIPAction_UNKNOWN_IP_ACTION_ERROR UnknownIPAction = 3
+)
+
+// Enum value maps for UnknownIPAction.
+var (
+	UnknownIPAction_name = map[int32]string{
+		0: "UNKNOWN_IP_ACTION_UNSPECIFIED",
+		1: "UNKNOWN_IP_ACTION_DENY",
+		2: "UNKNOWN_IP_ACTION_ALLOW",
+		3: "UNKNOWN_IP_ACTION_ERROR",
+	}
+	UnknownIPAction_value = map[string]int32{
+		"UNKNOWN_IP_ACTION_UNSPECIFIED": 0,
+		"UNKNOWN_IP_ACTION_DENY":        1,
+		"UNKNOWN_IP_ACTION_ALLOW":       2,
+		"UNKNOWN_IP_ACTION_ERROR":       3,
+	}
+)
+
+func (x UnknownIPAction) Enum() *UnknownIPAction {
+	p := new(UnknownIPAction)
+	*p = x
+	return p
+}
+
+func (x UnknownIPAction) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (UnknownIPAction) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_geofence_proto_enumTypes[1].Descriptor()
+}
+
+func (UnknownIPAction) Type() protoreflect.EnumType {
+	return &file_proto_geofence_proto_enumTypes[1]
+}
+
+func (x UnknownIPAction) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use UnknownIPAction.Descriptor instead.
+func (UnknownIPAction) EnumDescriptor() ([]byte, []int) {
+	return file_proto_geofence_proto_rawDescGZIP(), []int{1}
+}
+
+// IPStatus describes what is known about an IP's location.
+type IPStatus int32
+
+const (
+	// Not evaluated, e.g. a network override decided before any lookup.
+	IPStatus_IP_STATUS_UNSPECIFIED IPStatus = 0
+	IPStatus_IP_STATUS_GEOLOCATED  IPStatus = 1
+	// Public IP not present in the database.
+	IPStatus_IP_STATUS_UNKNOWN IPStatus = 2
+	// Private, loopback or reserved address; never looked up.
+	IPStatus_IP_STATUS_PRIVATE IPStatus = 3
+)
+
+// Enum value maps for IPStatus.
+var (
+	IPStatus_name = map[int32]string{
+		0: "IP_STATUS_UNSPECIFIED",
+		1: "IP_STATUS_GEOLOCATED",
+		2: "IP_STATUS_UNKNOWN",
+		3: "IP_STATUS_PRIVATE",
+	}
+	IPStatus_value = map[string]int32{
+		"IP_STATUS_UNSPECIFIED": 0,
+		"IP_STATUS_GEOLOCATED":  1,
+		"IP_STATUS_UNKNOWN":     2,
+		"IP_STATUS_PRIVATE":     3,
+	}
+)
+
+func (x IPStatus) Enum() *IPStatus {
+	p := new(IPStatus)
+	*p = x
+	return p
+}
+
+func (x IPStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (IPStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_geofence_proto_enumTypes[2].Descriptor()
+}
+
+func (IPStatus) Type() protoreflect.EnumType {
+	return &file_proto_geofence_proto_enumTypes[2]
+}
+
+func (x IPStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use IPStatus.Descriptor instead.
+func (IPStatus) EnumDescriptor() ([]byte, []int) {
+	return file_proto_geofence_proto_rawDescGZIP(), []int{2}
+}
+
+// DecisionSource identifies which kind of rule produced a decision.
+type DecisionSource int32
+
+const (
+	DecisionSource_DECISION_SOURCE_UNSPECIFIED DecisionSource = 0
+	// The country allow/deny lists decided.
+	DecisionSource_DECISION_SOURCE_COUNTRY DecisionSource = 1
+	// An allowed or denied network override matched before the country lookup.
+	DecisionSource_DECISION_SOURCE_OVERRIDE DecisionSource = 2
+	// The anonymous IP database flagged an IP the country lists allowed.
+	DecisionSource_DECISION_SOURCE_ANONYMITY DecisionSource = 3
+)
+
+// Enum value maps for DecisionSource.
+var (
+	DecisionSource_name = map[int32]string{
+		0: "DECISION_SOURCE_UNSPECIFIED",
+		1: "DECISION_SOURCE_COUNTRY",
+		2: "DECISION_SOURCE_OVERRIDE",
+		3: "DECISION_SOURCE_ANONYMITY",
+	}
+	DecisionSource_value = map[string]int32{
+		"DECISION_SOURCE_UNSPECIFIED": 0,
+		"DECISION_SOURCE_COUNTRY":     1,
+		"DECISION_SOURCE_OVERRIDE":    2,
+		"DECISION_SOURCE_ANONYMITY":   3,
+	}
+)
+
+func (x DecisionSource) Enum() *DecisionSource {
+	p := new(DecisionSource)
+	*p = x
+	return p
+}
+
+func (x DecisionSource) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (DecisionSource) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_geofence_proto_enumTypes[3].Descriptor()
+}
+
+func (DecisionSource) Type() protoreflect.EnumType {
+	return &file_proto_geofence_proto_enumTypes[3]
+}
+
+func (x DecisionSource) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DecisionSource.Descriptor instead.
+func (DecisionSource) EnumDescriptor() ([]byte, []int) {
+	return file_proto_geofence_proto_rawDescGZIP(), []int{3}
+}
+
 type CheckRequest struct {
-	state            protoimpl.MessageState `protogen:"open.v1"`
-	IpAddress        string                 `protobuf:"bytes,1,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
-	AllowedCountries []string               `protobuf:"bytes,2,rep,name=allowed_countries,json=allowedCountries,proto3" json:"allowed_countries,omitempty"`
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// IP to check. When empty, the server may check the caller's own IP instead.
+	IpAddress        string   `protobuf:"bytes,1,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	AllowedCountries []string `protobuf:"bytes,2,rep,name=allowed_countries,json=allowedCountries,proto3" json:"allowed_countries,omitempty"`
+	// Name of a server-side policy to check against instead of allowed_countries.
+	Policy string `protobuf:"bytes,3,opt,name=policy,proto3" json:"policy,omitempty"`
+	// Countries to block. An explicit code in either list beats the "*" wildcard;
+	// a code listed in both is denied.
+	DeniedCountries []string `protobuf:"bytes,4,rep,name=denied_countries,json=deniedCountries,proto3" json:"denied_countries,omitempty"`
+	// IP or CIDR overrides evaluated before the country lookup; the longest match wins.
+	AllowedNetworks []string `protobuf:"bytes,5,rep,name=allowed_networks,json=allowedNetworks,proto3" json:"allowed_networks,omitempty"`
+	DeniedNetworks  []string `protobuf:"bytes,6,rep,name=denied_networks,json=deniedNetworks,proto3" json:"denied_networks,omitempty"`
+	// What to do with IPs that have no country. May be combined with policy to
+	// override the policy's action. Defaults to deny.
+	UnknownIpAction UnknownIPAction `protobuf:"varint,7,opt,name=unknown_ip_action,json=unknownIpAction,proto3,enum=geofence.v1.UnknownIPAction" json:"unknown_ip_action,omitempty"`
+	// Regions (continent codes or named groups such as "EU27") whose member
+	// countries count as listed explicitly in allowed_countries / denied_countries.
+	AllowedRegions []string `protobuf:"bytes,8,rep,name=allowed_regions,json=allowedRegions,proto3" json:"allowed_regions,omitempty"`
+	DeniedRegions  []string `protobuf:"bytes,9,rep,name=denied_regions,json=deniedRegions,proto3" json:"denied_regions,omitempty"`
+	// Deny IPs the anonymous IP database flags with any of these: "anonymous",
+	// "vpn", "hosting", "public_proxy", "residential_proxy" or "tor". Needs an
+	// anonymous IP database on the server.
+	DeniedAnonymity []string `protobuf:"bytes,10,rep,name=denied_anonymity,json=deniedAnonymity,proto3" json:"denied_anonymity,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *CheckRequest) Reset() {
@@ -73,10 +332,85 @@ func (x *CheckRequest) GetAllowedCountries() []string {
 	return nil
 }
 
+func (x *CheckRequest) GetPolicy() string {
+	if x != nil {
+		return x.Policy
+	}
+	return ""
+}
+
+func (x *CheckRequest) GetDeniedCountries() []string {
+	if x != nil {
+		return x.DeniedCountries
+	}
+	return nil
+}
+
+func (x *CheckRequest) GetAllowedNetworks() []string {
+	if x != nil {
+		return x.AllowedNetworks
+	}
+	return nil
+}
+
+func (x *CheckRequest) GetDeniedNetworks() []string {
+	if x != nil {
+		return x.DeniedNetworks
+	}
+	return nil
+}
+
+func (x *CheckRequest) GetUnknownIpAction() UnknownIPAction {
+	if x != nil {
+		return x.UnknownIpAction
+	}
+	return UnknownIPAction_UNKNOWN_IP_ACTION_UNSPECIFIED
+}
+
+func (x *CheckRequest) GetAllowedRegions() []string {
+	if x != nil {
+		return x.AllowedRegions
+	}
+	return nil
+}
+
+func (x *CheckRequest) GetDeniedRegions() []string {
+	if x != nil {
+		return x.DeniedRegions
+	}
+	return nil
+}
+
+func (x *CheckRequest) GetDeniedAnonymity() []string {
+	if x != nil {
+		return x.DeniedAnonymity
+	}
+	return nil
+}
+
 type CheckResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Allowed       bool                   `protobuf:"varint,1,opt,name=allowed,proto3" json:"allowed,omitempty"`
-	Country       string                 `protobuf:"bytes,2,opt,name=country,proto3" json:"country,omitempty"`
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Allowed   bool                   `protobuf:"varint,1,opt,name=allowed,proto3" json:"allowed,omitempty"`
+	Country   string                 `protobuf:"bytes,2,opt,name=country,proto3" json:"country,omitempty"`
+	DecidedBy DecisionSource         `protobuf:"varint,3,opt,name=decided_by,json=decidedBy,proto3,enum=geofence.v1.DecisionSource" json:"decided_by,omitempty"`
+	IpStatus  IPStatus               `protobuf:"varint,4,opt,name=ip_status,json=ipStatus,proto3,enum=geofence.v1.IPStatus" json:"ip_status,omitempty"`
+	Reason    Reason                 `protobuf:"varint,5,opt,name=reason,proto3,enum=geofence.v1.Reason" json:"reason,omitempty"`
+	// The entry that decided: a country code or "*", an override prefix, or the
+	// reserved range for REASON_PRIVATE_IP. Empty when nothing matched.
+	MatchedRule string `protobuf:"bytes,6,opt,name=matched_rule,json=matchedRule,proto3" json:"matched_rule,omitempty"`
+	// The caller's own IP, set only when the request omitted ip_address and the
+	// server derived it from the connection.
+	IpAddress string `protobuf:"bytes,7,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	// The GeoIP provider that answered the lookup, e.g. "maxmind"; empty when no
+	// lookup was made. When several databases are consulted, the comma-separated
+	// names of those that reported the country.
+	Provider string `protobuf:"bytes,8,opt,name=provider,proto3" json:"provider,omitempty"`
+	// Every database's answer, set only when several databases were consulted and
+	// reported different countries.
+	Disagreement []*SourceAnswer `protobuf:"bytes,9,rep,name=disagreement,proto3" json:"disagreement,omitempty"`
+	// The anonymity flags the anonymous IP database reports for the IP, e.g. "vpn"
+	// or "tor". Empty when it is not listed or the server has no such database.
+	Anonymity     []string `protobuf:"bytes,10,rep,name=anonymity,proto3" json:"anonymity,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -125,26 +459,86 @@ func (x *CheckResponse) GetCountry() string {
 	return ""
 }
 
-type HealthRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
+func (x *CheckResponse) GetDecidedBy() DecisionSource {
+	if x != nil {
+		return x.DecidedBy
+	}
+	return DecisionSource_DECISION_SOURCE_UNSPECIFIED
+}
+
+func (x *CheckResponse) GetIpStatus() IPStatus {
+	if x != nil {
+		return x.IpStatus
+	}
+	return IPStatus_IP_STATUS_UNSPECIFIED
+}
+
+func (x *CheckResponse) GetReason() Reason {
+	if x != nil {
+		return x.Reason
+	}
+	return Reason_REASON_UNSPECIFIED
+}
+
+func (x *CheckResponse) GetMatchedRule() string {
+	if x != nil {
+		return x.MatchedRule
+	}
+	return ""
+}
+
+func (x *CheckResponse) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *CheckResponse) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *CheckResponse) GetDisagreement() []*SourceAnswer {
+	if x != nil {
+		return x.Disagreement
+	}
+	return nil
+}
+
+func (x *CheckResponse) GetAnonymity() []string {
+	if x != nil {
+		return x.Anonymity
+	}
+	return nil
+}
+
+// One GeoIP database's answer for an IP.
+type SourceAnswer struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Source string                 `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
+	// Empty when the database does not know the IP.
+	Country       string `protobuf:"bytes,2,opt,name=country,proto3" json:"country,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *HealthRequest) Reset() {
-	*x = HealthRequest{}
+func (x *SourceAnswer) Reset() {
+	*x = SourceAnswer{}
 	mi := &file_proto_geofence_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *HealthRequest) String() string {
+func (x *SourceAnswer) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*HealthRequest) ProtoMessage() {}
+func (*SourceAnswer) ProtoMessage() {}
 
-func (x *HealthRequest) ProtoReflect() protoreflect.Message {
+func (x *SourceAnswer) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_geofence_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -156,32 +550,56 @@ func (x *HealthRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use HealthRequest.ProtoReflect.Descriptor instead.
-func (*HealthRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use SourceAnswer.ProtoReflect.Descriptor instead.
+func (*SourceAnswer) Descriptor() ([]byte, []int) {
 	return file_proto_geofence_proto_rawDescGZIP(), []int{2}
 }
 
-type HealthResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *SourceAnswer) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
 }
 
-func (x *HealthResponse) Reset() {
-	*x = HealthResponse{}
+func (x *SourceAnswer) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+type BatchCheckRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Shared allow list or policy, used by items that do not set their own.
+	AllowedCountries []string          `protobuf:"bytes,1,rep,name=allowed_countries,json=allowedCountries,proto3" json:"allowed_countries,omitempty"`
+	Items            []*BatchCheckItem `protobuf:"bytes,2,rep,name=items,proto3" json:"items,omitempty"`
+	Policy           string            `protobuf:"bytes,3,opt,name=policy,proto3" json:"policy,omitempty"`
+	DeniedCountries  []string          `protobuf:"bytes,4,rep,name=denied_countries,json=deniedCountries,proto3" json:"denied_countries,omitempty"`
+	AllowedNetworks  []string          `protobuf:"bytes,5,rep,name=allowed_networks,json=allowedNetworks,proto3" json:"allowed_networks,omitempty"`
+	DeniedNetworks   []string          `protobuf:"bytes,6,rep,name=denied_networks,json=deniedNetworks,proto3" json:"denied_networks,omitempty"`
+	UnknownIpAction  UnknownIPAction   `protobuf:"varint,7,opt,name=unknown_ip_action,json=unknownIpAction,proto3,enum=geofence.v1.UnknownIPAction" json:"unknown_ip_action,omitempty"`
+	AllowedRegions   []string          `protobuf:"bytes,8,rep,name=allowed_regions,json=allowedRegions,proto3" json:"allowed_regions,omitempty"`
+	DeniedRegions    []string          `protobuf:"bytes,9,rep,name=denied_regions,json=deniedRegions,proto3" json:"denied_regions,omitempty"`
+	DeniedAnonymity  []string          `protobuf:"bytes,10,rep,name=denied_anonymity,json=deniedAnonymity,proto3" json:"denied_anonymity,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *BatchCheckRequest) Reset() {
+	*x = BatchCheckRequest{}
 	mi := &file_proto_geofence_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *HealthResponse) String() string {
+func (x *BatchCheckRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*HealthResponse) ProtoMessage() {}
+func (*BatchCheckRequest) ProtoMessage() {}
 
-func (x *HealthResponse) ProtoReflect() protoreflect.Message {
+func (x *BatchCheckRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_geofence_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -193,35 +611,1093 @@ func (x *HealthResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use HealthResponse.ProtoReflect.Descriptor instead.
-func (*HealthResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use BatchCheckRequest.ProtoReflect.Descriptor instead.
+func (*BatchCheckRequest) Descriptor() ([]byte, []int) {
 	return file_proto_geofence_proto_rawDescGZIP(), []int{3}
 }
 
-func (x *HealthResponse) GetStatus() string {
+func (x *BatchCheckRequest) GetAllowedCountries() []string {
 	if x != nil {
-		return x.Status
+		return x.AllowedCountries
+	}
+	return nil
+}
+
+func (x *BatchCheckRequest) GetItems() []*BatchCheckItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *BatchCheckRequest) GetPolicy() string {
+	if x != nil {
+		return x.Policy
 	}
 	return ""
 }
 
-var File_proto_geofence_proto protoreflect.FileDescriptor
+func (x *BatchCheckRequest) GetDeniedCountries() []string {
+	if x != nil {
+		return x.DeniedCountries
+	}
+	return nil
+}
 
-const file_proto_geofence_proto_rawDesc = "" +
-	"\n" +
-	"\x14proto/geofence.proto\x12\vgeofence.v1\"Z\n" +
-	"\fCheckRequest\x12\x1d\n" +
-	"\n" +
-	"ip_address\x18\x01 \x01(\tR\tipAddress\x12+\n" +
-	"\x11allowed_countries\x18\x02 \x03(\tR\x10allowedCountries\"C\n" +
-	"\rCheckResponse\x12\x18\n" +
-	"\aallowed\x18\x01 \x01(\bR\aallowed\x12\x18\n" +
-	"\acountry\x18\x02 \x01(\tR\acountry\"\x0f\n" +
+func (x *BatchCheckRequest) GetAllowedNetworks() []string {
+	if x != nil {
+		return x.AllowedNetworks
+	}
+	return nil
+}
+
+func (x *BatchCheckRequest) GetDeniedNetworks() []string {
+	if x != nil {
+		return x.DeniedNetworks
+	}
+	return nil
+}
+
+func (x *BatchCheckRequest) GetUnknownIpAction() UnknownIPAction {
+	if x != nil {
+		return x.UnknownIpAction
+	}
+	return UnknownIPAction_UNKNOWN_IP_ACTION_UNSPECIFIED
+}
+
+func (x *BatchCheckRequest) GetAllowedRegions() []string {
+	if x != nil {
+		return x.AllowedRegions
+	}
+	return nil
+}
+
+func (x *BatchCheckRequest) GetDeniedRegions() []string {
+	if x != nil {
+		return x.DeniedRegions
+	}
+	return nil
+}
+
+func (x *BatchCheckRequest) GetDeniedAnonymity() []string {
+	if x != nil {
+		return x.DeniedAnonymity
+	}
+	return nil
+}
+
+type BatchCheckItem struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	IpAddress        string                 `protobuf:"bytes,1,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	AllowedCountries []string               `protobuf:"bytes,2,rep,name=allowed_countries,json=allowedCountries,proto3" json:"allowed_countries,omitempty"`
+	Policy           string                 `protobuf:"bytes,3,opt,name=policy,proto3" json:"policy,omitempty"`
+	DeniedCountries  []string               `protobuf:"bytes,4,rep,name=denied_countries,json=deniedCountries,proto3" json:"denied_countries,omitempty"`
+	AllowedNetworks  []string               `protobuf:"bytes,5,rep,name=allowed_networks,json=allowedNetworks,proto3" json:"allowed_networks,omitempty"`
+	DeniedNetworks   []string               `protobuf:"bytes,6,rep,name=denied_networks,json=deniedNetworks,proto3" json:"denied_networks,omitempty"`
+	UnknownIpAction  UnknownIPAction        `protobuf:"varint,7,opt,name=unknown_ip_action,json=unknownIpAction,proto3,enum=geofence.v1.UnknownIPAction" json:"unknown_ip_action,omitempty"`
+	AllowedRegions   []string               `protobuf:"bytes,8,rep,name=allowed_regions,json=allowedRegions,proto3" json:"allowed_regions,omitempty"`
+	DeniedRegions    []string               `protobuf:"bytes,9,rep,name=denied_regions,json=deniedRegions,proto3" json:"denied_regions,omitempty"`
+	DeniedAnonymity  []string               `protobuf:"bytes,10,rep,name=denied_anonymity,json=deniedAnonymity,proto3" json:"denied_anonymity,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *BatchCheckItem) Reset() {
+	*x = BatchCheckItem{}
+	mi := &file_proto_geofence_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchCheckItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchCheckItem) ProtoMessage() {}
+
+func (x *BatchCheckItem) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_geofence_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchCheckItem.ProtoReflect.Descriptor instead.
+func (*BatchCheckItem) Descriptor() ([]byte, []int) {
+	return file_proto_geofence_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BatchCheckItem) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *BatchCheckItem) GetAllowedCountries() []string {
+	if x != nil {
+		return x.AllowedCountries
+	}
+	return nil
+}
+
+func (x *BatchCheckItem) GetPolicy() string {
+	if x != nil {
+		return x.Policy
+	}
+	return ""
+}
+
+func (x *BatchCheckItem) GetDeniedCountries() []string {
+	if x != nil {
+		return x.DeniedCountries
+	}
+	return nil
+}
+
+func (x *BatchCheckItem) GetAllowedNetworks() []string {
+	if x != nil {
+		return x.AllowedNetworks
+	}
+	return nil
+}
+
+func (x *BatchCheckItem) GetDeniedNetworks() []string {
+	if x != nil {
+		return x.DeniedNetworks
+	}
+	return nil
+}
+
+func (x *BatchCheckItem) GetUnknownIpAction() UnknownIPAction {
+	if x != nil {
+		return x.UnknownIpAction
+	}
+	return UnknownIPAction_UNKNOWN_IP_ACTION_UNSPECIFIED
+}
+
+func (x *BatchCheckItem) GetAllowedRegions() []string {
+	if x != nil {
+		return x.AllowedRegions
+	}
+	return nil
+}
+
+func (x *BatchCheckItem) GetDeniedRegions() []string {
+	if x != nil {
+		return x.DeniedRegions
+	}
+	return nil
+}
+
+func (x *BatchCheckItem) GetDeniedAnonymity() []string {
+	if x != nil {
+		return x.DeniedAnonymity
+	}
+	return nil
+}
+
+type BatchCheckResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*BatchCheckResult    `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchCheckResponse) Reset() {
+	*x = BatchCheckResponse{}
+	mi := &file_proto_geofence_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchCheckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchCheckResponse) ProtoMessage() {}
+
+func (x *BatchCheckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_geofence_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchCheckResponse.ProtoReflect.Descriptor instead.
+func (*BatchCheckResponse) Descriptor() ([]byte, []int) {
+	return file_proto_geofence_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *BatchCheckResponse) GetResults() []*BatchCheckResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type BatchCheckResult struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	IpAddress string                 `protobuf:"bytes,1,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	Allowed   bool                   `protobuf:"varint,2,opt,name=allowed,proto3" json:"allowed,omitempty"`
+	Country   string                 `protobuf:"bytes,3,opt,name=country,proto3" json:"country,omitempty"`
+	// Set when this item could not be checked (e.g., invalid IP); empty on success.
+	Error         string          `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	DecidedBy     DecisionSource  `protobuf:"varint,5,opt,name=decided_by,json=decidedBy,proto3,enum=geofence.v1.DecisionSource" json:"decided_by,omitempty"`
+	IpStatus      IPStatus        `protobuf:"varint,6,opt,name=ip_status,json=ipStatus,proto3,enum=geofence.v1.IPStatus" json:"ip_status,omitempty"`
+	Reason        Reason          `protobuf:"varint,7,opt,name=reason,proto3,enum=geofence.v1.Reason" json:"reason,omitempty"`
+	MatchedRule   string          `protobuf:"bytes,8,opt,name=matched_rule,json=matchedRule,proto3" json:"matched_rule,omitempty"`
+	Provider      string          `protobuf:"bytes,9,opt,name=provider,proto3" json:"provider,omitempty"`
+	Disagreement  []*SourceAnswer `protobuf:"bytes,10,rep,name=disagreement,proto3" json:"disagreement,omitempty"`
+	Anonymity     []string        `protobuf:"bytes,11,rep,name=anonymity,proto3" json:"anonymity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchCheckResult) Reset() {
+	*x = BatchCheckResult{}
+	mi := &file_proto_geofence_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchCheckResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchCheckResult) ProtoMessage() {}
+
+func (x *BatchCheckResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_geofence_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchCheckResult.ProtoReflect.Descriptor instead.
+func (*BatchCheckResult) Descriptor() ([]byte, []int) {
+	return file_proto_geofence_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *BatchCheckResult) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *BatchCheckResult) GetAllowed() bool {
+	if x != nil {
+		return x.Allowed
+	}
+	return false
+}
+
+func (x *BatchCheckResult) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *BatchCheckResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *BatchCheckResult) GetDecidedBy() DecisionSource {
+	if x != nil {
+		return x.DecidedBy
+	}
+	return DecisionSource_DECISION_SOURCE_UNSPECIFIED
+}
+
+func (x *BatchCheckResult) GetIpStatus() IPStatus {
+	if x != nil {
+		return x.IpStatus
+	}
+	return IPStatus_IP_STATUS_UNSPECIFIED
+}
+
+func (x *BatchCheckResult) GetReason() Reason {
+	if x != nil {
+		return x.Reason
+	}
+	return Reason_REASON_UNSPECIFIED
+}
+
+func (x *BatchCheckResult) GetMatchedRule() string {
+	if x != nil {
+		return x.MatchedRule
+	}
+	return ""
+}
+
+func (x *BatchCheckResult) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *BatchCheckResult) GetDisagreement() []*SourceAnswer {
+	if x != nil {
+		return x.Disagreement
+	}
+	return nil
+}
+
+func (x *BatchCheckResult) GetAnonymity() []string {
+	if x != nil {
+		return x.Anonymity
+	}
+	return nil
+}
+
+type LookupRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// IP to look up. When empty, the server may look up the caller's own IP instead.
+	IpAddress string `protobuf:"bytes,1,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	// Locale for names: de, en (the default), es, fr, ja, pt-BR, ru or zh-CN. Names
+	// missing in the locale fall back to English.
+	Locale        string `protobuf:"bytes,2,opt,name=locale,proto3" json:"locale,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupRequest) Reset() {
+	*x = LookupRequest{}
+	mi := &file_proto_geofence_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupRequest) ProtoMessage() {}
+
+func (x *LookupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_geofence_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupRequest.ProtoReflect.Descriptor instead.
+func (*LookupRequest) Descriptor() ([]byte, []int) {
+	return file_proto_geofence_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *LookupRequest) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *LookupRequest) GetLocale() string {
+	if x != nil {
+		return x.Locale
+	}
+	return ""
+}
+
+type LookupResponse struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	IpAddress string                 `protobuf:"bytes,1,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	IpStatus  IPStatus               `protobuf:"varint,2,opt,name=ip_status,json=ipStatus,proto3,enum=geofence.v1.IPStatus" json:"ip_status,omitempty"`
+	// Where the IP is believed to be located.
+	Country *CountryInfo `protobuf:"bytes,3,opt,name=country,proto3" json:"country,omitempty"`
+	// Where the ISP registered the network.
+	RegisteredCountry *CountryInfo `protobuf:"bytes,4,opt,name=registered_country,json=registeredCountry,proto3" json:"registered_country,omitempty"`
+	// The country represented by e.g. a military base; unset for most IPs.
+	RepresentedCountry *RepresentedCountryInfo `protobuf:"bytes,5,opt,name=represented_country,json=representedCountry,proto3" json:"represented_country,omitempty"`
+	Continent          *ContinentInfo          `protobuf:"bytes,6,opt,name=continent,proto3" json:"continent,omitempty"`
+	// The largest prefix containing the IP that shares the same record, or the
+	// reserved range for IP_STATUS_PRIVATE.
+	Network string `protobuf:"bytes,7,opt,name=network,proto3" json:"network,omitempty"`
+	// The GeoIP provider that answered; empty for IP_STATUS_PRIVATE.
+	Provider      string          `protobuf:"bytes,8,opt,name=provider,proto3" json:"provider,omitempty"`
+	Disagreement  []*SourceAnswer `protobuf:"bytes,9,rep,name=disagreement,proto3" json:"disagreement,omitempty"`
+	Anonymity     []string        `protobuf:"bytes,10,rep,name=anonymity,proto3" json:"anonymity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupResponse) Reset() {
+	*x = LookupResponse{}
+	mi := &file_proto_geofence_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupResponse) ProtoMessage() {}
+
+func (x *LookupResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_geofence_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupResponse.ProtoReflect.Descriptor instead.
+func (*LookupResponse) Descriptor() ([]byte, []int) {
+	return file_proto_geofence_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *LookupResponse) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *LookupResponse) GetIpStatus() IPStatus {
+	if x != nil {
+		return x.IpStatus
+	}
+	return IPStatus_IP_STATUS_UNSPECIFIED
+}
+
+func (x *LookupResponse) GetCountry() *CountryInfo {
+	if x != nil {
+		return x.Country
+	}
+	return nil
+}
+
+func (x *LookupResponse) GetRegisteredCountry() *CountryInfo {
+	if x != nil {
+		return x.RegisteredCountry
+	}
+	return nil
+}
+
+func (x *LookupResponse) GetRepresentedCountry() *RepresentedCountryInfo {
+	if x != nil {
+		return x.RepresentedCountry
+	}
+	return nil
+}
+
+func (x *LookupResponse) GetContinent() *ContinentInfo {
+	if x != nil {
+		return x.Continent
+	}
+	return nil
+}
+
+func (x *LookupResponse) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+func (x *LookupResponse) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *LookupResponse) GetDisagreement() []*SourceAnswer {
+	if x != nil {
+		return x.Disagreement
+	}
+	return nil
+}
+
+func (x *LookupResponse) GetAnonymity() []string {
+	if x != nil {
+		return x.Anonymity
+	}
+	return nil
+}
+
+type CountryInfo struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	IsoCode           string                 `protobuf:"bytes,1,opt,name=iso_code,json=isoCode,proto3" json:"iso_code,omitempty"`
+	Name              string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	IsInEuropeanUnion bool                   `protobuf:"varint,3,opt,name=is_in_european_union,json=isInEuropeanUnion,proto3" json:"is_in_european_union,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *CountryInfo) Reset() {
+	*x = CountryInfo{}
+	mi := &file_proto_geofence_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CountryInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CountryInfo) ProtoMessage() {}
+
+func (x *CountryInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_geofence_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CountryInfo.ProtoReflect.Descriptor instead.
+func (*CountryInfo) Descriptor() ([]byte, []int) {
+	return file_proto_geofence_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CountryInfo) GetIsoCode() string {
+	if x != nil {
+		return x.IsoCode
+	}
+	return ""
+}
+
+func (x *CountryInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CountryInfo) GetIsInEuropeanUnion() bool {
+	if x != nil {
+		return x.IsInEuropeanUnion
+	}
+	return false
+}
+
+type RepresentedCountryInfo struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	IsoCode           string                 `protobuf:"bytes,1,opt,name=iso_code,json=isoCode,proto3" json:"iso_code,omitempty"`
+	Name              string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	IsInEuropeanUnion bool                   `protobuf:"varint,3,opt,name=is_in_european_union,json=isInEuropeanUnion,proto3" json:"is_in_european_union,omitempty"`
+	// The kind of entity representing the country, e.g. "military".
+	Type          string `protobuf:"bytes,4,opt,name=type,proto3" json:"type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RepresentedCountryInfo) Reset() {
+	*x = RepresentedCountryInfo{}
+	mi := &file_proto_geofence_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RepresentedCountryInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RepresentedCountryInfo) ProtoMessage() {}
+
+func (x *RepresentedCountryInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_geofence_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RepresentedCountryInfo.ProtoReflect.Descriptor instead.
+func (*RepresentedCountryInfo) Descriptor() ([]byte, []int) {
+	return file_proto_geofence_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *RepresentedCountryInfo) GetIsoCode() string {
+	if x != nil {
+		return x.IsoCode
+	}
+	return ""
+}
+
+func (x *RepresentedCountryInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *RepresentedCountryInfo) GetIsInEuropeanUnion() bool {
+	if x != nil {
+		return x.IsInEuropeanUnion
+	}
+	return false
+}
+
+func (x *RepresentedCountryInfo) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+type ContinentInfo struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Two-letter code such as "NA" or "EU".
+	Code          string `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	Name          string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ContinentInfo) Reset() {
+	*x = ContinentInfo{}
+	mi := &file_proto_geofence_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContinentInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContinentInfo) ProtoMessage() {}
+
+func (x *ContinentInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_geofence_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContinentInfo.ProtoReflect.Descriptor instead.
+func (*ContinentInfo) Descriptor() ([]byte, []int) {
+	return file_proto_geofence_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ContinentInfo) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *ContinentInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type ListRegionsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Return only this region. Empty lists all of them.
+	Name          string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRegionsRequest) Reset() {
+	*x = ListRegionsRequest{}
+	mi := &file_proto_geofence_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRegionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRegionsRequest) ProtoMessage() {}
+
+func (x *ListRegionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_geofence_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRegionsRequest.ProtoReflect.Descriptor instead.
+func (*ListRegionsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_geofence_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ListRegionsRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type ListRegionsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Sorted by name.
+	Regions       []*Region `protobuf:"bytes,1,rep,name=regions,proto3" json:"regions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRegionsResponse) Reset() {
+	*x = ListRegionsResponse{}
+	mi := &file_proto_geofence_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRegionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRegionsResponse) ProtoMessage() {}
+
+func (x *ListRegionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_geofence_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRegionsResponse.ProtoReflect.Descriptor instead.
+func (*ListRegionsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_geofence_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ListRegionsResponse) GetRegions() []*Region {
+	if x != nil {
+		return x.Regions
+	}
+	return nil
+}
+
+type Region struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// ISO country codes, sorted.
+	Countries     []string `protobuf:"bytes,2,rep,name=countries,proto3" json:"countries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Region) Reset() {
+	*x = Region{}
+	mi := &file_proto_geofence_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Region) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Region) ProtoMessage() {}
+
+func (x *Region) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_geofence_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Region.ProtoReflect.Descriptor instead.
+func (*Region) Descriptor() ([]byte, []int) {
+	return file_proto_geofence_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Region) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Region) GetCountries() []string {
+	if x != nil {
+		return x.Countries
+	}
+	return nil
+}
+
+type HealthRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HealthRequest) Reset() {
+	*x = HealthRequest{}
+	mi := &file_proto_geofence_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HealthRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthRequest) ProtoMessage() {}
+
+func (x *HealthRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_geofence_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HealthRequest.ProtoReflect.Descriptor instead.
+func (*HealthRequest) Descriptor() ([]byte, []int) {
+	return file_proto_geofence_proto_rawDescGZIP(), []int{15}
+}
+
+type HealthResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HealthResponse) Reset() {
+	*x = HealthResponse{}
+	mi := &file_proto_geofence_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HealthResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthResponse) ProtoMessage() {}
+
+func (x *HealthResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_geofence_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HealthResponse.ProtoReflect.Descriptor instead.
+func (*HealthResponse) Descriptor() ([]byte, []int) {
+	return file_proto_geofence_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *HealthResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+var File_proto_geofence_proto protoreflect.FileDescriptor
+
+const file_proto_geofence_proto_rawDesc = "" +
+	"\n" +
+	"\x14proto/geofence.proto\x12\vgeofence.v1\"\xb6\x03\n" +
+	"\fCheckRequest\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\x01 \x01(\tR\tipAddress\x12+\n" +
+	"\x11allowed_countries\x18\x02 \x03(\tR\x10allowedCountries\x12\x16\n" +
+	"\x06policy\x18\x03 \x01(\tR\x06policy\x12)\n" +
+	"\x10denied_countries\x18\x04 \x03(\tR\x0fdeniedCountries\x12)\n" +
+	"\x10allowed_networks\x18\x05 \x03(\tR\x0fallowedNetworks\x12'\n" +
+	"\x0fdenied_networks\x18\x06 \x03(\tR\x0edeniedNetworks\x12H\n" +
+	"\x11unknown_ip_action\x18\a \x01(\x0e2\x1c.geofence.v1.UnknownIPActionR\x0funknownIpAction\x12'\n" +
+	"\x0fallowed_regions\x18\b \x03(\tR\x0eallowedRegions\x12%\n" +
+	"\x0edenied_regions\x18\t \x03(\tR\rdeniedRegions\x12)\n" +
+	"\x10denied_anonymity\x18\n" +
+	" \x03(\tR\x0fdeniedAnonymity\"\x9b\x03\n" +
+	"\rCheckResponse\x12\x18\n" +
+	"\aallowed\x18\x01 \x01(\bR\aallowed\x12\x18\n" +
+	"\acountry\x18\x02 \x01(\tR\acountry\x12:\n" +
+	"\n" +
+	"decided_by\x18\x03 \x01(\x0e2\x1b.geofence.v1.DecisionSourceR\tdecidedBy\x122\n" +
+	"\tip_status\x18\x04 \x01(\x0e2\x15.geofence.v1.IPStatusR\bipStatus\x12+\n" +
+	"\x06reason\x18\x05 \x01(\x0e2\x13.geofence.v1.ReasonR\x06reason\x12!\n" +
+	"\fmatched_rule\x18\x06 \x01(\tR\vmatchedRule\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\a \x01(\tR\tipAddress\x12\x1a\n" +
+	"\bprovider\x18\b \x01(\tR\bprovider\x12=\n" +
+	"\fdisagreement\x18\t \x03(\v2\x19.geofence.v1.SourceAnswerR\fdisagreement\x12\x1c\n" +
+	"\tanonymity\x18\n" +
+	" \x03(\tR\tanonymity\"@\n" +
+	"\fSourceAnswer\x12\x16\n" +
+	"\x06source\x18\x01 \x01(\tR\x06source\x12\x18\n" +
+	"\acountry\x18\x02 \x01(\tR\acountry\"\xcf\x03\n" +
+	"\x11BatchCheckRequest\x12+\n" +
+	"\x11allowed_countries\x18\x01 \x03(\tR\x10allowedCountries\x121\n" +
+	"\x05items\x18\x02 \x03(\v2\x1b.geofence.v1.BatchCheckItemR\x05items\x12\x16\n" +
+	"\x06policy\x18\x03 \x01(\tR\x06policy\x12)\n" +
+	"\x10denied_countries\x18\x04 \x03(\tR\x0fdeniedCountries\x12)\n" +
+	"\x10allowed_networks\x18\x05 \x03(\tR\x0fallowedNetworks\x12'\n" +
+	"\x0fdenied_networks\x18\x06 \x03(\tR\x0edeniedNetworks\x12H\n" +
+	"\x11unknown_ip_action\x18\a \x01(\x0e2\x1c.geofence.v1.UnknownIPActionR\x0funknownIpAction\x12'\n" +
+	"\x0fallowed_regions\x18\b \x03(\tR\x0eallowedRegions\x12%\n" +
+	"\x0edenied_regions\x18\t \x03(\tR\rdeniedRegions\x12)\n" +
+	"\x10denied_anonymity\x18\n" +
+	" \x03(\tR\x0fdeniedAnonymity\"\xb8\x03\n" +
+	"\x0eBatchCheckItem\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\x01 \x01(\tR\tipAddress\x12+\n" +
+	"\x11allowed_countries\x18\x02 \x03(\tR\x10allowedCountries\x12\x16\n" +
+	"\x06policy\x18\x03 \x01(\tR\x06policy\x12)\n" +
+	"\x10denied_countries\x18\x04 \x03(\tR\x0fdeniedCountries\x12)\n" +
+	"\x10allowed_networks\x18\x05 \x03(\tR\x0fallowedNetworks\x12'\n" +
+	"\x0fdenied_networks\x18\x06 \x03(\tR\x0edeniedNetworks\x12H\n" +
+	"\x11unknown_ip_action\x18\a \x01(\x0e2\x1c.geofence.v1.UnknownIPActionR\x0funknownIpAction\x12'\n" +
+	"\x0fallowed_regions\x18\b \x03(\tR\x0eallowedRegions\x12%\n" +
+	"\x0edenied_regions\x18\t \x03(\tR\rdeniedRegions\x12)\n" +
+	"\x10denied_anonymity\x18\n" +
+	" \x03(\tR\x0fdeniedAnonymity\"M\n" +
+	"\x12BatchCheckResponse\x127\n" +
+	"\aresults\x18\x01 \x03(\v2\x1d.geofence.v1.BatchCheckResultR\aresults\"\xb4\x03\n" +
+	"\x10BatchCheckResult\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\x01 \x01(\tR\tipAddress\x12\x18\n" +
+	"\aallowed\x18\x02 \x01(\bR\aallowed\x12\x18\n" +
+	"\acountry\x18\x03 \x01(\tR\acountry\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\x12:\n" +
+	"\n" +
+	"decided_by\x18\x05 \x01(\x0e2\x1b.geofence.v1.DecisionSourceR\tdecidedBy\x122\n" +
+	"\tip_status\x18\x06 \x01(\x0e2\x15.geofence.v1.IPStatusR\bipStatus\x12+\n" +
+	"\x06reason\x18\a \x01(\x0e2\x13.geofence.v1.ReasonR\x06reason\x12!\n" +
+	"\fmatched_rule\x18\b \x01(\tR\vmatchedRule\x12\x1a\n" +
+	"\bprovider\x18\t \x01(\tR\bprovider\x12=\n" +
+	"\fdisagreement\x18\n" +
+	" \x03(\v2\x19.geofence.v1.SourceAnswerR\fdisagreement\x12\x1c\n" +
+	"\tanonymity\x18\v \x03(\tR\tanonymity\"F\n" +
+	"\rLookupRequest\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\x01 \x01(\tR\tipAddress\x12\x16\n" +
+	"\x06locale\x18\x02 \x01(\tR\x06locale\"\x83\x04\n" +
+	"\x0eLookupResponse\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\x01 \x01(\tR\tipAddress\x122\n" +
+	"\tip_status\x18\x02 \x01(\x0e2\x15.geofence.v1.IPStatusR\bipStatus\x122\n" +
+	"\acountry\x18\x03 \x01(\v2\x18.geofence.v1.CountryInfoR\acountry\x12G\n" +
+	"\x12registered_country\x18\x04 \x01(\v2\x18.geofence.v1.CountryInfoR\x11registeredCountry\x12T\n" +
+	"\x13represented_country\x18\x05 \x01(\v2#.geofence.v1.RepresentedCountryInfoR\x12representedCountry\x128\n" +
+	"\tcontinent\x18\x06 \x01(\v2\x1a.geofence.v1.ContinentInfoR\tcontinent\x12\x18\n" +
+	"\anetwork\x18\a \x01(\tR\anetwork\x12\x1a\n" +
+	"\bprovider\x18\b \x01(\tR\bprovider\x12=\n" +
+	"\fdisagreement\x18\t \x03(\v2\x19.geofence.v1.SourceAnswerR\fdisagreement\x12\x1c\n" +
+	"\tanonymity\x18\n" +
+	" \x03(\tR\tanonymity\"m\n" +
+	"\vCountryInfo\x12\x19\n" +
+	"\biso_code\x18\x01 \x01(\tR\aisoCode\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12/\n" +
+	"\x14is_in_european_union\x18\x03 \x01(\bR\x11isInEuropeanUnion\"\x8c\x01\n" +
+	"\x16RepresentedCountryInfo\x12\x19\n" +
+	"\biso_code\x18\x01 \x01(\tR\aisoCode\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12/\n" +
+	"\x14is_in_european_union\x18\x03 \x01(\bR\x11isInEuropeanUnion\x12\x12\n" +
+	"\x04type\x18\x04 \x01(\tR\x04type\"7\n" +
+	"\rContinentInfo\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\tR\x04code\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\"(\n" +
+	"\x12ListRegionsRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"D\n" +
+	"\x13ListRegionsResponse\x12-\n" +
+	"\aregions\x18\x01 \x03(\v2\x13.geofence.v1.RegionR\aregions\":\n" +
+	"\x06Region\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1c\n" +
+	"\tcountries\x18\x02 \x03(\tR\tcountries\"\x0f\n" +
 	"\rHealthRequest\"(\n" +
 	"\x0eHealthResponse\x12\x16\n" +
-	"\x06status\x18\x01 \x01(\tR\x06status2W\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status*\xd8\x01\n" +
+	"\x06Reason\x12\x16\n" +
+	"\x12REASON_UNSPECIFIED\x10\x00\x12\x1a\n" +
+	"\x16REASON_COUNTRY_ALLOWED\x10\x01\x12\x1e\n" +
+	"\x1aREASON_COUNTRY_NOT_IN_LIST\x10\x02\x12\x19\n" +
+	"\x15REASON_COUNTRY_DENIED\x10\x03\x12\x15\n" +
+	"\x11REASON_UNKNOWN_IP\x10\x04\x12\x15\n" +
+	"\x11REASON_PRIVATE_IP\x10\x05\x12\x18\n" +
+	"\x14REASON_OVERRIDE_RULE\x10\x06\x12\x17\n" +
+	"\x13REASON_ANONYMOUS_IP\x10\a*\x8a\x01\n" +
+	"\x0fUnknownIPAction\x12!\n" +
+	"\x1dUNKNOWN_IP_ACTION_UNSPECIFIED\x10\x00\x12\x1a\n" +
+	"\x16UNKNOWN_IP_ACTION_DENY\x10\x01\x12\x1b\n" +
+	"\x17UNKNOWN_IP_ACTION_ALLOW\x10\x02\x12\x1b\n" +
+	"\x17UNKNOWN_IP_ACTION_ERROR\x10\x03*m\n" +
+	"\bIPStatus\x12\x19\n" +
+	"\x15IP_STATUS_UNSPECIFIED\x10\x00\x12\x18\n" +
+	"\x14IP_STATUS_GEOLOCATED\x10\x01\x12\x15\n" +
+	"\x11IP_STATUS_UNKNOWN\x10\x02\x12\x15\n" +
+	"\x11IP_STATUS_PRIVATE\x10\x03*\x8b\x01\n" +
+	"\x0eDecisionSource\x12\x1f\n" +
+	"\x1bDECISION_SOURCE_UNSPECIFIED\x10\x00\x12\x1b\n" +
+	"\x17DECISION_SOURCE_COUNTRY\x10\x01\x12\x1c\n" +
+	"\x18DECISION_SOURCE_OVERRIDE\x10\x02\x12\x1d\n" +
+	"\x19DECISION_SOURCE_ANONYMITY\x10\x032\xc1\x02\n" +
 	"\x0fGeoFenceService\x12D\n" +
-	"\vCheckAccess\x12\x19.geofence.v1.CheckRequest\x1a\x1a.geofence.v1.CheckResponse2W\n" +
+	"\vCheckAccess\x12\x19.geofence.v1.CheckRequest\x1a\x1a.geofence.v1.CheckResponse\x12S\n" +
+	"\x10BatchCheckAccess\x12\x1e.geofence.v1.BatchCheckRequest\x1a\x1f.geofence.v1.BatchCheckResponse\x12A\n" +
+	"\x06Lookup\x12\x1a.geofence.v1.LookupRequest\x1a\x1b.geofence.v1.LookupResponse\x12P\n" +
+	"\vListRegions\x12\x1f.geofence.v1.ListRegionsRequest\x1a .geofence.v1.ListRegionsResponse2W\n" +
 	"\rHealthService\x12F\n" +
 	"\vCheckHealth\x12\x1a.geofence.v1.HealthRequest\x1a\x1b.geofence.v1.HealthResponseB9Z7github.com/jadenmounteer/avoxi-geo-fence/internal/pb;pbb\x06proto3"
 
@@ -237,23 +1713,67 @@ func file_proto_geofence_proto_rawDescGZIP() []byte {
 	return file_proto_geofence_proto_rawDescData
 }
 
-var file_proto_geofence_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_proto_geofence_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
+var file_proto_geofence_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
 var file_proto_geofence_proto_goTypes = []any{
-	(*CheckRequest)(nil),   // 0: geofence.v1.CheckRequest
-	(*CheckResponse)(nil),  // 1: geofence.v1.CheckResponse
-	(*HealthRequest)(nil),  // 2: geofence.v1.HealthRequest
-	(*HealthResponse)(nil), // 3: geofence.v1.HealthResponse
+	(Reason)(0),                    // 0: geofence.v1.Reason
+	(UnknownIPAction)(0),           // 1: geofence.v1.UnknownIPAction
+	(IPStatus)(0),                  // 2: geofence.v1.IPStatus
+	(DecisionSource)(0),            // 3: geofence.v1.DecisionSource
+	(*CheckRequest)(nil),           // 4: geofence.v1.CheckRequest
+	(*CheckResponse)(nil),          // 5: geofence.v1.CheckResponse
+	(*SourceAnswer)(nil),           // 6: geofence.v1.SourceAnswer
+	(*BatchCheckRequest)(nil),      // 7: geofence.v1.BatchCheckRequest
+	(*BatchCheckItem)(nil),         // 8: geofence.v1.BatchCheckItem
+	(*BatchCheckResponse)(nil),     // 9: geofence.v1.BatchCheckResponse
+	(*BatchCheckResult)(nil),       // 10: geofence.v1.BatchCheckResult
+	(*LookupRequest)(nil),          // 11: geofence.v1.LookupRequest
+	(*LookupResponse)(nil),         // 12: geofence.v1.LookupResponse
+	(*CountryInfo)(nil),            // 13: geofence.v1.CountryInfo
+	(*RepresentedCountryInfo)(nil), // 14: geofence.v1.RepresentedCountryInfo
+	(*ContinentInfo)(nil),          // 15: geofence.v1.ContinentInfo
+	(*ListRegionsRequest)(nil),     // 16: geofence.v1.ListRegionsRequest
+	(*ListRegionsResponse)(nil),    // 17: geofence.v1.ListRegionsResponse
+	(*Region)(nil),                 // 18: geofence.v1.Region
+	(*HealthRequest)(nil),          // 19: geofence.v1.HealthRequest
+	(*HealthResponse)(nil),         // 20: geofence.v1.HealthResponse
 }
 var file_proto_geofence_proto_depIdxs = []int32{
-	0, // 0: geofence.v1.GeoFenceService.CheckAccess:input_type -> geofence.v1.CheckRequest
-	2, // 1: geofence.v1.HealthService.CheckHealth:input_type -> geofence.v1.HealthRequest
-	1, // 2: geofence.v1.GeoFenceService.CheckAccess:output_type -> geofence.v1.CheckResponse
-	3, // 3: geofence.v1.HealthService.CheckHealth:output_type -> geofence.v1.HealthResponse
-	2, // [2:4] is the sub-list for method output_type
-	0, // [0:2] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	1,  // 0: geofence.v1.CheckRequest.unknown_ip_action:type_name -> geofence.v1.UnknownIPAction
+	3,  // 1: geofence.v1.CheckResponse.decided_by:type_name -> geofence.v1.DecisionSource
+	2,  // 2: geofence.v1.CheckResponse.ip_status:type_name -> geofence.v1.IPStatus
+	0,  // 3: geofence.v1.CheckResponse.reason:type_name -> geofence.v1.Reason
+	6,  // 4: geofence.v1.CheckResponse.disagreement:type_name -> geofence.v1.SourceAnswer
+	8,  // 5: geofence.v1.BatchCheckRequest.items:type_name -> geofence.v1.BatchCheckItem
+	1,  // 6: geofence.v1.BatchCheckRequest.unknown_ip_action:type_name -> geofence.v1.UnknownIPAction
+	1,  // 7: geofence.v1.BatchCheckItem.unknown_ip_action:type_name -> geofence.v1.UnknownIPAction
+	10, // 8: geofence.v1.BatchCheckResponse.results:type_name -> geofence.v1.BatchCheckResult
+	3,  // 9: geofence.v1.BatchCheckResult.decided_by:type_name -> geofence.v1.DecisionSource
+	2,  // 10: geofence.v1.BatchCheckResult.ip_status:type_name -> geofence.v1.IPStatus
+	0,  // 11: geofence.v1.BatchCheckResult.reason:type_name -> geofence.v1.Reason
+	6,  // 12: geofence.v1.BatchCheckResult.disagreement:type_name -> geofence.v1.SourceAnswer
+	2,  // 13: geofence.v1.LookupResponse.ip_status:type_name -> geofence.v1.IPStatus
+	13, // 14: geofence.v1.LookupResponse.country:type_name -> geofence.v1.CountryInfo
+	13, // 15: geofence.v1.LookupResponse.registered_country:type_name -> geofence.v1.CountryInfo
+	14, // 16: geofence.v1.LookupResponse.represented_country:type_name -> geofence.v1.RepresentedCountryInfo
+	15, // 17: geofence.v1.LookupResponse.continent:type_name -> geofence.v1.ContinentInfo
+	6,  // 18: geofence.v1.LookupResponse.disagreement:type_name -> geofence.v1.SourceAnswer
+	18, // 19: geofence.v1.ListRegionsResponse.regions:type_name -> geofence.v1.Region
+	4,  // 20: geofence.v1.GeoFenceService.CheckAccess:input_type -> geofence.v1.CheckRequest
+	7,  // 21: geofence.v1.GeoFenceService.BatchCheckAccess:input_type -> geofence.v1.BatchCheckRequest
+	11, // 22: geofence.v1.GeoFenceService.Lookup:input_type -> geofence.v1.LookupRequest
+	16, // 23: geofence.v1.GeoFenceService.ListRegions:input_type -> geofence.v1.ListRegionsRequest
+	19, // 24: geofence.v1.HealthService.CheckHealth:input_type -> geofence.v1.HealthRequest
+	5,  // 25: geofence.v1.GeoFenceService.CheckAccess:output_type -> geofence.v1.CheckResponse
+	9,  // 26: geofence.v1.GeoFenceService.BatchCheckAccess:output_type -> geofence.v1.BatchCheckResponse
+	12, // 27: geofence.v1.GeoFenceService.Lookup:output_type -> geofence.v1.LookupResponse
+	17, // 28: geofence.v1.GeoFenceService.ListRegions:output_type -> geofence.v1.ListRegionsResponse
+	20, // 29: geofence.v1.HealthService.CheckHealth:output_type -> geofence.v1.HealthResponse
+	25, // [25:30] is the sub-list for method output_type
+	20, // [20:25] is the sub-list for method input_type
+	20, // [20:20] is the sub-list for extension type_name
+	20, // [20:20] is the sub-list for extension extendee
+	0,  // [0:20] is the sub-list for field type_name
 }
 
 func init() { file_proto_geofence_proto_init() }
@@ -266,13 +1786,14 @@ func file_proto_geofence_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_geofence_proto_rawDesc), len(file_proto_geofence_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   4,
+			NumEnums:      4,
+			NumMessages:   17,
 			NumExtensions: 0,
 			NumServices:   2,
 		},
 		GoTypes:           file_proto_geofence_proto_goTypes,
 		DependencyIndexes: file_proto_geofence_proto_depIdxs,
+		EnumInfos:         file_proto_geofence_proto_enumTypes,
 		MessageInfos:      file_proto_geofence_proto_msgTypes,
 	}.Build()
 	File_proto_geofence_proto = out.File
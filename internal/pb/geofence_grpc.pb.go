@@ -19,16 +19,26 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	GeoFenceService_CheckAccess_FullMethodName = "/geofence.v1.GeoFenceService/CheckAccess"
+	GeoFenceService_CheckAccess_FullMethodName      = "/geofence.v1.GeoFenceService/CheckAccess"
+	GeoFenceService_BatchCheckAccess_FullMethodName = "/geofence.v1.GeoFenceService/BatchCheckAccess"
+	GeoFenceService_Lookup_FullMethodName           = "/geofence.v1.GeoFenceService/Lookup"
+	GeoFenceService_ListRegions_FullMethodName      = "/geofence.v1.GeoFenceService/ListRegions"
 )
 
 // GeoFenceServiceClient is the client API for GeoFenceService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 //
-// GeoFenceService checks IP addresses against an allowed country list.
+// GeoFenceService checks IP addresses against allowed and denied country lists.
 type GeoFenceServiceClient interface {
 	CheckAccess(ctx context.Context, in *CheckRequest, opts ...grpc.CallOption) (*CheckResponse, error)
+	// BatchCheckAccess checks many IPs in one call. Results are returned in input order;
+	// a bad item sets its own error instead of failing the whole batch.
+	BatchCheckAccess(ctx context.Context, in *BatchCheckRequest, opts ...grpc.CallOption) (*BatchCheckResponse, error)
+	// Lookup returns what the database knows about an IP without evaluating any rules.
+	Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*LookupResponse, error)
+	// ListRegions returns the configured regions and the countries each contains.
+	ListRegions(ctx context.Context, in *ListRegionsRequest, opts ...grpc.CallOption) (*ListRegionsResponse, error)
 }
 
 type geoFenceServiceClient struct {
@@ -49,13 +59,50 @@ func (c *geoFenceServiceClient) CheckAccess(ctx context.Context, in *CheckReques
 	return out, nil
 }
 
+func (c *geoFenceServiceClient) BatchCheckAccess(ctx context.Context, in *BatchCheckRequest, opts ...grpc.CallOption) (*BatchCheckResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchCheckResponse)
+	err := c.cc.Invoke(ctx, GeoFenceService_BatchCheckAccess_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *geoFenceServiceClient) Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*LookupResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LookupResponse)
+	err := c.cc.Invoke(ctx, GeoFenceService_Lookup_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *geoFenceServiceClient) ListRegions(ctx context.Context, in *ListRegionsRequest, opts ...grpc.CallOption) (*ListRegionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListRegionsResponse)
+	err := c.cc.Invoke(ctx, GeoFenceService_ListRegions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // GeoFenceServiceServer is the server API for GeoFenceService service.
 // All implementations must embed UnimplementedGeoFenceServiceServer
 // for forward compatibility.
 //
-// GeoFenceService checks IP addresses against an allowed country list.
+// GeoFenceService checks IP addresses against allowed and denied country lists.
 type GeoFenceServiceServer interface {
 	CheckAccess(context.Context, *CheckRequest) (*CheckResponse, error)
+	// BatchCheckAccess checks many IPs in one call. Results are returned in input order;
+	// a bad item sets its own error instead of failing the whole batch.
+	BatchCheckAccess(context.Context, *BatchCheckRequest) (*BatchCheckResponse, error)
+	// Lookup returns what the database knows about an IP without evaluating any rules.
+	Lookup(context.Context, *LookupRequest) (*LookupResponse, error)
+	// ListRegions returns the configured regions and the countries each contains.
+	ListRegions(context.Context, *ListRegionsRequest) (*ListRegionsResponse, error)
 	mustEmbedUnimplementedGeoFenceServiceServer()
 }
 
@@ -69,6 +116,15 @@ type UnimplementedGeoFenceServiceServer struct{}
 func (UnimplementedGeoFenceServiceServer) CheckAccess(context.Context, *CheckRequest) (*CheckResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method CheckAccess not implemented")
 }
+func (UnimplementedGeoFenceServiceServer) BatchCheckAccess(context.Context, *BatchCheckRequest) (*BatchCheckResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method BatchCheckAccess not implemented")
+}
+func (UnimplementedGeoFenceServiceServer) Lookup(context.Context, *LookupRequest) (*LookupResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Lookup not implemented")
+}
+func (UnimplementedGeoFenceServiceServer) ListRegions(context.Context, *ListRegionsRequest) (*ListRegionsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListRegions not implemented")
+}
 func (UnimplementedGeoFenceServiceServer) mustEmbedUnimplementedGeoFenceServiceServer() {}
 func (UnimplementedGeoFenceServiceServer) testEmbeddedByValue()                         {}
 
@@ -108,6 +164,60 @@ func _GeoFenceService_CheckAccess_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _GeoFenceService_BatchCheckAccess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchCheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeoFenceServiceServer).BatchCheckAccess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GeoFenceService_BatchCheckAccess_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeoFenceServiceServer).BatchCheckAccess(ctx, req.(*BatchCheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GeoFenceService_Lookup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LookupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeoFenceServiceServer).Lookup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GeoFenceService_Lookup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeoFenceServiceServer).Lookup(ctx, req.(*LookupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GeoFenceService_ListRegions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRegionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeoFenceServiceServer).ListRegions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GeoFenceService_ListRegions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeoFenceServiceServer).ListRegions(ctx, req.(*ListRegionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // GeoFenceService_ServiceDesc is the grpc.ServiceDesc for GeoFenceService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -119,6 +229,18 @@ var GeoFenceService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CheckAccess",
 			Handler:    _GeoFenceService_CheckAccess_Handler,
 		},
+		{
+			MethodName: "BatchCheckAccess",
+			Handler:    _GeoFenceService_BatchCheckAccess_Handler,
+		},
+		{
+			MethodName: "Lookup",
+			Handler:    _GeoFenceService_Lookup_Handler,
+		},
+		{
+			MethodName: "ListRegions",
+			Handler:    _GeoFenceService_ListRegions_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/geofence.proto",
@@ -0,0 +1,38 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSetupTracing(t *testing.T) {
+	tests := []struct {
+		name     string
+		exporter string
+		wantErr  error
+	}{
+		{name: "none", exporter: ExporterNone},
+		{name: "empty defaults to none", exporter: ""},
+		{name: "stdout", exporter: ExporterStdout},
+		{name: "unknown", exporter: "zipkin", wantErr: ErrUnknownExporter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shutdown, err := SetupTracing(context.Background(), tt.exporter, "geo-fence-service", "test")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("SetupTracing() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetupTracing() unexpected error: %v", err)
+			}
+			if err := shutdown(context.Background()); err != nil {
+				t.Errorf("shutdown() unexpected error: %v", err)
+			}
+		})
+	}
+}
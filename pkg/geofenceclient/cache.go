@@ -0,0 +1,60 @@
+package geofenceclient
+
+import (
+	"sync"
+	"time"
+)
+
+// cache is a small TTL cache of decisions. When full, expired entries are dropped
+// first and then arbitrary ones; decisions are cheap to recompute, so precise
+// eviction order does not matter.
+type cache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	size    int
+	entries map[string]cacheEntry
+	now     func() time.Time
+}
+
+type cacheEntry struct {
+	result  CheckResult
+	expires time.Time
+}
+
+func newCache(ttl time.Duration, size int) *cache {
+	return &cache{ttl: ttl, size: size, entries: make(map[string]cacheEntry, size), now: time.Now}
+}
+
+func (c *cache) get(key string) (CheckResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return CheckResult{}, false
+	}
+	if c.now().After(entry.expires) {
+		delete(c.entries, key)
+		return CheckResult{}, false
+	}
+	return entry.result, true
+}
+
+func (c *cache) put(key string, result CheckResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if len(c.entries) >= c.size {
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	for k := range c.entries {
+		if len(c.entries) < c.size {
+			break
+		}
+		delete(c.entries, k)
+	}
+	c.entries[key] = cacheEntry{result: result, expires: now.Add(c.ttl)}
+}
@@ -0,0 +1,308 @@
+// Package geofenceclient is the Go client for the geo-fence service. One Client
+// interface covers both transports, with per-attempt deadlines, retries with
+// backoff while the service is unavailable, client-side load balancing across
+// replicas, an optional TTL cache of decisions and a fail-open or fail-closed
+// fallback:
+//
+//	c, err := geofenceclient.NewGRPC([]string{"geo-fence-0:9090", "geo-fence-1:9090"},
+//		geofenceclient.WithCache(30*time.Second, 10000),
+//		geofenceclient.WithFallback(geofenceclient.FailClosed),
+//	)
+//	if err != nil { ... }
+//	defer c.Close()
+//	result, err := c.Check(ctx, geofenceclient.CheckRequest{IPAddress: ip, Policy: "voice-signup"})
+package geofenceclient
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+var (
+	// ErrInvalidRequest is returned when the service rejects a request as invalid,
+	// e.g. a malformed IP or an unknown policy.
+	ErrInvalidRequest = errors.New("invalid request")
+	// ErrUnknownIP is returned for IPs without a country when the policy's
+	// unknown_ip_action is "error".
+	ErrUnknownIP = errors.New("unknown IP")
+	// ErrUnavailable is returned when no replica could be reached before retries ran out.
+	ErrUnavailable = errors.New("geo-fence service unavailable")
+)
+
+// CheckRequest selects the IP and rules to check. Set either Policy or the inline
+// lists; the fields mean the same as in a /v1/check request.
+type CheckRequest struct {
+	IPAddress        string
+	Policy           string
+	AllowedCountries []string
+	DeniedCountries  []string
+	AllowedNetworks  []string
+	DeniedNetworks   []string
+	UnknownIPAction  string
+	AllowedRegions   []string
+	DeniedRegions    []string
+	DeniedAnonymity  []string
+}
+
+// CheckResult is the service's decision.
+type CheckResult struct {
+	Allowed     bool
+	Country     string
+	DecidedBy   string
+	IPStatus    string
+	Reason      string
+	MatchedRule string
+	// Provider is the GeoIP provider that answered the lookup, e.g. "maxmind".
+	Provider string
+	// Disagreement holds every database's answer when the service consulted
+	// several and they reported different countries.
+	Disagreement []SourceAnswer
+	// Anonymity lists the IP's anonymity flags, e.g. "vpn" or "tor", when the
+	// service has an anonymous IP database that lists it.
+	Anonymity []string
+	// Fallback is true when the service was unreachable and the result came from
+	// the configured FallbackMode rather than a real check.
+	Fallback bool
+}
+
+// SourceAnswer is one GeoIP database's answer. Country is empty when the database
+// does not know the IP.
+type SourceAnswer struct {
+	Source  string
+	Country string
+}
+
+// LookupResult is what the service knows about an IP's location, as returned by
+// /v1/lookup. Records the database has no data for are left zero; for private IPs
+// Network is the reserved range the IP is in.
+type LookupResult struct {
+	IPStatus           string
+	Country            Country // where the IP is believed to be located
+	RegisteredCountry  Country // where the ISP registered the network
+	RepresentedCountry RepresentedCountry
+	Continent          Continent
+	Network            string
+	Provider           string
+	Disagreement       []SourceAnswer
+	Anonymity          []string
+}
+
+// Country is a country with its name in the requested locale.
+type Country struct {
+	ISOCode           string
+	Name              string
+	IsInEuropeanUnion bool
+}
+
+// RepresentedCountry is the country represented by e.g. a military base. Type is
+// the kind of entity, e.g. "military".
+type RepresentedCountry struct {
+	Country
+	Type string
+}
+
+// Continent is a continent with its name in the requested locale.
+type Continent struct {
+	Code string // two-letter code such as "NA" or "EU"
+	Name string
+}
+
+// Client checks IPs against the geo-fence service. It is safe for concurrent use.
+type Client interface {
+	Check(ctx context.Context, req CheckRequest) (CheckResult, error)
+	// Lookup returns the database record for an IP without evaluating any rules.
+	// Names are in locale, or English when it is empty. It is retried like Check
+	// but never cached and has no fallback.
+	Lookup(ctx context.Context, ipAddress, locale string) (LookupResult, error)
+	Close() error
+}
+
+// FallbackMode decides what Check returns when the service is unreachable. It does
+// not apply when the caller's own context is canceled or times out; Check then
+// returns the context's error.
+type FallbackMode int
+
+const (
+	// FailError returns ErrUnavailable. It is the default.
+	FailError FallbackMode = iota
+	// FailOpen allows the request.
+	FailOpen
+	// FailClosed denies the request.
+	FailClosed
+)
+
+// Option configures a Client.
+type Option func(*options)
+
+type options struct {
+	timeout     time.Duration
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	cacheTTL    time.Duration
+	cacheSize   int
+	fallback    FallbackMode
+	dialOptions []grpc.DialOption
+	httpClient  *http.Client
+}
+
+func defaultOptions() options {
+	return options{
+		timeout:     2 * time.Second,
+		maxAttempts: 3,
+		backoff:     50 * time.Millisecond,
+		maxBackoff:  time.Second,
+	}
+}
+
+// WithTimeout sets the deadline for each attempt. The default is 2s. The caller's
+// context still bounds the call as a whole.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) { o.timeout = d }
+}
+
+// WithRetry sets how many attempts are made while the service is unavailable and
+// the initial backoff between them, which doubles (with jitter) up to 1s. The
+// default is 3 attempts starting at 50ms; 1 disables retries.
+func WithRetry(maxAttempts int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.maxAttempts = max(maxAttempts, 1)
+		o.backoff = backoff
+	}
+}
+
+// WithCache caches decisions for ttl, keeping at most size entries. Errors and
+// fallback results are never cached.
+func WithCache(ttl time.Duration, size int) Option {
+	return func(o *options) {
+		o.cacheTTL = ttl
+		o.cacheSize = size
+	}
+}
+
+// WithFallback sets what Check returns once retries are exhausted.
+func WithFallback(mode FallbackMode) Option {
+	return func(o *options) { o.fallback = mode }
+}
+
+// WithDialOptions adds gRPC dial options, e.g. transport credentials. Without
+// credentials the gRPC client connects in plaintext.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) { o.dialOptions = append(o.dialOptions, opts...) }
+}
+
+// WithHTTPClient sets the http.Client used by the HTTP transport. By default the
+// client creates its own and closes its idle connections on Close.
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) { o.httpClient = c }
+}
+
+// transport performs one attempt against the service. Errors that are worth
+// retrying on another attempt wrap ErrUnavailable.
+type transport interface {
+	check(ctx context.Context, req CheckRequest) (CheckResult, error)
+	lookup(ctx context.Context, ipAddress, locale string) (LookupResult, error)
+	close() error
+}
+
+// client adds deadlines, retries, caching and fallback on top of a transport.
+type client struct {
+	transport transport
+	opts      options
+	cache     *cache
+}
+
+func newClient(t transport, opts options) *client {
+	c := &client{transport: t, opts: opts}
+	if opts.cacheTTL > 0 && opts.cacheSize > 0 {
+		c.cache = newCache(opts.cacheTTL, opts.cacheSize)
+	}
+	return c
+}
+
+func (c *client) Check(ctx context.Context, req CheckRequest) (CheckResult, error) {
+	key := cacheKey(req)
+	if c.cache != nil {
+		if result, ok := c.cache.get(key); ok {
+			return result, nil
+		}
+	}
+
+	result, err := withRetry(ctx, c.opts, func(ctx context.Context) (CheckResult, error) {
+		return c.transport.check(ctx, req)
+	})
+	if err != nil && ctx.Err() != nil {
+		// The caller gave up; the fallback is only for an unreachable service.
+		return CheckResult{}, ctx.Err()
+	}
+	if errors.Is(err, ErrUnavailable) && c.opts.fallback != FailError {
+		return CheckResult{Allowed: c.opts.fallback == FailOpen, Fallback: true}, nil
+	}
+	if err != nil {
+		return CheckResult{}, err
+	}
+	if c.cache != nil {
+		c.cache.put(key, result)
+	}
+	return result, nil
+}
+
+func (c *client) Lookup(ctx context.Context, ipAddress, locale string) (LookupResult, error) {
+	result, err := withRetry(ctx, c.opts, func(ctx context.Context) (LookupResult, error) {
+		return c.transport.lookup(ctx, ipAddress, locale)
+	})
+	if err != nil && ctx.Err() != nil {
+		return LookupResult{}, ctx.Err()
+	}
+	return result, err
+}
+
+// withRetry makes attempts with a per-attempt deadline until one succeeds, fails
+// with an error other than ErrUnavailable, or the attempts run out.
+func withRetry[T any](ctx context.Context, opts options, attempt func(context.Context) (T, error)) (T, error) {
+	backoff := opts.backoff
+	for n := 1; ; n++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, opts.timeout)
+		result, err := attempt(attemptCtx)
+		cancel()
+		if err == nil || !errors.Is(err, ErrUnavailable) || n >= opts.maxAttempts {
+			return result, err
+		}
+
+		// Full jitter keeps replicas from being hit in lockstep after an outage.
+		wait := time.Duration(rand.Int64N(int64(backoff) + 1))
+		select {
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, opts.maxBackoff)
+	}
+}
+
+func (c *client) Close() error {
+	return c.transport.close()
+}
+
+// cacheKey identifies a request by every field that affects the decision.
+func cacheKey(req CheckRequest) string {
+	return strings.Join([]string{
+		req.IPAddress,
+		req.Policy,
+		strings.Join(req.AllowedCountries, ","),
+		strings.Join(req.DeniedCountries, ","),
+		strings.Join(req.AllowedNetworks, ","),
+		strings.Join(req.DeniedNetworks, ","),
+		req.UnknownIPAction,
+		strings.Join(req.AllowedRegions, ","),
+		strings.Join(req.DeniedRegions, ","),
+		strings.Join(req.DeniedAnonymity, ","),
+	}, "|")
+}
@@ -0,0 +1,263 @@
+package geofenceclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/api"
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/pb"
+	"google.golang.org/grpc"
+)
+
+type lookupFunc func(net.IP) (string, error)
+
+func (f lookupFunc) Lookup(ip net.IP) (string, error) { return f(ip) }
+
+type anonymityFunc func(net.IP) (geofence.Anonymity, error)
+
+func (f anonymityFunc) LookupAnonymity(ip net.IP) (geofence.Anonymity, error) { return f(ip) }
+
+func testChecker() *geofence.Checker {
+	return geofence.NewChecker(lookupFunc(func(ip net.IP) (string, error) {
+		if ip.String() == "1.2.3.4" {
+			return "", geofence.ErrUnknownIP
+		}
+		return "US", nil
+	}), geofence.WithPolicies(map[string]geofence.Policy{
+		"us-only": {AllowedCountries: []string{"US"}},
+	}), geofence.WithAnonymity(anonymityFunc(func(ip net.IP) (geofence.Anonymity, error) {
+		if ip.String() == "185.220.101.7" {
+			return geofence.AnonymityAnonymous | geofence.AnonymityTor, nil
+		}
+		return 0, nil
+	})))
+}
+
+// testAnonymity checks that c sends denied_anonymity and returns the flags.
+func testAnonymity(t *testing.T, c Client) {
+	t.Helper()
+	result, err := c.Check(context.Background(), CheckRequest{IPAddress: "185.220.101.7", AllowedCountries: []string{"US"}, DeniedAnonymity: []string{"tor"}})
+	if err != nil {
+		t.Fatalf("Check() unexpected error: %v", err)
+	}
+	want := CheckResult{Country: "US", DecidedBy: "anonymity", IPStatus: "geolocated", Reason: "ANONYMOUS_IP", MatchedRule: "tor", Anonymity: []string{"anonymous", "tor"}}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Check() = %+v, want %+v", result, want)
+	}
+}
+
+// testLookup checks that c returns lookup records and rejects invalid IPs.
+func testLookup(t *testing.T, c Client) {
+	t.Helper()
+	result, err := c.Lookup(context.Background(), "185.220.101.7", "")
+	if err != nil {
+		t.Fatalf("Lookup() unexpected error: %v", err)
+	}
+	want := LookupResult{IPStatus: "geolocated", Country: Country{ISOCode: "US"}, Anonymity: []string{"anonymous", "tor"}}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Lookup() = %+v, want %+v", result, want)
+	}
+
+	result, err = c.Lookup(context.Background(), "10.0.0.1", "")
+	want = LookupResult{IPStatus: "private", Network: "10.0.0.0/8"}
+	if err != nil || !reflect.DeepEqual(result, want) {
+		t.Errorf("Lookup(10.0.0.1) = %+v, %v, want %+v", result, err, want)
+	}
+
+	if _, err := c.Lookup(context.Background(), "not-an-ip", ""); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("Lookup() invalid IP error = %v, want ErrInvalidRequest", err)
+	}
+}
+
+func TestHTTPClient_Lookup(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/v1/lookup/{ip}", api.NewLookupHandler(testChecker()))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c, _ := NewHTTP([]string{srv.URL + "/"})
+	defer c.Close()
+	testLookup(t, c)
+}
+
+func TestHTTPClient_Check(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(countingHandler(&hits, api.NewCheckHandler(testChecker())))
+	defer srv.Close()
+
+	c, err := NewHTTP([]string{srv.URL})
+	if err != nil {
+		t.Fatalf("NewHTTP() unexpected error: %v", err)
+	}
+	defer c.Close()
+
+	result, err := c.Check(context.Background(), CheckRequest{IPAddress: "8.8.8.8", Policy: "us-only"})
+	if err != nil {
+		t.Fatalf("Check() unexpected error: %v", err)
+	}
+	want := CheckResult{Allowed: true, Country: "US", DecidedBy: "country", IPStatus: "geolocated", Reason: "COUNTRY_ALLOWED", MatchedRule: "US"}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Check() = %+v, want %+v", result, want)
+	}
+
+	if _, err := c.Check(context.Background(), CheckRequest{IPAddress: "not-an-ip", Policy: "us-only"}); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("invalid IP error = %v, want ErrInvalidRequest", err)
+	}
+	_, err = c.Check(context.Background(), CheckRequest{IPAddress: "1.2.3.4", AllowedCountries: []string{"US"}, UnknownIPAction: "error"})
+	if !errors.Is(err, ErrUnknownIP) {
+		t.Errorf("unknown IP error = %v, want ErrUnknownIP", err)
+	}
+	testAnonymity(t, c)
+}
+
+func TestHTTPClient_RetriesNextReplica(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	up := httptest.NewServer(api.NewCheckHandler(testChecker()))
+	defer up.Close()
+
+	c, _ := NewHTTP([]string{down.URL, up.URL}, WithRetry(2, time.Millisecond))
+	defer c.Close()
+
+	result, err := c.Check(context.Background(), CheckRequest{IPAddress: "8.8.8.8", AllowedCountries: []string{"US"}})
+	if err != nil || !result.Allowed {
+		t.Errorf("Check() = %+v, %v, want allowed after retry", result, err)
+	}
+}
+
+func TestClient_Fallback(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	tests := []struct {
+		name        string
+		mode        FallbackMode
+		wantErr     error
+		wantAllowed bool
+	}{
+		{name: "error", mode: FailError, wantErr: ErrUnavailable},
+		{name: "open", mode: FailOpen, wantAllowed: true},
+		{name: "closed", mode: FailClosed, wantAllowed: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := NewHTTP([]string{down.URL}, WithRetry(2, time.Millisecond), WithFallback(tt.mode))
+			defer c.Close()
+
+			result, err := c.Check(context.Background(), CheckRequest{IPAddress: "8.8.8.8", AllowedCountries: []string{"US"}})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Check() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if !result.Fallback || result.Allowed != tt.wantAllowed {
+				t.Errorf("Check() = %+v, want fallback with allowed=%v", result, tt.wantAllowed)
+			}
+		})
+	}
+	// A caller that gave up gets its context error, never a fail-open allow.
+	c, _ := NewHTTP([]string{down.URL}, WithRetry(3, time.Millisecond), WithFallback(FailOpen))
+	defer c.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err := c.Check(ctx, CheckRequest{IPAddress: "8.8.8.8", AllowedCountries: []string{"US"}})
+	if !errors.Is(err, context.Canceled) || result.Allowed {
+		t.Errorf("Check() with a canceled context = %+v, %v, want context.Canceled", result, err)
+	}
+}
+
+func TestClient_Cache(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(countingHandler(&hits, api.NewCheckHandler(testChecker())))
+	defer srv.Close()
+
+	c, _ := NewHTTP([]string{srv.URL}, WithCache(time.Minute, 10))
+	defer c.Close()
+
+	req := CheckRequest{IPAddress: "8.8.8.8", AllowedCountries: []string{"US"}}
+	for range 3 {
+		if _, err := c.Check(context.Background(), req); err != nil {
+			t.Fatalf("Check() unexpected error: %v", err)
+		}
+	}
+	req.AllowedCountries = []string{"GB"}
+	if result, _ := c.Check(context.Background(), req); result.Allowed {
+		t.Error("Check() with different countries returned the cached decision")
+	}
+	if got := hits.Load(); got != 2 {
+		t.Errorf("server hits = %d, want 2", got)
+	}
+}
+
+func TestCache_Expiry(t *testing.T) {
+	now := time.Now()
+	c := newCache(time.Second, 2)
+	c.now = func() time.Time { return now }
+
+	c.put("a", CheckResult{Allowed: true})
+	if _, ok := c.get("a"); !ok {
+		t.Error("get() before expiry = miss, want hit")
+	}
+	now = now.Add(2 * time.Second)
+	if _, ok := c.get("a"); ok {
+		t.Error("get() after expiry = hit, want miss")
+	}
+
+	c.put("b", CheckResult{})
+	c.put("c", CheckResult{})
+	c.put("d", CheckResult{})
+	if len(c.entries) > 2 {
+		t.Errorf("len(entries) = %d, want at most 2", len(c.entries))
+	}
+}
+
+func TestGRPCClient_Check(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := grpc.NewServer()
+	pb.RegisterGeoFenceServiceServer(srv, api.NewGeoFenceServer(testChecker()))
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	c, err := NewGRPC([]string{lis.Addr().String(), lis.Addr().String()})
+	if err != nil {
+		t.Fatalf("NewGRPC() unexpected error: %v", err)
+	}
+	defer c.Close()
+
+	result, err := c.Check(context.Background(), CheckRequest{IPAddress: "10.0.0.1", AllowedCountries: []string{"US"}, UnknownIPAction: "allow"})
+	if err != nil {
+		t.Fatalf("Check() unexpected error: %v", err)
+	}
+	want := CheckResult{Allowed: true, IPStatus: "private", Reason: "PRIVATE_IP", MatchedRule: "10.0.0.0/8"}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Check() = %+v, want %+v", result, want)
+	}
+	if _, err := c.Check(context.Background(), CheckRequest{IPAddress: "8.8.8.8", Policy: "nope"}); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("unknown policy error = %v, want ErrInvalidRequest", err)
+	}
+	testAnonymity(t, c)
+	testLookup(t, c)
+}
+
+func countingHandler(hits *atomic.Int32, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,158 @@
+package geofenceclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+	"google.golang.org/grpc/status"
+)
+
+// roundRobin spreads calls across every resolved replica instead of gRPC's
+// default of pinning to the first one.
+const roundRobin = `{"loadBalancingConfig": [{"round_robin": {}}]}`
+
+// NewGRPC creates a Client for the gRPC transport. With one address it is used as
+// the dial target, so "dns:///geo-fence:9090" balances across every replica DNS
+// returns; with several, calls are balanced across all of them.
+func NewGRPC(addresses []string, opts ...Option) (Client, error) {
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("geofenceclient: at least one address is required")
+	}
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	target := addresses[0]
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultServiceConfig(roundRobin),
+	}
+	if len(addresses) > 1 {
+		r := manual.NewBuilderWithScheme("geofence")
+		state := resolver.State{}
+		for _, addr := range addresses {
+			state.Addresses = append(state.Addresses, resolver.Address{Addr: addr})
+		}
+		r.InitialState(state)
+		target = r.Scheme() + ":///replicas"
+		dialOpts = append(dialOpts, grpc.WithResolvers(r))
+	}
+	conn, err := grpc.NewClient(target, append(dialOpts, o.dialOptions...)...)
+	if err != nil {
+		return nil, fmt.Errorf("geofenceclient: %w", err)
+	}
+	return newClient(&grpcTransport{conn: conn, client: pb.NewGeoFenceServiceClient(conn)}, o), nil
+}
+
+type grpcTransport struct {
+	conn   *grpc.ClientConn
+	client pb.GeoFenceServiceClient
+}
+
+func (t *grpcTransport) check(ctx context.Context, req CheckRequest) (CheckResult, error) {
+	resp, err := t.client.CheckAccess(ctx, &pb.CheckRequest{
+		IpAddress:        req.IPAddress,
+		Policy:           req.Policy,
+		AllowedCountries: req.AllowedCountries,
+		DeniedCountries:  req.DeniedCountries,
+		AllowedNetworks:  req.AllowedNetworks,
+		DeniedNetworks:   req.DeniedNetworks,
+		UnknownIpAction:  pb.UnknownIPAction(pb.UnknownIPAction_value["UNKNOWN_IP_ACTION_"+strings.ToUpper(req.UnknownIPAction)]),
+		AllowedRegions:   req.AllowedRegions,
+		DeniedRegions:    req.DeniedRegions,
+		DeniedAnonymity:  req.DeniedAnonymity,
+	})
+	if err != nil {
+		return CheckResult{}, statusError("check access", err)
+	}
+	result := CheckResult{
+		Allowed:     resp.GetAllowed(),
+		Country:     resp.GetCountry(),
+		DecidedBy:   enumString(resp.GetDecidedBy().String(), "DECISION_SOURCE_", true),
+		IPStatus:    enumString(resp.GetIpStatus().String(), "IP_STATUS_", true),
+		Reason:      enumString(resp.GetReason().String(), "REASON_", false),
+		MatchedRule: resp.GetMatchedRule(),
+		Provider:    resp.GetProvider(),
+		Anonymity:   resp.GetAnonymity(),
+	}
+	result.Disagreement = protoSourceAnswers(resp.GetDisagreement())
+	return result, nil
+}
+
+func (t *grpcTransport) lookup(ctx context.Context, ipAddress, locale string) (LookupResult, error) {
+	resp, err := t.client.Lookup(ctx, &pb.LookupRequest{IpAddress: ipAddress, Locale: locale})
+	if err != nil {
+		return LookupResult{}, statusError("lookup", err)
+	}
+	result := LookupResult{
+		IPStatus:     enumString(resp.GetIpStatus().String(), "IP_STATUS_", true),
+		Network:      resp.GetNetwork(),
+		Provider:     resp.GetProvider(),
+		Disagreement: protoSourceAnswers(resp.GetDisagreement()),
+		Anonymity:    resp.GetAnonymity(),
+		Continent:    Continent{Code: resp.GetContinent().GetCode(), Name: resp.GetContinent().GetName()},
+	}
+	if c := resp.GetCountry(); c != nil {
+		result.Country = Country{ISOCode: c.GetIsoCode(), Name: c.GetName(), IsInEuropeanUnion: c.GetIsInEuropeanUnion()}
+	}
+	if c := resp.GetRegisteredCountry(); c != nil {
+		result.RegisteredCountry = Country{ISOCode: c.GetIsoCode(), Name: c.GetName(), IsInEuropeanUnion: c.GetIsInEuropeanUnion()}
+	}
+	if c := resp.GetRepresentedCountry(); c != nil {
+		result.RepresentedCountry = RepresentedCountry{
+			Country: Country{ISOCode: c.GetIsoCode(), Name: c.GetName(), IsInEuropeanUnion: c.GetIsInEuropeanUnion()},
+			Type:    c.GetType(),
+		}
+	}
+	return result, nil
+}
+
+func (t *grpcTransport) close() error {
+	return t.conn.Close()
+}
+
+// statusError maps a gRPC status to ErrInvalidRequest, ErrUnknownIP or
+// ErrUnavailable where it means that.
+func statusError(op string, err error) error {
+	st := status.Convert(err)
+	switch st.Code() {
+	case codes.InvalidArgument:
+		return fmt.Errorf("%w: %s", ErrInvalidRequest, st.Message())
+	case codes.NotFound:
+		return fmt.Errorf("%w: %s", ErrUnknownIP, st.Message())
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return fmt.Errorf("%w: %s", ErrUnavailable, st.Message())
+	default:
+		return fmt.Errorf("%s: %w", op, err)
+	}
+}
+
+func protoSourceAnswers(answers []*pb.SourceAnswer) []SourceAnswer {
+	var out []SourceAnswer
+	for _, a := range answers {
+		out = append(out, SourceAnswer{Source: a.GetSource(), Country: a.GetCountry()})
+	}
+	return out
+}
+
+// enumString converts a proto enum name to the string the HTTP API uses, e.g.
+// IP_STATUS_PRIVATE to "private" or REASON_PRIVATE_IP to "PRIVATE_IP".
+// UNSPECIFIED becomes empty.
+func enumString(name, prefix string, lower bool) string {
+	name = strings.TrimPrefix(name, prefix)
+	if name == "UNSPECIFIED" {
+		return ""
+	}
+	if lower {
+		return strings.ToLower(name)
+	}
+	return name
+}
@@ -0,0 +1,169 @@
+package geofenceclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+// NewHTTP creates a Client for the HTTP transport. Calls rotate across baseURLs
+// (e.g. "http://geo-fence-0:8080"), so a retry goes to the next replica.
+func NewHTTP(baseURLs []string, opts ...Option) (Client, error) {
+	if len(baseURLs) == 0 {
+		return nil, fmt.Errorf("geofenceclient: at least one base URL is required")
+	}
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	urls := make([]string, len(baseURLs))
+	for i, u := range baseURLs {
+		urls[i] = strings.TrimSuffix(u, "/")
+	}
+	t := &httpTransport{client: o.httpClient, baseURLs: urls}
+	if t.client == nil {
+		t.client, t.owned = &http.Client{}, true
+	}
+	return newClient(t, o), nil
+}
+
+type httpTransport struct {
+	client   *http.Client
+	owned    bool // client was created by NewHTTP
+	baseURLs []string
+	next     atomic.Uint64
+}
+
+// baseURL returns the next replica in rotation.
+func (t *httpTransport) baseURL() string {
+	return t.baseURLs[(t.next.Add(1)-1)%uint64(len(t.baseURLs))]
+}
+
+func (t *httpTransport) check(ctx context.Context, req CheckRequest) (CheckResult, error) {
+	body, err := json.Marshal(checkRequest{
+		IPAddress:        req.IPAddress,
+		AllowedCountries: req.AllowedCountries,
+		DeniedCountries:  req.DeniedCountries,
+		AllowedNetworks:  req.AllowedNetworks,
+		DeniedNetworks:   req.DeniedNetworks,
+		UnknownIPAction:  req.UnknownIPAction,
+		Policy:           req.Policy,
+		AllowedRegions:   req.AllowedRegions,
+		DeniedRegions:    req.DeniedRegions,
+		DeniedAnonymity:  req.DeniedAnonymity,
+	})
+	if err != nil {
+		return CheckResult{}, fmt.Errorf("encode request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, t.baseURL()+"/v1/check", bytes.NewReader(body))
+	if err != nil {
+		return CheckResult{}, fmt.Errorf("build request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	var checkResp checkResponse
+	if err := t.do(httpReq, "check", &checkResp); err != nil {
+		return CheckResult{}, err
+	}
+	result := CheckResult{
+		Allowed:     checkResp.Allowed,
+		Country:     checkResp.Country,
+		DecidedBy:   checkResp.DecidedBy,
+		IPStatus:    checkResp.IPStatus,
+		Reason:      checkResp.Reason,
+		MatchedRule: checkResp.MatchedRule,
+		Provider:    checkResp.Provider,
+		Anonymity:   checkResp.Anonymity,
+	}
+	result.Disagreement = sourceAnswers(checkResp.Disagreement)
+	return result, nil
+}
+
+func (t *httpTransport) lookup(ctx context.Context, ipAddress, locale string) (LookupResult, error) {
+	if ipAddress == "" {
+		return LookupResult{}, fmt.Errorf("%w: ip_address is required", ErrInvalidRequest)
+	}
+	u := t.baseURL() + "/v1/lookup/" + url.PathEscape(ipAddress)
+	if locale != "" {
+		u += "?locale=" + url.QueryEscape(locale)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return LookupResult{}, fmt.Errorf("build request: %w", err)
+	}
+
+	var lookupResp lookupResponse
+	if err := t.do(httpReq, "lookup", &lookupResp); err != nil {
+		return LookupResult{}, err
+	}
+	result := LookupResult{
+		IPStatus:     lookupResp.IPStatus,
+		Network:      lookupResp.Network,
+		Provider:     lookupResp.Provider,
+		Disagreement: sourceAnswers(lookupResp.Disagreement),
+		Anonymity:    lookupResp.Anonymity,
+	}
+	if c := lookupResp.Country; c != nil {
+		result.Country = Country(*c)
+	}
+	if c := lookupResp.RegisteredCountry; c != nil {
+		result.RegisteredCountry = Country(*c)
+	}
+	if c := lookupResp.RepresentedCountry; c != nil {
+		result.RepresentedCountry = RepresentedCountry{Country: Country(c.countryJSON), Type: c.Type}
+	}
+	if c := lookupResp.Continent; c != nil {
+		result.Continent = Continent(*c)
+	}
+	return result, nil
+}
+
+// do sends req and decodes a 200 response into v. Other statuses are mapped to
+// ErrInvalidRequest, ErrUnknownIP or ErrUnavailable where they mean that.
+func (t *httpTransport) do(req *http.Request, op string, v any) error {
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp errorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		switch resp.StatusCode {
+		case http.StatusBadRequest:
+			return fmt.Errorf("%w: %s", ErrInvalidRequest, errResp.Error)
+		case http.StatusUnprocessableEntity:
+			return fmt.Errorf("%w: %s", ErrUnknownIP, errResp.Error)
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return fmt.Errorf("%w: status %d", ErrUnavailable, resp.StatusCode)
+		default:
+			return fmt.Errorf("%s: status %d: %s", op, resp.StatusCode, errResp.Error)
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+func sourceAnswers(answers []sourceAnswerJSON) []SourceAnswer {
+	var out []SourceAnswer
+	for _, a := range answers {
+		out = append(out, SourceAnswer{Source: a.Source, Country: a.Country})
+	}
+	return out
+}
+
+func (t *httpTransport) close() error {
+	if t.owned {
+		t.client.CloseIdleConnections()
+	}
+	return nil
+}
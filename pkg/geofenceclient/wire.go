@@ -0,0 +1,67 @@
+package geofenceclient
+
+// JSON bodies of the HTTP API, mirroring the service's own request and response
+// types. They are defined here rather than imported so the client does not pull
+// the server's dependencies into every binary that embeds it.
+
+type checkRequest struct {
+	IPAddress        string   `json:"ip_address"`
+	AllowedCountries []string `json:"allowed_countries"`
+	DeniedCountries  []string `json:"denied_countries,omitempty"`
+	AllowedNetworks  []string `json:"allowed_networks,omitempty"`
+	DeniedNetworks   []string `json:"denied_networks,omitempty"`
+	UnknownIPAction  string   `json:"unknown_ip_action,omitempty"`
+	Policy           string   `json:"policy,omitempty"`
+	AllowedRegions   []string `json:"allowed_regions,omitempty"`
+	DeniedRegions    []string `json:"denied_regions,omitempty"`
+	DeniedAnonymity  []string `json:"denied_anonymity,omitempty"`
+}
+
+type checkResponse struct {
+	Allowed      bool               `json:"allowed"`
+	Country      string             `json:"country"`
+	DecidedBy    string             `json:"decided_by"`
+	IPStatus     string             `json:"ip_status"`
+	Reason       string             `json:"reason"`
+	MatchedRule  string             `json:"matched_rule"`
+	Provider     string             `json:"provider"`
+	Disagreement []sourceAnswerJSON `json:"disagreement"`
+	Anonymity    []string           `json:"anonymity"`
+}
+
+type sourceAnswerJSON struct {
+	Source  string `json:"source"`
+	Country string `json:"country"`
+}
+
+type lookupResponse struct {
+	IPStatus           string                  `json:"ip_status"`
+	Country            *countryJSON            `json:"country"`
+	RegisteredCountry  *countryJSON            `json:"registered_country"`
+	RepresentedCountry *representedCountryJSON `json:"represented_country"`
+	Continent          *continentJSON          `json:"continent"`
+	Network            string                  `json:"network"`
+	Provider           string                  `json:"provider"`
+	Disagreement       []sourceAnswerJSON      `json:"disagreement"`
+	Anonymity          []string                `json:"anonymity"`
+}
+
+type countryJSON struct {
+	ISOCode           string `json:"iso_code"`
+	Name              string `json:"name"`
+	IsInEuropeanUnion bool   `json:"is_in_european_union"`
+}
+
+type representedCountryJSON struct {
+	countryJSON
+	Type string `json:"type"`
+}
+
+type continentJSON struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
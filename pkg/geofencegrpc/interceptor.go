@@ -0,0 +1,201 @@
+// Package geofencegrpc geo-fences gRPC services in-process with server
+// interceptors, so a service can enforce geo rules without calling
+// GeoFenceService:
+//
+//	gf, err := geofencegrpc.New(geofencegrpc.Config{
+//		DBPath: "data/GeoLite2-Country.mmdb",
+//		Methods: map[string]geofencegrpc.Policy{
+//			"/billing.v1.Billing/Charge": {AllowedCountries: []string{"US", "CA"}},
+//		},
+//	})
+//	if err != nil { ... }
+//	defer gf.Close()
+//	srv := grpc.NewServer(
+//		grpc.ChainUnaryInterceptor(gf.UnaryServerInterceptor()),
+//		grpc.ChainStreamInterceptor(gf.StreamServerInterceptor()),
+//	)
+//
+// Handlers read the decision with FromContext or CountryFromContext.
+package geofencegrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/embedded"
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrNoDatabase is returned by New when neither DBPath nor Lookuper is set.
+var ErrNoDatabase = embedded.ErrNoDatabase
+
+// ErrUnknownIP is the error a CountryLookuper returns for IPs without a country.
+var ErrUnknownIP = embedded.ErrUnknownIP
+
+// CountryLookuper resolves an IP to an ISO country code. It returns an error
+// wrapping ErrUnknownIP for IPs that have no country.
+type CountryLookuper = embedded.CountryLookuper
+
+// Policy is a set of geo rules with the same meaning as the fields of a /v1/check
+// request. UnknownIPAction is "deny" (the default), "allow" or "error"; with
+// "error" an IP without a country is rejected like a denied one.
+type Policy struct {
+	AllowedCountries []string
+	DeniedCountries  []string
+	AllowedNetworks  []string
+	DeniedNetworks   []string
+	UnknownIPAction  string
+}
+
+// Config configures the interceptors.
+type Config struct {
+	// DBPath is the GeoLite2-Country.mmdb file to load. Ignored if Lookuper is set.
+	DBPath string
+	// Provider is the format of DBPath: "maxmind" (the default), "dbip", "ipinfo"
+	// or "csv".
+	Provider string
+	// Lookuper replaces the database, e.g. to share one GeoIP reader or in tests.
+	Lookuper CountryLookuper
+
+	// Methods maps full method names ("/package.Service/Method") to the policy
+	// that guards them.
+	Methods map[string]Policy
+	// Default guards methods missing from Methods. When nil they are not checked.
+	Default *Policy
+
+	// TrustedProxies are proxy IPs or CIDR prefixes whose forwarded, x-forwarded-for
+	// and x-real-ip metadata is trusted. Without any, the peer address is checked.
+	TrustedProxies []string
+}
+
+// Decision is the outcome of a check, stored in the call context.
+type Decision = embedded.Decision
+
+// FromContext returns the decision for the current call, if it was checked.
+func FromContext(ctx context.Context) (Decision, bool) {
+	return embedded.FromContext(ctx)
+}
+
+// CountryFromContext returns the country detected for the current call, or "".
+func CountryFromContext(ctx context.Context) string {
+	return embedded.CountryFromContext(ctx)
+}
+
+// defaultPolicy is the name the Default policy is registered under; full method
+// names always start with "/", so it cannot collide.
+const defaultPolicy = "default"
+
+// GeoFence holds the compiled policies shared by both interceptors.
+type GeoFence struct {
+	fence      *embedded.Fence
+	methods    map[string]bool
+	hasDefault bool
+}
+
+// New validates cfg and opens the database. Call Close to release it.
+func New(cfg Config) (*GeoFence, error) {
+	policies := make(map[string]geofence.Policy, len(cfg.Methods)+1)
+	methods := make(map[string]bool, len(cfg.Methods))
+	for method, p := range cfg.Methods {
+		compiled, err := embedded.Policy(p).Compile()
+		if err != nil {
+			return nil, fmt.Errorf("geofencegrpc: method %s: %w", method, err)
+		}
+		policies[method] = compiled
+		methods[method] = true
+	}
+	if cfg.Default != nil {
+		compiled, err := embedded.Policy(*cfg.Default).Compile()
+		if err != nil {
+			return nil, fmt.Errorf("geofencegrpc: default policy: %w", err)
+		}
+		policies[defaultPolicy] = compiled
+	}
+	fence, err := embedded.New(embedded.Config{
+		DBPath:         cfg.DBPath,
+		Provider:       cfg.Provider,
+		Lookuper:       cfg.Lookuper,
+		Policies:       policies,
+		TrustedProxies: cfg.TrustedProxies,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("geofencegrpc: %w", err)
+	}
+	return &GeoFence{fence: fence, methods: methods, hasDefault: cfg.Default != nil}, nil
+}
+
+// UnaryServerInterceptor rejects unary calls the method's policy does not admit
+// with codes.PermissionDenied.
+func (g *GeoFence) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := g.check(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor rejects streams the method's policy does not admit with
+// codes.PermissionDenied before the handler runs.
+func (g *GeoFence) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := g.check(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// check evaluates the caller against the policy for method and returns ctx with
+// the decision attached, or a status error if the call must be rejected.
+func (g *GeoFence) check(ctx context.Context, method string) (context.Context, error) {
+	name := method
+	if !g.methods[method] {
+		if !g.hasDefault {
+			return ctx, nil
+		}
+		name = defaultPolicy
+	}
+
+	decision, err := g.fence.CheckCall(ctx, name)
+	if err != nil {
+		if errors.Is(err, geofence.ErrUnknownIP) || errors.Is(err, geofence.ErrInvalidIP) {
+			slog.Info("geo-fence rejected call", "method", method, "ip_address", decision.IPAddress, "err", err)
+			return ctx, status.Error(codes.PermissionDenied, "access denied")
+		}
+		slog.Error("geo-fence check failed", "method", method, "err", err)
+		return ctx, status.Error(codes.Internal, "geo-fence check failed")
+	}
+	if !decision.Allowed {
+		return ctx, status.Error(codes.PermissionDenied, "access denied")
+	}
+	return embedded.NewContext(ctx, decision), nil
+}
+
+// Reload re-reads the database from DBPath, e.g. after a scheduled update. It is a
+// no-op when Config.Lookuper was used.
+func (g *GeoFence) Reload() error {
+	return g.fence.Reload()
+}
+
+// Close releases the database. It is a no-op when Config.Lookuper was used.
+func (g *GeoFence) Close() error {
+	return g.fence.Close()
+}
+
+// contextStream overrides the context of a ServerStream so stream handlers see the decision.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
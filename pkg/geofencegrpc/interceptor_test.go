@@ -0,0 +1,117 @@
+package geofencegrpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/embedded"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func peerContext(ip string) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 5555}})
+}
+
+func newTestGeoFence(t *testing.T, cfg Config) *GeoFence {
+	t.Helper()
+	cfg.Lookuper = embedded.LookupFunc(func(ip net.IP) (string, error) {
+		if ip.String() == "81.2.69.142" {
+			return "GB", nil
+		}
+		return "US", nil
+	})
+	g, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	return g
+}
+
+func TestGeoFence_UnaryServerInterceptor(t *testing.T) {
+	g := newTestGeoFence(t, Config{
+		Methods:        map[string]Policy{"/svc.v1.Svc/Charge": {AllowedCountries: []string{"US"}}},
+		TrustedProxies: []string{"10.0.0.0/8"},
+	})
+	interceptor := g.UnaryServerInterceptor()
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		method      string
+		wantCode    codes.Code
+		wantCountry string
+	}{
+		{name: "allowed", ctx: peerContext("8.8.8.8"), method: "/svc.v1.Svc/Charge", wantCode: codes.OK, wantCountry: "US"},
+		{name: "denied", ctx: peerContext("81.2.69.142"), method: "/svc.v1.Svc/Charge", wantCode: codes.PermissionDenied},
+		{
+			name:     "forwarded metadata from trusted proxy",
+			ctx:      metadata.NewIncomingContext(peerContext("10.0.0.2"), metadata.Pairs("x-forwarded-for", "81.2.69.142")),
+			method:   "/svc.v1.Svc/Charge",
+			wantCode: codes.PermissionDenied,
+		},
+		{name: "no peer", ctx: context.Background(), method: "/svc.v1.Svc/Charge", wantCode: codes.PermissionDenied},
+		{name: "unguarded method", ctx: peerContext("81.2.69.142"), method: "/svc.v1.Svc/Ping", wantCode: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotCountry string
+			handler := func(ctx context.Context, _ any) (any, error) {
+				gotCountry = CountryFromContext(ctx)
+				return "ok", nil
+			}
+			_, err := interceptor(tt.ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("code = %v, want %v", code, tt.wantCode)
+			}
+			if gotCountry != tt.wantCountry {
+				t.Errorf("context country = %q, want %q", gotCountry, tt.wantCountry)
+			}
+		})
+	}
+}
+
+type fakeStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s fakeStream) Context() context.Context { return s.ctx }
+
+func TestGeoFence_StreamServerInterceptor(t *testing.T) {
+	g := newTestGeoFence(t, Config{Default: &Policy{AllowedCountries: []string{"GB"}}})
+	interceptor := g.StreamServerInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/svc.v1.Svc/Watch"}
+
+	var gotCountry string
+	handler := func(_ any, ss grpc.ServerStream) error {
+		gotCountry = CountryFromContext(ss.Context())
+		return nil
+	}
+
+	if err := interceptor(nil, fakeStream{ctx: peerContext("81.2.69.142")}, info, handler); err != nil {
+		t.Fatalf("allowed stream error = %v", err)
+	}
+	if gotCountry != "GB" {
+		t.Errorf("context country = %q, want GB", gotCountry)
+	}
+	err := interceptor(nil, fakeStream{ctx: peerContext("8.8.8.8")}, info, handler)
+	if code := status.Code(err); code != codes.PermissionDenied {
+		t.Errorf("denied stream code = %v, want PermissionDenied", code)
+	}
+}
+
+func TestNew_InvalidPolicy(t *testing.T) {
+	_, err := New(Config{
+		Lookuper: embedded.LookupFunc(nil),
+		Methods:  map[string]Policy{"/svc.v1.Svc/Charge": {}},
+	})
+	if err == nil {
+		t.Error("New() expected error for empty allowed list, got nil")
+	}
+}
@@ -0,0 +1,164 @@
+// Package geofencehttp geo-fences HTTP requests in-process. It wraps the same
+// Checker the geo-fence service runs as net/http middleware, so latency-sensitive
+// services can enforce a policy without a network hop:
+//
+//	mw, err := geofencehttp.New(geofencehttp.Config{
+//		DBPath:           "data/GeoLite2-Country.mmdb",
+//		AllowedCountries: []string{"US", "CA"},
+//	})
+//	if err != nil { ... }
+//	defer mw.Close()
+//	http.ListenAndServe(":8080", mw.Handler(mux))
+//
+// Downstream handlers read the decision with FromContext or CountryFromContext.
+package geofencehttp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/embedded"
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/geofence"
+)
+
+// ErrNoDatabase is returned by New when neither DBPath nor Lookuper is set.
+var ErrNoDatabase = embedded.ErrNoDatabase
+
+// CountryLookuper resolves an IP to an ISO country code. It returns an error
+// wrapping ErrUnknownIP for IPs that have no country.
+type CountryLookuper = embedded.CountryLookuper
+
+// ErrUnknownIP is the error a CountryLookuper returns for IPs without a country.
+var ErrUnknownIP = embedded.ErrUnknownIP
+
+// Config configures the middleware. The country and network fields and
+// UnknownIPAction have the same meaning as in a /v1/check request.
+type Config struct {
+	// DBPath is the GeoLite2-Country.mmdb file to load. Ignored if Lookuper is set.
+	DBPath string
+	// Provider is the format of DBPath: "maxmind" (the default), "dbip", "ipinfo"
+	// or "csv".
+	Provider string
+	// Lookuper replaces the database, e.g. to share one GeoIP reader or in tests.
+	Lookuper CountryLookuper
+
+	AllowedCountries []string
+	DeniedCountries  []string
+	AllowedNetworks  []string
+	DeniedNetworks   []string
+	// UnknownIPAction is "deny" (the default), "allow" or "error" for IPs without
+	// a country. With "error" such requests go to ErrorHandler.
+	UnknownIPAction string
+
+	// TrustedProxies are proxy IPs or CIDR prefixes whose Forwarded,
+	// X-Forwarded-For and X-Real-IP headers are trusted. Without any, the
+	// connection's remote address is checked.
+	TrustedProxies []string
+
+	// DeniedHandler writes the response for blocked requests. The default is a
+	// 403 with a JSON error body.
+	DeniedHandler http.Handler
+	// ErrorHandler writes the response when a request cannot be checked, e.g.
+	// its IP cannot be determined. The default is DeniedHandler (fail closed).
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// Decision is the outcome of a check, stored in the request context.
+type Decision = embedded.Decision
+
+// FromContext returns the decision for the current request, if the middleware ran.
+func FromContext(ctx context.Context) (Decision, bool) {
+	return embedded.FromContext(ctx)
+}
+
+// CountryFromContext returns the country detected for the current request, or "".
+func CountryFromContext(ctx context.Context) string {
+	return embedded.CountryFromContext(ctx)
+}
+
+// policyName is the name the middleware's policy is registered under.
+const policyName = "default"
+
+// Middleware checks each request against one policy.
+type Middleware struct {
+	fence   *embedded.Fence
+	denied  http.Handler
+	onError func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// New validates cfg and opens the database. Call Close to release it.
+func New(cfg Config) (*Middleware, error) {
+	policy, err := embedded.Policy{
+		AllowedCountries: cfg.AllowedCountries,
+		DeniedCountries:  cfg.DeniedCountries,
+		AllowedNetworks:  cfg.AllowedNetworks,
+		DeniedNetworks:   cfg.DeniedNetworks,
+		UnknownIPAction:  cfg.UnknownIPAction,
+	}.Compile()
+	if err != nil {
+		return nil, fmt.Errorf("geofencehttp: %w", err)
+	}
+	fence, err := embedded.New(embedded.Config{
+		DBPath:         cfg.DBPath,
+		Provider:       cfg.Provider,
+		Lookuper:       cfg.Lookuper,
+		Policies:       map[string]geofence.Policy{policyName: policy},
+		TrustedProxies: cfg.TrustedProxies,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("geofencehttp: %w", err)
+	}
+
+	m := &Middleware{fence: fence, denied: cfg.DeniedHandler, onError: cfg.ErrorHandler}
+	if m.denied == nil {
+		m.denied = http.HandlerFunc(defaultDenied)
+	}
+	if m.onError == nil {
+		m.onError = func(w http.ResponseWriter, r *http.Request, _ error) { m.denied.ServeHTTP(w, r) }
+	}
+	return m, nil
+}
+
+// Handler wraps next so that only requests admitted by the policy reach it.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		decision, err := m.fence.CheckRequest(r, policyName)
+		if err != nil {
+			slog.Warn("geo-fence check failed", "ip_address", decision.IPAddress, "err", err)
+			m.onError(w, r, err)
+			return
+		}
+
+		r = r.WithContext(embedded.NewContext(r.Context(), decision))
+		if !decision.Allowed {
+			m.denied.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Reload re-reads the database from DBPath, e.g. after a scheduled update. It is a
+// no-op when Config.Lookuper was used.
+func (m *Middleware) Reload() error {
+	return m.fence.Reload()
+}
+
+// Close releases the database. It is a no-op when Config.Lookuper was used.
+func (m *Middleware) Close() error {
+	return m.fence.Close()
+}
+
+// errorResponse matches the error body of the geo-fence service.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func defaultDenied(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	_ = json.NewEncoder(w).Encode(errorResponse{Error: "access denied"})
+}
@@ -0,0 +1,134 @@
+package geofencehttp
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jadenmounteer/avoxi-geo-fence/internal/embedded"
+)
+
+func TestMiddleware_Handler(t *testing.T) {
+	lookup := embedded.LookupFunc(func(ip net.IP) (string, error) {
+		switch ip.String() {
+		case "81.2.69.142":
+			return "GB", nil
+		case "1.2.3.4":
+			return "", ErrUnknownIP
+		}
+		return "US", nil
+	})
+
+	tests := []struct {
+		name        string
+		cfg         Config
+		remoteAddr  string
+		headers     map[string]string
+		wantStatus  int
+		wantCountry string
+	}{
+		{
+			name:        "allowed",
+			cfg:         Config{AllowedCountries: []string{"US"}},
+			remoteAddr:  "8.8.8.8:1234",
+			wantStatus:  http.StatusOK,
+			wantCountry: "US",
+		},
+		{
+			name:       "denied",
+			cfg:        Config{AllowedCountries: []string{"US"}},
+			remoteAddr: "81.2.69.142:1234",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "custom denied handler",
+			cfg: Config{AllowedCountries: []string{"US"}, DeniedHandler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if CountryFromContext(r.Context()) != "GB" {
+					t.Errorf("denied handler country = %q, want GB", CountryFromContext(r.Context()))
+				}
+				w.WriteHeader(http.StatusUnavailableForLegalReasons)
+			})},
+			remoteAddr: "81.2.69.142:1234",
+			wantStatus: http.StatusUnavailableForLegalReasons,
+		},
+		{
+			name:        "trusted proxy header",
+			cfg:         Config{AllowedCountries: []string{"GB"}, TrustedProxies: []string{"10.0.0.0/8"}},
+			remoteAddr:  "10.0.0.2:1234",
+			headers:     map[string]string{"X-Forwarded-For": "81.2.69.142"},
+			wantStatus:  http.StatusOK,
+			wantCountry: "GB",
+		},
+		{
+			name:       "untrusted proxy header ignored",
+			cfg:        Config{AllowedCountries: []string{"GB"}},
+			remoteAddr: "8.8.8.8:1234",
+			headers:    map[string]string{"X-Forwarded-For": "81.2.69.142"},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "unknown IP with error action goes to error handler",
+			cfg: Config{AllowedCountries: []string{"US"}, UnknownIPAction: "error", ErrorHandler: func(w http.ResponseWriter, _ *http.Request, err error) {
+				if !errors.Is(err, ErrUnknownIP) {
+					t.Errorf("error handler err = %v, want ErrUnknownIP", err)
+				}
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}},
+			remoteAddr: "1.2.3.4:1234",
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Lookuper = lookup
+			mw, err := New(tt.cfg)
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+			defer mw.Close()
+
+			var gotCountry string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotCountry = CountryFromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			mw.Handler(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if gotCountry != tt.wantCountry {
+				t.Errorf("context country = %q, want %q", gotCountry, tt.wantCountry)
+			}
+		})
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{name: "no database", cfg: Config{AllowedCountries: []string{"US"}}},
+		{name: "empty allowed list", cfg: Config{Lookuper: embedded.LookupFunc(nil)}},
+		{name: "bad network", cfg: Config{Lookuper: embedded.LookupFunc(nil), AllowedCountries: []string{"US"}, DeniedNetworks: []string{"bogus"}}},
+		{name: "bad action", cfg: Config{Lookuper: embedded.LookupFunc(nil), AllowedCountries: []string{"US"}, UnknownIPAction: "maybe"}},
+		{name: "bad trusted proxy", cfg: Config{Lookuper: embedded.LookupFunc(nil), AllowedCountries: []string{"US"}, TrustedProxies: []string{"proxy"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg); err == nil {
+				t.Error("New() expected error, got nil")
+			}
+		})
+	}
+}